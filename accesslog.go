@@ -0,0 +1,87 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Annotate adds key=value to the access log line of the request ctx belongs
+// to, for middlewares and handlers to record what downstream analysis needs.
+func Annotate(ctx context.Context, key string, value string) {
+	if values := GetValues(ctx); values != nil {
+		values.annotations = append(values.annotations, key, value)
+	}
+}
+
+// AccessLog is a middleware logging one line per request once it has been
+// served:
+//
+//	4bf92f35 GET /users/42 200 1.2ms exp.checkout=b
+//
+// with the trace id, the method, the path and query, the status, the
+// duration and the annotations added with Annotate. The query params holding
+// credentials, such as the signature of signed URLs, are redacted.
+func AccessLog(logger Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			start := time.Now()
+
+			next(ctx, response, request)
+
+			values := GetValues(ctx)
+			if values == nil {
+				logger.Printf("%s %s %s", request.Method, redactURI(request.URL), time.Since(start))
+				return
+			}
+
+			status := values.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			var builder strings.Builder
+
+			for index := 0; index+1 < len(values.annotations); index += 2 {
+				builder.WriteString(" " + values.annotations[index] + "=" + values.annotations[index+1])
+			}
+
+			logger.Printf("%s %s %s %d %s%s", values.TraceID, request.Method, redactURI(request.URL), status, time.Since(start), builder.String())
+		}
+	}
+}
+
+// redactedParams are the query params holding credentials, such as the
+// signature of signed URLs or the code and state of OIDC callbacks, whose
+// values are kept out of logs, samples and records.
+var redactedParams = []string{"signature", "code", "state", "token", "access_token", "id_token", "refresh_token"}
+
+// redactURI returns the request URI of u with the values of redactedParams
+// replaced with Redacted.
+func redactURI(u *url.URL) string {
+	uri := u.RequestURI()
+	if u.RawQuery == "" {
+		return uri
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	redacted := false
+
+	for index, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+
+		if name, err := url.QueryUnescape(key); err == nil && slices.Contains(redactedParams, strings.ToLower(name)) {
+			pairs[index] = key + "=" + Redacted
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return uri
+	}
+
+	return strings.TrimSuffix(uri, u.RawQuery) + strings.Join(pairs, "&")
+}
@@ -0,0 +1,44 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogRedactsCredentials(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter(AccessLog(logger))
+	router.Handle("/auth/callback", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth/callback?code=secret-code&state=secret-state&lang=uz", nil))
+
+	if len(logger.lines) != 1 || strings.Contains(logger.lines[0], "secret") || !strings.Contains(logger.lines[0], "/auth/callback?code=[REDACTED]&state=[REDACTED]&lang=uz 200") {
+		t.Errorf("expected the credentials to be redacted from the log, got %q", logger.lines)
+	}
+}
+
+func TestRedactURI(t *testing.T) {
+	tests := map[string]string{
+		"/orders":                                 "/orders",
+		"/orders?page=2&sort=-id":                 "/orders?page=2&sort=-id",
+		"/files/a.pdf?expires=1700&signature=abc": "/files/a.pdf?expires=1700&signature=[REDACTED]",
+		"/reset?Token=abc":                        "/reset?Token=[REDACTED]",
+		"/reset?access%5Ftoken=abc&token":         "/reset?access%5Ftoken=[REDACTED]&token=[REDACTED]",
+	}
+
+	for uri, expected := range tests {
+		parsed, err := url.ParseRequestURI(uri)
+		if err != nil {
+			t.Fatalf("ParseRequestURI: %s", err)
+		}
+
+		if redacted := redactURI(parsed); redacted != expected {
+			t.Errorf("%s: expected %s but got %s", uri, expected, redacted)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ibnsina
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// AfterResponse registers fn to run once the response of the request ctx
+// belongs to is sent, for work that must only happen when the request
+// succeeded, such as invalidating caches or dispatching webhooks:
+//
+//	ibnsina.AfterResponse(ctx, func(ctx context.Context) {
+//		webhooks.Dispatch(ctx, "order.created", order)
+//	})
+//
+// Functions run in the order registered, on the goroutine of the request,
+// once the handler returned and the response is flushed, with a detached
+// copy of ctx. They are skipped when the handler panics, the response has
+// a 4xx or 5xx status or failed, or the client has gone away. A function
+// panicking is logged and the next ones still run. AfterResponse does
+// nothing when ctx does not come from the router.
+func AfterResponse(ctx context.Context, fn func(ctx context.Context)) {
+	if values := GetValues(ctx); values != nil {
+		values.after = append(values.after, fn)
+	}
+}
+
+// finish flushes the response and runs the functions registered with
+// AfterResponse, if it succeeded.
+func (writer *responseWriter) finish(ctx context.Context) {
+	values := writer.values
+	if len(values.after) == 0 || writer.failed || values.Status >= 400 || Disconnected(ctx) {
+		return
+	}
+
+	writer.Flush()
+
+	detached := Detach(ctx)
+
+	for _, fn := range values.after {
+		runAfter(detached, fn)
+	}
+}
+
+func runAfter(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		if values := GetValues(ctx); values.Logger != nil {
+			values.Logger.Printf("%s: after response: panic: %v\n%s", values.TraceID, recovered, debug.Stack())
+		}
+	}()
+
+	fn(ctx)
+}
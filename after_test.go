@@ -0,0 +1,59 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAfterResponse(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter()
+	router.Logger = logger
+
+	var recorder *httptest.ResponseRecorder
+	var ran []string
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		AfterResponse(ctx, func(ctx context.Context) {
+			if !recorder.Flushed || recorder.Body.String() != "created" || ctx.Err() != nil {
+				t.Errorf("expected the response to be sent first, got %q flushed %t, %v", recorder.Body, recorder.Flushed, ctx.Err())
+			}
+
+			ran = append(ran, "invalidate")
+			panic("webhook down")
+		})
+
+		AfterResponse(ctx, func(ctx context.Context) {
+			ran = append(ran, "dispatch")
+		})
+
+		response.WriteHeader(http.StatusCreated)
+		response.Write([]byte("created"))
+	}, "POST")
+
+	router.Handle("/failed", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		AfterResponse(ctx, func(ctx context.Context) {
+			ran = append(ran, "failed")
+		})
+
+		response.WriteHeader(http.StatusConflict)
+	}, "POST")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/orders", nil))
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/failed", nil))
+
+	if strings.Join(ran, ",") != "invalidate,dispatch" {
+		t.Errorf("expected the hooks of the successful request alone, in order, got %v", ran)
+	}
+
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "after response: panic: webhook down") {
+		t.Errorf("expected the panic to be logged, got %q", logger.lines)
+	}
+}
@@ -0,0 +1,278 @@
+package ibnsina
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssetOptions configures an AssetManifest.
+type AssetOptions struct {
+	// Prefix is the URL path the assets are served under, e.g. "/static".
+	Prefix string
+
+	// Minify minifies .css files with MinifyCSS before they are
+	// fingerprinted. JavaScript cannot be minified safely without parsing
+	// it, so .js files are served as they are: minify them with a bundler.
+	Minify bool
+
+	// Precompress gzips the text assets, such as .css and .js files, that
+	// have no .gz sibling, so ServeAssets can send them compressed.
+	Precompress bool
+}
+
+// AssetManifest maps the paths of static assets to fingerprinted ones, with
+// a hash of the content in the name, such as "/static/app.3f0a1b2c.css" for
+// "/static/app.css". Fingerprinted assets never change, so ServeAssets lets
+// clients cache them forever, and a new version is fetched as soon as pages
+// link to it.
+//
+// Files with a .br or .gz sibling, such as "app.css.br", are sent in that
+// encoding to the clients accepting it. The siblings of minified files are
+// ignored since they no longer match.
+type AssetManifest struct {
+	fsys     fs.FS
+	paths    map[string]string
+	files    map[string]string
+	minified map[string][]byte
+	encoded  map[string][]encodedAsset
+	replacer *strings.Replacer
+}
+
+// encodedAsset is a content-encoded variant of an asset, read from the file
+// name or held in content.
+type encodedAsset struct {
+	encoding string
+	name     string
+	content  []byte
+}
+
+// assetEncodings maps the extensions of precompressed siblings to their
+// encodings, in order of preference.
+var assetEncodings = []struct{ extension, encoding string }{
+	{".br", "br"},
+	{".gz", "gzip"},
+}
+
+// compressible lists the extensions of the assets worth precompressing.
+var compressible = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true, ".html": true,
+	".svg": true, ".json": true, ".txt": true, ".xml": true,
+}
+
+// NewAssetManifest fingerprints the files of fsys.
+func NewAssetManifest(fsys fs.FS, options AssetOptions) (*AssetManifest, error) {
+	manifest := &AssetManifest{
+		fsys:     fsys,
+		paths:    make(map[string]string),
+		files:    make(map[string]string),
+		minified: make(map[string][]byte),
+		encoded:  make(map[string][]encodedAsset),
+	}
+
+	prefix := strings.TrimSuffix(options.Prefix, "/")
+	var replacements []string
+
+	siblings := make(map[string][]encodedAsset)
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		extension := path.Ext(name)
+
+		// archives such as data.tar.gz are assets of their own, only those
+		// next to the file they compress being variants of it
+		for _, variant := range assetEncodings {
+			if extension != variant.extension {
+				continue
+			}
+
+			base := strings.TrimSuffix(name, extension)
+			if info, err := fs.Stat(fsys, base); err == nil && !info.IsDir() {
+				siblings[base] = append(siblings[base], encodedAsset{encoding: variant.encoding, name: name})
+				return nil
+			}
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		if options.Minify && extension == ".css" {
+			content = MinifyCSS(content)
+			manifest.minified[name] = content
+		}
+
+		sum := sha256.Sum256(content)
+		logical := prefix + "/" + name
+		fingerprinted := prefix + "/" + strings.TrimSuffix(name, extension) + "." + hex.EncodeToString(sum[:4]) + extension
+
+		manifest.paths[logical] = fingerprinted
+		manifest.files[fingerprinted] = name
+		manifest.files[logical] = name
+
+		replacements = append(replacements, `"`+logical+`"`, `"`+fingerprinted+`"`, `'`+logical+`'`, `'`+fingerprinted+`'`)
+
+		if options.Precompress && compressible[extension] {
+			if compressed := gzipped(content); len(compressed) < len(content) {
+				manifest.encoded[name] = []encodedAsset{{encoding: "gzip", content: compressed}}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, variants := range siblings {
+		_, exists := manifest.files[prefix+"/"+name]
+		if _, minified := manifest.minified[name]; minified || !exists {
+			continue
+		}
+
+		for _, variant := range variants {
+			manifest.encoded[name] = slices.DeleteFunc(manifest.encoded[name], func(existing encodedAsset) bool {
+				return existing.encoding == variant.encoding
+			})
+			manifest.encoded[name] = append(manifest.encoded[name], variant)
+		}
+	}
+
+	manifest.replacer = strings.NewReplacer(replacements...)
+
+	return manifest, nil
+}
+
+// Path returns the fingerprinted path of the asset at path, or path when it
+// is not an asset.
+func (manifest *AssetManifest) Path(path string) string {
+	if fingerprinted, ok := manifest.paths[path]; ok {
+		return fingerprinted
+	}
+
+	return path
+}
+
+// Rewrite replaces the quoted asset paths in html, as in
+// href="/static/app.css", with their fingerprinted paths.
+func (manifest *AssetManifest) Rewrite(html []byte) []byte {
+	return []byte(manifest.replacer.Replace(string(html)))
+}
+
+// ServeAssets is a Handler serving the assets, to register under the
+// prefix:
+//
+//	router.Handle("/static/...", manifest.ServeAssets, "GET")
+//
+// Fingerprinted paths are cached for a year; the others are revalidated.
+// Assets with encoded variants are sent in the first encoding the client
+// accepts, br before gzip.
+func (manifest *AssetManifest) ServeAssets(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	name, ok := manifest.files[request.URL.Path]
+	if !ok {
+		writeError(ctx, response, &StatusError{Status: http.StatusNotFound, Message: "the requested resource could not be found"})
+		return
+	}
+
+	if _, logical := manifest.paths[request.URL.Path]; logical {
+		response.Header().Set("Cache-Control", "no-cache")
+	} else {
+		response.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
+	if variants := manifest.encoded[name]; len(variants) > 0 {
+		response.Header().Add("Vary", "Accept-Encoding")
+
+		if variant, ok := preferredEncoding(variants, request.Header.Get("Accept-Encoding")); ok {
+			response.Header().Set("Content-Encoding", variant.encoding)
+
+			// ServeContent would otherwise type the response after the sibling
+			if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+				response.Header().Set("Content-Type", contentType)
+			}
+
+			if variant.content != nil {
+				http.ServeContent(response, request, name, time.Time{}, bytes.NewReader(variant.content))
+			} else {
+				http.ServeFileFS(response, request, manifest.fsys, variant.name)
+			}
+
+			return
+		}
+	}
+
+	if content, ok := manifest.minified[name]; ok {
+		http.ServeContent(response, request, name, time.Time{}, bytes.NewReader(content))
+		return
+	}
+
+	http.ServeFileFS(response, request, manifest.fsys, name)
+}
+
+// preferredEncoding returns the variant of an asset to send to a client
+// sending acceptEncoding.
+func preferredEncoding(variants []encodedAsset, acceptEncoding string) (encodedAsset, bool) {
+	for _, preference := range assetEncodings {
+		for _, variant := range variants {
+			if variant.encoding == preference.encoding && acceptsEncoding(acceptEncoding, variant.encoding) {
+				return variant, true
+			}
+		}
+	}
+
+	return encodedAsset{}, false
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts
+// encoding, either by name or through "*".
+func acceptsEncoding(header string, encoding string) bool {
+	accepted := false
+
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(part, ";")
+
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token != encoding && token != "*" {
+			continue
+		}
+
+		quality := 1.0
+
+		if name, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+			// an invalid quality counts as q=0
+			quality, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		}
+
+		if token == encoding {
+			return quality > 0
+		}
+
+		accepted = quality > 0
+	}
+
+	return accepted
+}
+
+func gzipped(content []byte) []byte {
+	var buffer bytes.Buffer
+
+	writer, _ := gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+	writer.Write(content)
+	writer.Close()
+
+	return buffer.Bytes()
+}
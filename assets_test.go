@@ -0,0 +1,99 @@
+package ibnsina
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetEncodings(t *testing.T) {
+	script := strings.Repeat("console.log('hello');\n", 50)
+
+	static := fstest.MapFS{
+		"app.js":      {Data: []byte(script)},
+		"app.js.br":   {Data: []byte("brotli")},
+		"app.css":     {Data: []byte(strings.Repeat("body { margin: 0; }\n", 50))},
+		"logo.png":    {Data: []byte(strings.Repeat("\x89PNG", 50))},
+		"data.tar.gz": {Data: []byte("archive")},
+		"index.html":  {Data: []byte("<p>hi</p>")},
+	}
+
+	manifest, err := NewAssetManifest(static, AssetOptions{Prefix: "/static", Precompress: true})
+	if err != nil {
+		t.Fatalf("NewAssetManifest: %s", err)
+	}
+
+	router := NewRouter()
+	router.Handle("/static/...", manifest.ServeAssets, "GET")
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+		vary           bool
+	}{
+		{"/static/app.js", "gzip, br", "br", true},
+		{"/static/app.js", "gzip", "gzip", true},
+		{"/static/app.js", "*, br;q=0", "gzip", true},
+		{"/static/app.js", "", "", true},
+		{"/static/app.css", "br, gzip", "gzip", true},
+		{"/static/app.css", "gzip;q=0", "", true},
+		{"/static/logo.png", "gzip", "", false},
+		{"/static/index.html", "gzip", "", false},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", manifest.Path(test.path), nil)
+		if test.acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		header := recorder.Header()
+
+		if recorder.Code != 200 || header.Get("Content-Encoding") != test.encoding || (header.Get("Vary") == "Accept-Encoding") != test.vary {
+			t.Errorf("%s %q: expected encoding %q but was %d %v", test.path, test.acceptEncoding, test.encoding, recorder.Code, header)
+		}
+
+		if test.encoding != "" && !strings.HasPrefix(header.Get("Content-Type"), "text/") {
+			t.Errorf("%s %q: unexpected content type %q", test.path, test.acceptEncoding, header.Get("Content-Type"))
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/static/app.js", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	reader, err := gzip.NewReader(bytes.NewReader(recorder.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %s", err)
+	}
+
+	if content, _ := io.ReadAll(reader); string(content) != script {
+		t.Errorf("unexpected decompressed asset %q", content)
+	}
+
+	if manifest.Path("/static/app.js.br") != "/static/app.js.br" {
+		t.Errorf("expected encoded siblings not to be fingerprinted")
+	}
+
+	archive := manifest.Path("/static/data.tar.gz")
+	if archive == "/static/data.tar.gz" {
+		t.Errorf("expected a standalone archive to be fingerprinted as an asset")
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", archive, nil))
+
+	if recorder.Code != 200 || recorder.Body.String() != "archive" || recorder.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected the archive served as is but got %d %v %q", recorder.Code, recorder.Header(), recorder.Body)
+	}
+}
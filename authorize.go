@@ -0,0 +1,118 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Principal is the authenticated identity a request is made on behalf of.
+type Principal struct {
+	// ID identifies the principal, such as a user or an API key.
+	ID string
+
+	// Permissions are the permissions granted to the principal, such as
+	// "orders:write". "orders:*" grants every orders permission, "*" all.
+	Permissions []string
+
+	// Claims holds further attributes, such as an email or a plan.
+	Claims map[string]string
+}
+
+// Can reports whether the principal was granted permission.
+func (principal *Principal) Can(permission string) bool {
+	if principal == nil {
+		return false
+	}
+
+	for _, granted := range principal.Permissions {
+		if granted == "*" || granted == permission {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(granted, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetPrincipal records principal as the authenticated principal of the
+// request ctx belongs to. Authentication middlewares call it before
+// Authorize runs.
+func SetPrincipal(ctx context.Context, principal *Principal) {
+	if values := GetValues(ctx); values != nil {
+		values.principal = principal
+	}
+}
+
+// GetPrincipal returns the authenticated principal of the request ctx
+// belongs to, nil if the request is anonymous.
+func GetPrincipal(ctx context.Context) *Principal {
+	if values := GetValues(ctx); values != nil {
+		return values.principal
+	}
+
+	return nil
+}
+
+// Require declares the permissions the principal must all hold to use the
+// route, checked by Authorize. Requests reaching the route without going
+// through Authorize are answered with 500 Internal Server Error, so that a
+// forgotten Authorize fails closed.
+func (route *Route) Require(permissions ...string) *Route {
+	return route.update(func(meta *routeMeta) {
+		meta.permissions = append(slices.Clone(meta.permissions), permissions...)
+	})
+}
+
+var errNotAuthorized = errors.New("ibnsina: the route requires permissions but Authorize is not installed")
+
+// requireAuthorize wraps the handler of routes, refusing the requests to
+// routes declaring permissions that Authorize did not check.
+func requireAuthorize(handler Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if values := GetValues(ctx); values != nil && !values.authorized && values.meta != nil && len(values.meta.permissions) > 0 {
+			writeError(ctx, response, errNotAuthorized)
+			return
+		}
+
+		handler(ctx, response, request)
+	}
+}
+
+// Authorize is a middleware checking the permissions declared with
+// Route.Require against the principal set by an earlier authentication
+// middleware, answering with a problem+json 401 for anonymous requests and
+// 403 for principals missing a permission. Routes requiring nothing are
+// open to all.
+func Authorize(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		values := GetValues(ctx)
+		if values != nil {
+			values.authorized = true
+		}
+
+		if values == nil || values.meta == nil || len(values.meta.permissions) == 0 {
+			next(ctx, response, request)
+			return
+		}
+
+		if values.principal == nil {
+			WriteProblem(ctx, response, Problem{Status: http.StatusUnauthorized, Detail: "the request must be authenticated"})
+			return
+		}
+
+		for _, permission := range values.meta.permissions {
+			if !values.principal.Can(permission) {
+				WriteProblem(ctx, response, Problem{Status: http.StatusForbidden, Detail: "missing permission " + permission})
+				return
+			}
+		}
+
+		next(ctx, response, request)
+	}
+}
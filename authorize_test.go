@@ -0,0 +1,125 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	principals := map[string]*Principal{
+		"reader": {ID: "reader", Permissions: []string{"orders:read"}},
+		"admin":  {ID: "admin", Permissions: []string{"orders:*"}},
+	}
+
+	authenticate := func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if principal, ok := principals[request.Header.Get("Authorization")]; ok {
+				SetPrincipal(ctx, principal)
+			}
+
+			next(ctx, response, request)
+		}
+	}
+
+	router := NewRouter(authenticate, Authorize)
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(GetPrincipal(ctx).ID))
+	}
+
+	router.Handle("/orders", handler, "GET").Require("orders:read")
+	router.Handle("/orders", handler, "POST").Require("orders:read", "orders:write")
+	router.Handle("/health", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	tests := []struct {
+		method    string
+		path      string
+		principal string
+		status    int
+	}{
+		{"GET", "/health", "", http.StatusOK},
+		{"GET", "/orders", "", http.StatusUnauthorized},
+		{"GET", "/orders", "reader", http.StatusOK},
+		{"POST", "/orders", "reader", http.StatusForbidden},
+		{"POST", "/orders", "admin", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, nil)
+		request.Header.Set("Authorization", test.principal)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s %s as %q: expected status %d but was %d", test.method, test.path, test.principal, test.status, recorder.Code)
+		}
+
+		if test.status == http.StatusForbidden {
+			body := recorder.Body.String()
+
+			if recorder.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(body, `"title":"Forbidden","status":403,"detail":"missing permission orders:write"`) {
+				t.Errorf("unexpected problem %q", body)
+			}
+		}
+	}
+}
+
+func TestRequireWithoutAuthorize(t *testing.T) {
+	router := NewRouter()
+	router.TraceStages = true
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		t.Error("expected the handler not to be called without Authorize")
+	}, "GET").Require("orders:read")
+
+	router.Handle("/health", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	for path, status := range map[string]int{"/orders": http.StatusInternalServerError, "/health": http.StatusOK} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+		if recorder.Code != status {
+			t.Errorf("%s: expected status %d but was %d", path, status, recorder.Code)
+		}
+	}
+}
+
+// failingWriter is a ResponseWriter whose body writes fail, as when the
+// connection breaks.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write(data []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestWriteProblemFailure(t *testing.T) {
+	router := NewRouter()
+
+	var status int
+
+	router.Use(func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			next(ctx, response, request)
+			status = GetValues(ctx).Status
+		}
+	})
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if err := WriteProblem(ctx, response, Problem{Status: http.StatusConflict}); err == nil {
+			t.Error("expected the write error")
+		}
+	}, "GET")
+
+	serveAborted(t, router, failingWriter{httptest.NewRecorder()}, httptest.NewRequest("GET", "/", nil))
+
+	if status != http.StatusInternalServerError {
+		t.Errorf("expected the failed response to count as 500 but got %d", status)
+	}
+}
@@ -0,0 +1,164 @@
+package ibnsina
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff is a policy spacing out retries: the delays start at Min and grow
+// by Factor up to Max, each varied by up to Jitter of itself, either way, so
+// that clients failing together do not retry together.
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+
+	// Jitter is a fraction from 0 to 1.
+	Jitter float64
+}
+
+// Delay returns the delay before the retry following attempt, the first
+// being attempt 0. It never exceeds Max, the jitter only shortening the
+// delays it would lengthen beyond it.
+func (backoff Backoff) Delay(attempt int) time.Duration {
+	delay := float64(backoff.Min) * math.Pow(max(backoff.Factor, 1), float64(attempt))
+	if delay > float64(backoff.Max) {
+		delay = float64(backoff.Max)
+	}
+
+	if backoff.Jitter > 0 {
+		delay = min(delay*(1+backoff.Jitter*(2*rand.Float64()-1)), float64(backoff.Max))
+	}
+
+	return time.Duration(delay)
+}
+
+// String formats the policy as ParseBackoff parses it.
+func (backoff Backoff) String() string {
+	text := backoff.Min.String() + ".." + backoff.Max.String() + " x" + strconv.FormatFloat(max(backoff.Factor, 1), 'f', -1, 64)
+
+	if backoff.Jitter > 0 {
+		text += " jitter=" + strconv.FormatFloat(backoff.Jitter, 'f', -1, 64)
+	}
+
+	return text
+}
+
+// ParseBackoff parses a policy written as the range of the delays, followed
+// by the factor, 2 if omitted, and the jitter, none if omitted, as a
+// fraction or a percentage:
+//
+//	100ms..5s x2 jitter=0.2
+//	1s..1m jitter=10%
+//	500ms
+//
+// A single duration is a constant delay.
+func ParseBackoff(value string) (Backoff, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return Backoff{}, fmt.Errorf("invalid backoff %q", value)
+	}
+
+	backoff := Backoff{Factor: 2}
+
+	low, high, isRange := strings.Cut(fields[0], "..")
+
+	var err error
+	if backoff.Min, err = time.ParseDuration(low); err != nil {
+		return Backoff{}, fmt.Errorf("invalid backoff %q: %w", value, err)
+	}
+
+	backoff.Max = backoff.Min
+	if isRange {
+		if backoff.Max, err = time.ParseDuration(high); err != nil {
+			return Backoff{}, fmt.Errorf("invalid backoff %q: %w", value, err)
+		}
+	}
+
+	if backoff.Min <= 0 || backoff.Max < backoff.Min {
+		return Backoff{}, fmt.Errorf("invalid backoff %q: the delays must be positive and increasing", value)
+	}
+
+	for _, field := range fields[1:] {
+		if factor, ok := strings.CutPrefix(field, "x"); ok {
+			if backoff.Factor, err = strconv.ParseFloat(factor, 64); err != nil || backoff.Factor < 1 {
+				return Backoff{}, fmt.Errorf("invalid backoff %q: the factor must be a number of at least 1", value)
+			}
+
+			continue
+		}
+
+		if jitter, ok := strings.CutPrefix(field, "jitter="); ok {
+			if backoff.Jitter, err = parsePercent(jitter); err != nil {
+				return Backoff{}, fmt.Errorf("invalid backoff %q: %w", value, err)
+			}
+
+			continue
+		}
+
+		return Backoff{}, fmt.Errorf("invalid backoff %q: unexpected %q", value, field)
+	}
+
+	return backoff, nil
+}
+
+// Backoff returns the value of key as parsed by ParseBackoff.
+func (config *Config) Backoff(key string) (Backoff, error) {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return Backoff{}, fmt.Errorf("unknown key %s", key)
+	}
+
+	return ParseBackoff(value)
+}
+
+func (config *Config) BackoffOrDefault(key string, def Backoff) Backoff {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return def
+	}
+
+	backoff, err := ParseBackoff(value)
+	if err != nil {
+		return def
+	}
+
+	return backoff
+}
+
+func (config *Config) MustBackoff(key string) Backoff {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		panic(fmt.Sprintf("unknown key %s", key))
+	}
+
+	backoff, err := ParseBackoff(value)
+	if err != nil {
+		panic(fmt.Sprintf("key %q value is not a Backoff", key))
+	}
+
+	return backoff
+}
+
+func (config *Config) SetBackoff(key string, value Backoff) {
+	config.Update(func(tx *ConfigTx) {
+		tx.SetBackoff(key, value)
+	})
+}
+
+func (tx *ConfigTx) SetBackoff(key string, value Backoff) {
+	tx.set(key, value.String())
+}
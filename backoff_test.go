@@ -0,0 +1,64 @@
+package ibnsina
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBackoff(t *testing.T) {
+	tests := map[string]Backoff{
+		"100ms..5s x2 jitter=0.2": {Min: 100 * time.Millisecond, Max: 5 * time.Second, Factor: 2, Jitter: 0.2},
+		"1s..1m x1.5 jitter=10%":  {Min: time.Second, Max: time.Minute, Factor: 1.5, Jitter: 0.1},
+		"500ms":                   {Min: 500 * time.Millisecond, Max: 500 * time.Millisecond, Factor: 2},
+	}
+
+	for value, expected := range tests {
+		backoff, err := ParseBackoff(value)
+		if err != nil || backoff != expected {
+			t.Errorf("%s: expected %+v but got %+v %v", value, expected, backoff, err)
+		}
+
+		if again, err := ParseBackoff(backoff.String()); err != nil || again != backoff {
+			t.Errorf("%s: expected %q to parse back but got %+v %v", value, backoff, again, err)
+		}
+	}
+
+	if zero := (Backoff{Min: time.Second, Max: time.Minute}); zero.String() != "1s..1m0s x1" {
+		t.Errorf("expected a zero factor written as 1 but got %q", zero.String())
+	} else if _, err := ParseBackoff(zero.String()); err != nil {
+		t.Errorf("expected %q to parse but got %v", zero.String(), err)
+	}
+
+	for _, value := range []string{"", "5s..1s", "0s..1s", "1s..5s x0.5", "1s..5s jitter=2", "1s..5s fast"} {
+		if _, err := ParseBackoff(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Min: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+
+	for attempt, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if delay := backoff.Delay(attempt); delay != expected {
+			t.Errorf("attempt %d: expected %s but got %s", attempt, expected, delay)
+		}
+	}
+
+	backoff.Jitter = 0.2
+
+	for range 100 {
+		if delay := backoff.Delay(2); delay < 320*time.Millisecond || delay > 480*time.Millisecond {
+			t.Fatalf("expected the delay within 20%% of 400ms but got %s", delay)
+		}
+
+		if delay := backoff.Delay(10); delay < 800*time.Millisecond || delay > time.Second {
+			t.Fatalf("expected the delay within 20%% of 1s, and no more, but got %s", delay)
+		}
+	}
+
+	config := &Config{m: map[string]string{"retry.backoff": "100ms..5s x2 jitter=0.2"}}
+	if policy := config.MustBackoff("retry.backoff"); policy.Max != 5*time.Second || policy.Jitter != 0.2 {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+}
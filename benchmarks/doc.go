@@ -0,0 +1,5 @@
+// Package benchmarks holds the routing benchmarks and the allocation gate
+// that keeps them from regressing. Run them with:
+//
+//	go test -bench . -benchmem ./benchmarks
+package benchmarks
@@ -0,0 +1,102 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/i33ym/ibnsina"
+	"github.com/i33ym/ibnsina/ibnsinatest"
+)
+
+func noop(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+func passthrough(next ibnsina.Handler) ibnsina.Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		next(ctx, response, request)
+	}
+}
+
+// newRouter registers a table resembling a mid-sized API: static routes,
+// param routes with and without regexps and a wildcard, behind n routes that
+// never match.
+func newRouter(n int, middlewares ...ibnsina.Middleware) *ibnsina.Router {
+	router := ibnsina.NewRouter(middlewares...)
+
+	for index := 0; index < n; index++ {
+		router.Handle(fmt.Sprintf("/filler/%d/:id", index), noop, http.MethodGet)
+	}
+
+	router.Handle("/health", noop, http.MethodGet)
+	router.Handle("/users/:id", noop, http.MethodGet, http.MethodPut)
+	router.Handle("/users/:id|^[0-9]+$/posts/:post", noop, http.MethodGet)
+	router.Handle("/static/...", noop, http.MethodGet)
+
+	return router
+}
+
+func BenchmarkStatic(b *testing.B) {
+	ibnsinatest.Benchmark(b, newRouter(50), httptest.NewRequest(http.MethodGet, "/health", nil))
+}
+
+func BenchmarkParams(b *testing.B) {
+	ibnsinatest.Benchmark(b, newRouter(50), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+}
+
+func BenchmarkRegexpParams(b *testing.B) {
+	ibnsinatest.Benchmark(b, newRouter(50), httptest.NewRequest(http.MethodGet, "/users/42/posts/7", nil))
+}
+
+func BenchmarkDeepWildcard(b *testing.B) {
+	ibnsinatest.Benchmark(b, newRouter(50), httptest.NewRequest(http.MethodGet, "/static/a/b/c/d/e/f/g/h/i/j/site.css", nil))
+}
+
+func BenchmarkNotFound(b *testing.B) {
+	ibnsinatest.Benchmark(b, newRouter(50), httptest.NewRequest(http.MethodGet, "/missing/route", nil))
+}
+
+func BenchmarkMiddlewareChain(b *testing.B) {
+	middlewares := make([]ibnsina.Middleware, 10)
+	for index := range middlewares {
+		middlewares[index] = passthrough
+	}
+
+	ibnsinatest.Benchmark(b, newRouter(50, middlewares...), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+}
+
+func BenchmarkTableSize(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			ibnsinatest.Benchmark(b, newRouter(n), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+		})
+	}
+}
+
+// TestAllocs is the regression gate: the limits are the current numbers, so
+// a change that allocates more per request has to raise them explicitly.
+func TestAllocs(t *testing.T) {
+	tests := []struct {
+		path string
+		max  float64
+	}{
+		{"/health", 6},
+		{"/users/42", 8},
+		{"/static/a/b/c/site.css", 8},
+		{"/missing/route", 7},
+	}
+
+	router := newRouter(50)
+
+	for _, test := range tests {
+		ibnsinatest.AssertAllocs(t, router, httptest.NewRequest(http.MethodGet, test.path, nil), test.max)
+	}
+}
+
+func BenchmarkMatchCache(b *testing.B) {
+	router := newRouter(1000)
+	router.EnableMatchCache(100)
+
+	ibnsinatest.Benchmark(b, router, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+}
@@ -0,0 +1,332 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CodeInvalidType = "invalid_type"
+
+// ErrUnsupportedContentType is returned by Bind for bodies it cannot decode.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// MaxBodyBytes bounds the request bodies read by Bind.
+var MaxBodyBytes int64 = 1 << 20
+
+// Bind decodes the body of request into dst, a pointer to a struct, as JSON or
+// as a form depending on the Content-Type. Form fields are matched by their
+// `form` tag, falling back to the `json` tag and then the field name.
+//
+// Problems caused by the client are added to validator instead of being
+// returned: a value of the wrong type becomes a field error with the code
+// "invalid_type" keyed like Validate keys its errors, and malformed or empty
+// bodies become non-field errors. Only unsupported content types, bodies
+// larger than MaxBodyBytes, as ErrBodyTooLarge, and unreadable bodies are
+// returned as errors. For JSON, encoding/json stops at
+// the first type mismatch, so at most one of those is reported.
+func Bind(request *http.Request, dst any, validator *Validator) error {
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return bindJSON(request, dst, validator)
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		return bindForm(request, dst, validator)
+	}
+
+	return fmt.Errorf("%w %q", ErrUnsupportedContentType, mediaType)
+}
+
+// BindBody is a middleware decoding the body of requests to routes declaring
+// its type with Route.Accepts, then validating it with Validate. Invalid
+// bodies are answered with ValidationFailed, unsupported content types with
+// 415 Unsupported Media Type, bodies too large with 413 Request Entity Too
+// Large and unreadable ones with 400 Bad Request,
+// without calling the handler. The handler reads the body with Body.
+// Requests to other routes pass through.
+func BindBody(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		values := GetValues(ctx)
+		if values == nil || values.meta == nil || values.meta.body == nil {
+			next(ctx, response, request)
+			return
+		}
+
+		body := reflect.New(values.meta.body)
+
+		validator := GetValidator()
+		defer PutValidator(validator)
+
+		if err := Bind(request, body.Interface(), validator); err != nil {
+			switch {
+			case errors.Is(err, ErrUnsupportedContentType):
+				response.WriteHeader(http.StatusUnsupportedMediaType)
+				response.Write([]byte("the content type of the request body is not supported\n"))
+			case errors.Is(err, ErrBodyTooLarge):
+				response.WriteHeader(http.StatusRequestEntityTooLarge)
+				response.Write([]byte("the request body is too large\n"))
+			default:
+				response.WriteHeader(http.StatusBadRequest)
+				response.Write([]byte("the request body could not be read\n"))
+			}
+
+			return
+		}
+
+		if validator.Ok() {
+			validator.Merge(Validate(body.Interface()))
+		}
+
+		if !validator.Ok() {
+			ValidationFailed(ctx, response, validator)
+			return
+		}
+
+		values.body = body.Interface()
+		next(ctx, response, request)
+	}
+}
+
+// Body returns the request body decoded by BindBody, or the zero value when
+// there is none of type T.
+func Body[T any](ctx context.Context) T {
+	var zero T
+
+	values := GetValues(ctx)
+	if values == nil {
+		return zero
+	}
+
+	body, ok := values.body.(*T)
+	if !ok {
+		return zero
+	}
+
+	return *body
+}
+
+func bindJSON(request *http.Request, dst any, validator *Validator) error {
+	request.Body = http.MaxBytesReader(nil, request.Body, MaxBodyBytes)
+	decoder := json.NewDecoder(request.Body)
+
+	err := decoder.Decode(dst)
+	if err == nil {
+		if decoder.More() {
+			validator.AddNonFieldError("body must only contain a single JSON value")
+		}
+
+		return nil
+	}
+
+	if bodyTooLarge(err) {
+		return ErrBodyTooLarge
+	}
+
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &typeError):
+		if typeError.Field == "" {
+			validator.AddNonFieldError("body must be a JSON " + jsonTypeName(typeError.Type))
+			return nil
+		}
+
+		validator.AddFieldError(typeError.Field, CodeInvalidType, "must be a "+jsonTypeName(typeError.Type))
+	case errors.As(err, &syntaxError), errors.Is(err, io.ErrUnexpectedEOF):
+		validator.AddNonFieldError("body contains badly-formed JSON")
+	case errors.Is(err, io.EOF):
+		validator.AddNonFieldError("body must not be empty")
+	default:
+		var invalid *json.InvalidUnmarshalError
+		if errors.As(err, &invalid) {
+			return err
+		}
+
+		validator.AddNonFieldError("body contains invalid JSON")
+	}
+
+	return nil
+}
+
+// bodyTooLarge reports whether err comes from a body exceeding its limit,
+// that of Bind or of Decompress.
+func bodyTooLarge(err error) bool {
+	var maxBytes *http.MaxBytesError
+	return errors.As(err, &maxBytes) || errors.Is(err, ErrBodyTooLarge)
+}
+
+func bindForm(request *http.Request, dst any, validator *Validator) error {
+	request.Body = http.MaxBytesReader(nil, request.Body, MaxBodyBytes)
+
+	var err error
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "multipart/") {
+		err = request.ParseMultipartForm(MaxBodyBytes)
+	} else {
+		err = request.ParseForm()
+	}
+
+	if bodyTooLarge(err) {
+		return ErrBodyTooLarge
+	}
+
+	if err != nil {
+		validator.AddNonFieldError("body contains an invalid form")
+		return nil
+	}
+
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: destination must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	value = value.Elem()
+	typ := value.Type()
+
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		if name == "" {
+			name = fieldName(field)
+		}
+
+		if name == "-" {
+			continue
+		}
+
+		values, exists := request.Form[name]
+		if !exists {
+			continue
+		}
+
+		if err := setFormValue(value.Field(index), values); err != nil {
+			typ := value.Field(index).Type()
+			if typ.Kind() == reflect.Slice {
+				typ = typ.Elem()
+			}
+
+			validator.AddFieldError(name, CodeInvalidType, "must be a "+jsonTypeName(typ))
+		}
+	}
+
+	return nil
+}
+
+func setFormValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+
+		return setFormValue(field.Elem(), values)
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+
+		for index := range values {
+			if err := setFormValue(slice.Index(index), values[index:index+1]); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	value := ""
+	if len(values) > 0 {
+		value = values[0]
+	}
+
+	if _, isTime := field.Interface().(time.Time); isTime {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			// checkboxes submit "on"
+			if value != "on" {
+				return err
+			}
+
+			parsed = true
+		}
+
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("bind: unsupported form field type %s", field.Type())
+	}
+
+	return nil
+}
+
+func jsonTypeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "timestamp"
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	}
+
+	return "object"
+}
@@ -0,0 +1,182 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type testSignup struct {
+	Name    string   `json:"name" form:"name"`
+	Age     int      `json:"age" form:"age"`
+	Admin   bool     `json:"admin" form:"admin"`
+	Tags    []string `json:"tags" form:"tag"`
+	Scores  []int    `json:"scores" form:"score"`
+	Address struct {
+		Zip int `json:"zip"`
+	} `json:"address" form:"-"`
+}
+
+func TestBindJSON(t *testing.T) {
+	var tests = []struct {
+		Body string
+
+		ExpectedField   string
+		ExpectedMessage string
+		ExpectedError   string
+	}{
+		{`{"name": "ibn", "age": 42, "tags": ["a"]}`, "", "", ""},
+		{`{"name": "ibn", "age": "forty-two"}`, "age", "must be a whole number", ""},
+		{`{"address": {"zip": "abc"}}`, "address.zip", "must be a whole number", ""},
+		{`{"name": 42}`, "name", "must be a string", ""},
+		{`[1, 2]`, "", "", "body must be a JSON object"},
+		{`{"name": "ibn"`, "", "", "body contains badly-formed JSON"},
+		{``, "", "", "body must not be empty"},
+		{`{} {}`, "", "", "body must only contain a single JSON value"},
+	}
+
+	for _, test := range tests {
+		request, err := http.NewRequest("POST", "/signup", strings.NewReader(test.Body))
+		if err != nil {
+			t.Fatalf("NewRequest: %s", err)
+		}
+
+		request.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		var dst testSignup
+		validator := NewValidator()
+
+		if err := Bind(request, &dst, validator); err != nil {
+			t.Errorf("%s: unexpected error %s", test.Body, err)
+			continue
+		}
+
+		if test.ExpectedField != "" && validator.FieldErrors[test.ExpectedField] != test.ExpectedMessage {
+			t.Errorf("%s: expected %q for %q, got %v", test.Body, test.ExpectedMessage, test.ExpectedField, validator.FieldErrors)
+		}
+
+		if test.ExpectedField != "" && validator.FieldCodes[test.ExpectedField] != CodeInvalidType {
+			t.Errorf("%s: expected code %q, got %v", test.Body, CodeInvalidType, validator.FieldCodes)
+		}
+
+		if test.ExpectedError != "" && (len(validator.NonFieldErrors) != 1 || validator.NonFieldErrors[0] != test.ExpectedError) {
+			t.Errorf("%s: expected non field error %q, got %v", test.Body, test.ExpectedError, validator.NonFieldErrors)
+		}
+
+		if test.ExpectedField == "" && test.ExpectedError == "" && !validator.Ok() {
+			t.Errorf("%s: unexpected errors %v %v", test.Body, validator.FieldErrors, validator.NonFieldErrors)
+		}
+	}
+}
+
+func TestBindTooLarge(t *testing.T) {
+	large := strings.Repeat("i", int(MaxBodyBytes))
+
+	for contentType, body := range map[string]string{
+		"application/json":                  `{"name": "` + large + `"}`,
+		"application/x-www-form-urlencoded": "name=" + large,
+	} {
+		request := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+
+		if err := Bind(request, &testSignup{}, NewValidator()); err != ErrBodyTooLarge {
+			t.Errorf("%s: expected ErrBodyTooLarge but got %v", contentType, err)
+		}
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	form := url.Values{
+		"name":  {"ibn"},
+		"age":   {"old"},
+		"admin": {"on"},
+		"tag":   {"a", "b"},
+		"score": {"1", "x"},
+	}
+
+	request, err := http.NewRequest("POST", "/signup", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var dst testSignup
+	validator := NewValidator()
+
+	if err := Bind(request, &dst, validator); err != nil {
+		t.Fatalf("Bind: %s", err)
+	}
+
+	if dst.Name != "ibn" || !dst.Admin || len(dst.Tags) != 2 {
+		t.Errorf("unexpected result %+v", dst)
+	}
+
+	if validator.FieldErrors["age"] != "must be a whole number" || validator.FieldErrors["score"] != "must be a whole number" {
+		t.Errorf("unexpected errors %v", validator.FieldErrors)
+	}
+
+	request.Header.Set("Content-Type", "text/plain")
+
+	if err := Bind(request, &dst, NewValidator()); err == nil {
+		t.Errorf("expected an error for an unsupported content type")
+	}
+}
+
+type testCreateUser struct {
+	Name  string `json:"name" validate:"required,min=2"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+func TestBindBody(t *testing.T) {
+	router := NewRouter(BindBody)
+
+	router.Handle("/users", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		user := Body[testCreateUser](ctx)
+		response.Write([]byte(user.Name + " " + user.Email))
+	}, http.MethodPost).Accepts(testCreateUser{})
+
+	router.Handle("/ping", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("pong"))
+	}, http.MethodPost)
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/users", "application/json", `{"name": "ibn", "email": "ibn@example.com"}`, http.StatusOK, "ibn ibn@example.com"},
+		{"/users", "application/json", `{"name": "i", "email": "ibn@example.com"}`, http.StatusUnprocessableEntity, `"name"`},
+		{"/users", "application/json", `{"name": 42}`, http.StatusUnprocessableEntity, `"invalid_type"`},
+		{"/users", "text/plain", `ibn`, http.StatusUnsupportedMediaType, ""},
+		{"/users", "application/json", `{"name": "` + strings.Repeat("i", int(MaxBodyBytes)) + `"}`, http.StatusRequestEntityTooLarge, "too large"},
+		{"/ping", "text/plain", `ping`, http.StatusOK, "pong"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		request.Header.Set("Content-Type", test.contentType)
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d but was %d", test.body, test.expectedStatus, response.Code)
+			continue
+		}
+
+		if !strings.Contains(response.Body.String(), test.expectedBody) {
+			t.Errorf("%s: expected body containing %q but was %q", test.body, test.expectedBody, response.Body.String())
+		}
+	}
+
+	if user := Body[testCreateUser](context.Background()); user.Name != "" {
+		t.Errorf("expected no body outside of a request, got %+v", user)
+	}
+}
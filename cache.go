@@ -0,0 +1,342 @@
+package ibnsina
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOptions configures NewCache.
+type CacheOptions struct {
+	// Store holds the cached values, such as a RedisStore shared by
+	// replicas. If nil, a MemoryStore of MaxEntries keys is used.
+	Store Store
+
+	// MaxEntries bounds the default MemoryStore, 10000 keys if zero, the
+	// least recently used being evicted first.
+	MaxEntries int
+
+	// TTL is how long values are cached when set without one, 5 minutes if
+	// zero.
+	TTL time.Duration
+
+	// Prefix is prepended to the keys in Store, "cache:" if empty, so that
+	// the cache can share a store with other middlewares.
+	Prefix string
+}
+
+// Cache caches values, such as rendered fragments or the results of
+// expensive queries, in a Store. Handlers reach the cache of the router,
+// Router.Cache, with GetCache.
+type Cache struct {
+	store  Store
+	ttl    time.Duration
+	prefix string
+
+	mu    sync.Mutex
+	loads map[string]*cacheLoad
+
+	hits       atomic.Int64
+	misses     atomic.Int64
+	loaded     atomic.Int64
+	loadErrors atomic.Int64
+}
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	// Hits and Misses count the lookups with Get and Load.
+	Hits   int64
+	Misses int64
+
+	// Loads counts the calls of the load functions of Load, LoadErrors
+	// those that failed.
+	Loads      int64
+	LoadErrors int64
+
+	// Evictions counts the values evicted for MaxEntries, with the default
+	// store only.
+	Evictions int64
+}
+
+// cacheLoad is a call of a load function, shared by concurrent Loads of the
+// same key.
+type cacheLoad struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+func NewCache(options CacheOptions) *Cache {
+	if options.Store == nil {
+		if options.MaxEntries == 0 {
+			options.MaxEntries = 10000
+		}
+
+		store := NewMemoryStore()
+		store.MaxEntries = options.MaxEntries
+		options.Store = store
+	}
+
+	if options.TTL == 0 {
+		options.TTL = 5 * time.Minute
+	}
+
+	if options.Prefix == "" {
+		options.Prefix = "cache:"
+	}
+
+	return &Cache{store: options.Store, ttl: options.TTL, prefix: options.Prefix, loads: make(map[string]*cacheLoad)}
+}
+
+// GetCache returns the cache of the router serving the request ctx belongs
+// to, nil if there is none.
+func GetCache(ctx context.Context) *Cache {
+	if values := GetValues(ctx); values != nil {
+		return values.cache
+	}
+
+	return nil
+}
+
+// Get returns the value cached under key, or ErrNotStored.
+func (cache *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := cache.store.Get(ctx, cache.prefix+key)
+
+	switch {
+	case err == nil:
+		cache.hits.Add(1)
+	case errors.Is(err, ErrNotStored):
+		cache.misses.Add(1)
+	}
+
+	return value, err
+}
+
+// Set caches value under key for ttl, the TTL of the cache if zero.
+func (cache *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = cache.ttl
+	}
+
+	return cache.store.Set(ctx, cache.prefix+key, value, ttl)
+}
+
+// Delete removes the value cached under key, if any.
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.store.Delete(ctx, cache.prefix+key)
+}
+
+// Load returns the value cached under key or, on a miss, the value returned
+// by load, cached for ttl, the TTL of the cache if zero:
+//
+//	data, err := ibnsina.GetCache(ctx).Load(ctx, "pricing", time.Minute, func(ctx context.Context) ([]byte, error) {
+//		return json.Marshal(pricing.Compute(ctx))
+//	})
+//
+// Concurrent Loads of a key missing from the cache wait for a single call of
+// load, made with the context of the first one, and share its result, so an
+// expired value does not cause a stampede. Errors of load are not cached.
+// A failing store is bypassed: load is called and its error returned, if
+// any.
+func (cache *Cache) Load(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if value, err := cache.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	cache.mu.Lock()
+
+	if call, ok := cache.loads[key]; ok {
+		cache.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call := &cacheLoad{done: make(chan struct{})}
+	cache.loads[key] = call
+
+	cache.mu.Unlock()
+
+	defer func() {
+		cache.mu.Lock()
+		delete(cache.loads, key)
+		cache.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	cache.loaded.Add(1)
+
+	call.value, call.err = load(ctx)
+	if call.err != nil {
+		cache.loadErrors.Add(1)
+		return nil, call.err
+	}
+
+	cache.Set(ctx, key, call.value, ttl)
+
+	return call.value, nil
+}
+
+// Stats returns the counters of the cache since it was created.
+func (cache *Cache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:       cache.hits.Load(),
+		Misses:     cache.misses.Load(),
+		Loads:      cache.loaded.Load(),
+		LoadErrors: cache.loadErrors.Load(),
+	}
+
+	if store, ok := cache.store.(*MemoryStore); ok {
+		stats.Evictions = store.Evicted()
+	}
+
+	return stats
+}
+
+// responseCacheKey is the key of the response to request varying on the
+// headers in vary, beside those of the key of Coalesce, hashed so that the
+// credentials it varies on are not written to the store.
+func responseCacheKey(request *http.Request, vary []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(coalesceKey(request)))
+
+	for _, name := range vary {
+		hash.Write([]byte{0})
+		hash.Write([]byte(name))
+		hash.Write([]byte{':'})
+		hash.Write([]byte(strings.Join(request.Header.Values(name), ",")))
+	}
+
+	return "response:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedResponse is a response as kept by ResponseCache. Responses varying
+// on headers outside of the key of Coalesce are kept under a secondary key
+// per variant, the primary key only holding the names of these headers.
+type cachedResponse struct {
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	Vary   []string    `json:"vary,omitempty"`
+}
+
+// ResponseCache is a middleware caching the successful responses to GET
+// requests in cache for ttl, the TTL of the cache if zero, and serving the
+// identical requests that follow from it, as told apart by Coalesce and the
+// headers named in the Vary header of responses, such as the tenant header
+// of Tenants. Responses are cached when their status is 200 OK and they
+// neither set cookies, vary on "*" nor have a Cache-Control of private or
+// no-store. Requests are annotated with cache=hit or cache=miss.
+//
+// Responses are buffered entirely, so ResponseCache should not wrap
+// streaming endpoints. Wrap it with Coalesce to spare expired entries from
+// stampedes.
+func ResponseCache(cache *Cache, ttl time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if request.Method != http.MethodGet {
+				next(ctx, response, request)
+				return
+			}
+
+			key := responseCacheKey(request, nil)
+
+			cached, ok := cachedResponseOf(ctx, cache, key)
+			if ok && len(cached.Vary) > 0 {
+				cached, ok = cachedResponseOf(ctx, cache, responseCacheKey(request, cached.Vary))
+			}
+
+			if ok {
+				Annotate(ctx, "cache", "hit")
+
+				buffered := bufferedResponse{header: cached.Header, status: http.StatusOK}
+				buffered.body.Write(cached.Body)
+				buffered.writeTo(response)
+
+				return
+			}
+
+			Annotate(ctx, "cache", "miss")
+
+			buffered := &bufferedResponse{header: make(http.Header)}
+			next(ctx, buffered, request)
+
+			if cacheable(buffered) {
+				storeResponse(ctx, cache, ttl, request, response.Header(), buffered)
+			}
+
+			buffered.writeTo(response)
+		}
+	}
+}
+
+func cachedResponseOf(ctx context.Context, cache *Cache, key string) (cachedResponse, bool) {
+	var cached cachedResponse
+
+	data, err := cache.Get(ctx, key)
+	if err != nil || json.Unmarshal(data, &cached) != nil {
+		return cached, false
+	}
+
+	return cached, true
+}
+
+// storeResponse caches the response to request, whose header is that of the
+// response sent, to which the buffered one is added.
+func storeResponse(ctx context.Context, cache *Cache, ttl time.Duration, request *http.Request, header http.Header, buffered *bufferedResponse) {
+	vary, ok := responseVary(header, buffered.header)
+	if !ok {
+		return
+	}
+
+	vary = slices.DeleteFunc(vary, func(name string) bool {
+		return slices.Contains(coalesceHeaders, name)
+	})
+
+	key := responseCacheKey(request, nil)
+
+	if len(vary) > 0 {
+		data, err := json.Marshal(cachedResponse{Vary: vary})
+		if err != nil {
+			return
+		}
+
+		cache.Set(ctx, key, data, ttl)
+		key = responseCacheKey(request, vary)
+	}
+
+	if data, err := json.Marshal(cachedResponse{Header: buffered.header, Body: buffered.body.Bytes()}); err == nil {
+		cache.Set(ctx, key, data, ttl)
+	}
+}
+
+func cacheable(response *bufferedResponse) bool {
+	if response.status != http.StatusOK && response.status != 0 {
+		return false
+	}
+
+	if response.header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	for _, directive := range strings.Split(response.header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "private", "no-store":
+			return false
+		}
+	}
+
+	return true
+}
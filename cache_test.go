@@ -0,0 +1,224 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheLoad(t *testing.T) {
+	const callers = 8
+
+	ctx := context.Background()
+	cache := NewCache(CacheOptions{})
+
+	release := make(chan struct{})
+
+	var loads atomic.Int32
+	load := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("computed"), nil
+	}
+
+	values := make([][]byte, callers)
+
+	var wg sync.WaitGroup
+	for index := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[index], _ = cache.Load(ctx, "pricing", time.Minute, load)
+		}()
+	}
+
+	for cache.Stats().Misses < callers {
+		time.Sleep(time.Millisecond)
+	}
+
+	// let the callers wait for the load in progress
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, value := range values {
+		if string(value) != "computed" {
+			t.Errorf("expected the loaded value but got %q", value)
+		}
+	}
+
+	if value, err := cache.Load(ctx, "pricing", time.Minute, load); string(value) != "computed" || err != nil {
+		t.Errorf("expected the cached value but got %q %v", value, err)
+	}
+
+	stats := cache.Stats()
+	if loads.Load() != 1 || stats.Loads != 1 || stats.Hits != 1 || stats.Misses != callers {
+		t.Errorf("expected a single load, got %d and %+v", loads.Load(), stats)
+	}
+
+	failure := errors.New("database down")
+	if _, err := cache.Load(ctx, "quote", 0, func(ctx context.Context) ([]byte, error) { return nil, failure }); err != failure {
+		t.Errorf("expected the load error but got %v", err)
+	}
+
+	if _, err := cache.Get(ctx, "quote"); !errors.Is(err, ErrNotStored) || cache.Stats().LoadErrors != 1 {
+		t.Errorf("expected the failed load not to be cached, got %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache(CacheOptions{MaxEntries: 2})
+
+	cache.Set(ctx, "a", []byte("1"), 0)
+	cache.Set(ctx, "b", []byte("2"), 0)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := cache.Get(ctx, "b"); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected the least recently used value evicted, got %v", err)
+	}
+
+	if _, err := cache.Get(ctx, "a"); err != nil {
+		t.Errorf("expected the recently used value kept, got %v", err)
+	}
+
+	if evictions := cache.Stats().Evictions; evictions != 1 {
+		t.Errorf("expected 1 eviction but got %d", evictions)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+
+	router := NewRouter()
+	router.Cache = NewCache(CacheOptions{})
+
+	cached := router.Group(ResponseCache(router.Cache, time.Minute))
+
+	cached.Handle("/prices", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		calls.Add(1)
+
+		if GetCache(ctx) == nil {
+			t.Error("expected handlers to reach the cache of the router")
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.Write([]byte(`{"tea":3}`))
+	}, "GET")
+
+	cached.Handle("/account", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		calls.Add(1)
+		response.Header().Set("Cache-Control", "private")
+	}, "GET")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	serve("/prices")
+
+	hit := serve("/prices")
+	if hit.Body.String() != `{"tea":3}` || hit.Header().Get("Content-Type") != "application/json" || calls.Load() != 1 {
+		t.Errorf("expected the cached response, got %q %v after %d calls", hit.Body, hit.Header(), calls.Load())
+	}
+
+	serve("/account")
+	serve("/account")
+
+	if calls.Load() != 3 {
+		t.Errorf("expected private responses not to be cached, got %d calls", calls.Load())
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	var calls atomic.Int32
+
+	router := NewRouter(Tenants(TenantOptions{Resolver: testTenants{"acme": "acme", "globex": "globex"}, Header: "X-Tenant"}))
+	router.Cache = NewCache(CacheOptions{})
+
+	cached := router.Group(ResponseCache(router.Cache, time.Minute))
+
+	cached.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		calls.Add(1)
+		response.Write([]byte(Tenant(ctx)))
+	}, "GET")
+
+	cached.Handle("/anything", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		calls.Add(1)
+		response.Header().Set("Vary", "*")
+	}, "GET")
+
+	serve := func(path, tenant string) string {
+		request := httptest.NewRequest("GET", path, nil)
+		request.Header.Set("X-Tenant", tenant)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder.Body.String()
+	}
+
+	for _, tenant := range []string{"acme", "globex", "acme", "globex"} {
+		if body := serve("/orders", tenant); body != tenant {
+			t.Errorf("expected the orders of %s but got %q", tenant, body)
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("expected a cached response per tenant, got %d calls", calls.Load())
+	}
+
+	serve("/anything", "acme")
+	serve("/anything", "acme")
+
+	if calls.Load() != 4 {
+		t.Errorf("expected responses varying on * not to be cached, got %d calls", calls.Load())
+	}
+}
+
+// keyRecordingStore is a MemoryStore recording the keys set.
+type keyRecordingStore struct {
+	*MemoryStore
+	keys []string
+}
+
+func (store *keyRecordingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	store.keys = append(store.keys, key)
+	return store.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	store := &keyRecordingStore{MemoryStore: NewMemoryStore()}
+
+	router := NewRouter(ResponseCache(NewCache(CacheOptions{Store: store}), time.Minute))
+
+	router.Handle("/me", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("alice"))
+	}, "GET")
+
+	request := httptest.NewRequest("GET", "/me", nil)
+	request.Header.Set("Authorization", "Bearer secret-token")
+	request.Header.Set("Cookie", "session=secret-cookie")
+	request.Header.Set("Accept-Language", "uz-UZ")
+
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if len(store.keys) != 1 {
+		t.Fatalf("expected the response to be cached but got keys %q", store.keys)
+	}
+
+	for _, value := range []string{"secret-token", "secret-cookie", "uz-UZ"} {
+		if strings.Contains(store.keys[0], value) {
+			t.Errorf("expected %q not to appear in the key %q", value, store.keys[0])
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package ibnsina
+
+import "time"
+
+// Clock tells the time. Routers and configs read it through Clock, so tests
+// can replace it with a fake one, such as ibnsinatest.Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by time.Now.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+
+	return clock
+}
@@ -0,0 +1,221 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CoalesceOptions configures Coalesce.
+type CoalesceOptions struct {
+	// Key returns the key of requests, identical requests having the same
+	// key. By default, requests are identical when they have the same
+	// method, host, path and query, whatever the order of its parameters,
+	// and the same Accept, Accept-Encoding, Accept-Language, Authorization
+	// and Cookie headers, so that responses are never shared across users.
+	Key func(request *http.Request) string
+}
+
+// Coalesce is a middleware serving concurrent identical GET and HEAD
+// requests with a single execution of their handler, whose response is
+// buffered and sent to all of them, protecting expensive read endpoints
+// from stampedes when a cache expires. Requests served with the response
+// of another are annotated with coalesced=true. Responses with a Vary
+// header are only shared with the requests having the same values for the
+// headers it names, the others running the handler themselves.
+//
+// The handler goes on when the client that started it goes away, for the
+// others waiting, and is cancelled once none is left. Streaming responses
+// are buffered entirely, so Coalesce should not wrap streaming endpoints.
+func Coalesce(options CoalesceOptions) Middleware {
+	if options.Key == nil {
+		options.Key = coalesceKey
+	}
+
+	var mu sync.Mutex
+	calls := make(map[string]*coalescedCall)
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if request.Method != http.MethodGet && request.Method != http.MethodHead {
+				next(ctx, response, request)
+				return
+			}
+
+			key := options.Key(request)
+
+			mu.Lock()
+
+			call, coalesced := calls[key]
+			if !coalesced {
+				call = &coalescedCall{bufferedResponse: bufferedResponse{header: make(http.Header)}, request: request, done: make(chan struct{})}
+				call.ctx, call.cancel = context.WithCancel(context.WithoutCancel(ctx))
+				calls[key] = call
+			}
+
+			call.join()
+
+			mu.Unlock()
+
+			stop := context.AfterFunc(ctx, call.leave)
+			defer stop()
+
+			if coalesced {
+				select {
+				case <-call.done:
+				case <-ctx.Done():
+					return
+				}
+
+				if !call.panicked && !call.shares(request, response.Header()) {
+					next(ctx, response, request)
+					return
+				}
+
+				Annotate(ctx, "coalesced", "true")
+			} else {
+				call.run(next, request, func() {
+					mu.Lock()
+					delete(calls, key)
+					mu.Unlock()
+				})
+			}
+
+			call.replay(ctx, response)
+		}
+	}
+}
+
+// coalesceHeaders are the request headers coalesceKey tells requests apart
+// by.
+var coalesceHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cookie"}
+
+func coalesceKey(request *http.Request) string {
+	var key strings.Builder
+
+	key.WriteString(request.Method)
+	key.WriteByte(' ')
+	key.WriteString(request.Host)
+	key.WriteString(request.URL.EscapedPath())
+	key.WriteByte('?')
+	key.WriteString(request.URL.Query().Encode())
+
+	for _, name := range coalesceHeaders {
+		key.WriteByte(0)
+		key.WriteString(strings.Join(request.Header.Values(name), ","))
+	}
+
+	return key.String()
+}
+
+// responseVary returns the sorted names of the request headers the responses
+// with headers vary on, and false when one of them varies on "*".
+func responseVary(headers ...http.Header) ([]string, bool) {
+	var vary []string
+
+	for _, header := range headers {
+		for _, value := range header.Values("Vary") {
+			for _, name := range strings.Split(value, ",") {
+				name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+
+				switch {
+				case name == "*":
+					return nil, false
+				case name != "" && !slices.Contains(vary, name):
+					vary = append(vary, name)
+				}
+			}
+		}
+	}
+
+	slices.Sort(vary)
+
+	return vary, true
+}
+
+// sameVariant reports whether requests a and b have the same values for the
+// headers in vary.
+func sameVariant(a, b *http.Request, vary []string) bool {
+	for _, name := range vary {
+		if !slices.Equal(a.Header.Values(name), b.Header.Values(name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// coalescedCall is a handler execution shared by identical requests, and
+// the response it buffers.
+type coalescedCall struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// request is the request running the handler.
+	request *http.Request
+
+	mu      sync.Mutex
+	waiting int
+
+	// done is closed once the response is complete, or panicked is set.
+	done     chan struct{}
+	panicked bool
+
+	bufferedResponse
+}
+
+func (call *coalescedCall) join() {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+
+	call.waiting++
+}
+
+// leave cancels the handler once no request waits for it anymore.
+func (call *coalescedCall) leave() {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+
+	call.waiting--
+	if call.waiting == 0 {
+		call.cancel()
+	}
+}
+
+// run executes next, then calls release and completes the call, even when
+// next panics, in which case the panic goes on.
+func (call *coalescedCall) run(next Handler, request *http.Request, release func()) {
+	defer call.cancel()
+
+	call.panicked = true
+
+	defer func() {
+		release()
+		close(call.done)
+	}()
+
+	next(call.ctx, call, request.WithContext(call.ctx))
+
+	call.panicked = false
+}
+
+// shares reports whether the response of the call can be sent to request,
+// whose own response has header, which it cannot when it varies on headers
+// request has other values of.
+func (call *coalescedCall) shares(request *http.Request, header http.Header) bool {
+	vary, ok := responseVary(header, call.header)
+	return ok && sameVariant(call.request, request, vary)
+}
+
+// replay sends the buffered response.
+func (call *coalescedCall) replay(ctx context.Context, response http.ResponseWriter) {
+	if call.panicked {
+		writeError(ctx, response, errors.New("coalesce: the shared handler panicked"))
+		return
+	}
+
+	call.writeTo(response)
+}
@@ -0,0 +1,160 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForRequests waits until n requests share the call response belongs to.
+func waitForRequests(response http.ResponseWriter, n int) {
+	call := response.(*coalescedCall)
+
+	for {
+		call.mu.Lock()
+		waiting := call.waiting
+		call.mu.Unlock()
+
+		if waiting >= n {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	const requests = 8
+
+	var executions atomic.Int32
+
+	router := NewRouter(Coalesce(CoalesceOptions{}))
+
+	router.Handle("/report", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		executions.Add(1)
+		waitForRequests(response, requests)
+
+		response.Header().Set("Content-Type", "text/plain")
+		response.WriteHeader(http.StatusAccepted)
+		response.Write([]byte("expensive"))
+	}, "GET")
+
+	recorders := make([]*httptest.ResponseRecorder, requests)
+
+	var wg sync.WaitGroup
+	for index := range recorders {
+		recorders[index] = httptest.NewRecorder()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(recorders[index], httptest.NewRequest("GET", "/report?b=2&a=1", nil))
+		}()
+	}
+	wg.Wait()
+
+	if n := executions.Load(); n != 1 {
+		t.Errorf("expected a single execution but got %d", n)
+	}
+
+	traceIDs := map[string]bool{}
+
+	for _, recorder := range recorders {
+		if recorder.Code != http.StatusAccepted || recorder.Body.String() != "expensive" || recorder.Header().Get("Content-Type") != "text/plain" {
+			t.Errorf("unexpected response %d %q %v", recorder.Code, recorder.Body, recorder.Header())
+		}
+
+		traceIDs[recorder.Header().Get(TraceIDHeader)] = true
+	}
+
+	if len(traceIDs) != requests {
+		t.Errorf("expected every request to keep its trace ID, got %d", len(traceIDs))
+	}
+}
+
+func TestCoalesceVary(t *testing.T) {
+	var executions atomic.Int32
+
+	router := NewRouter(Tenants(TenantOptions{Resolver: testTenants{"acme": "acme", "globex": "globex"}, Header: "X-Tenant"}), Coalesce(CoalesceOptions{}))
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if executions.Add(1) == 1 {
+			waitForRequests(response, 2)
+		}
+
+		response.Write([]byte(Tenant(ctx)))
+	}, "GET")
+
+	tenants := []string{"acme", "globex"}
+	recorders := make([]*httptest.ResponseRecorder, len(tenants))
+
+	var wg sync.WaitGroup
+	for index, tenant := range tenants {
+		recorders[index] = httptest.NewRecorder()
+
+		request := httptest.NewRequest("GET", "/orders", nil)
+		request.Header.Set("X-Tenant", tenant)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(recorders[index], request)
+		}()
+	}
+	wg.Wait()
+
+	for index, tenant := range tenants {
+		if body := recorders[index].Body.String(); body != tenant {
+			t.Errorf("expected the orders of %s but got %q", tenant, body)
+		}
+	}
+
+	if n := executions.Load(); n != 2 {
+		t.Errorf("expected an execution per tenant but got %d", n)
+	}
+}
+
+func TestCoalesceCancel(t *testing.T) {
+	cancelled := make(chan error, 1)
+
+	router := NewRouter(Coalesce(CoalesceOptions{}))
+
+	router.Handle("/report", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+	}, "GET")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/report", nil).WithContext(ctx))
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("expected the handler to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the handler to be cancelled once its only client went away")
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	request := func(target string, authorization string) *http.Request {
+		request := httptest.NewRequest("GET", target, nil)
+		request.Header.Set("Authorization", authorization)
+		return request
+	}
+
+	if coalesceKey(request("/report?a=1&b=2", "alice")) != coalesceKey(request("/report?b=2&a=1", "alice")) {
+		t.Error("expected the order of query parameters not to matter")
+	}
+
+	if coalesceKey(request("/report", "alice")) == coalesceKey(request("/report", "bob")) {
+		t.Error("expected requests of different users not to be coalesced")
+	}
+}
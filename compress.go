@@ -0,0 +1,193 @@
+package ibnsina
+
+import (
+	"compress/gzip"
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CompressOptions configures Compress.
+type CompressOptions struct {
+	// MinSize is the size under which responses are not worth compressing,
+	// 1024 bytes if zero or less.
+	MinSize int
+
+	// Level is the gzip compression level, gzip.DefaultCompression if zero.
+	Level int
+}
+
+// Compress is a middleware gzipping the responses of clients accepting it.
+// Only textual content types, such as HTML, CSS, JavaScript and JSON, of at
+// least MinSize bytes are compressed; responses already encoded, partial
+// ones and those flushed before reaching MinSize, such as streams, are sent
+// as written.
+func Compress(options CompressOptions) Middleware {
+	if options.MinSize <= 0 {
+		options.MinSize = 1024
+	}
+
+	if options.Level == 0 {
+		options.Level = gzip.DefaultCompression
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.Header().Add("Vary", "Accept-Encoding")
+
+			if request.Method == http.MethodHead || !acceptsEncoding(request.Header.Get("Accept-Encoding"), "gzip") {
+				next(ctx, response, request)
+				return
+			}
+
+			writer := &compressWriter{ResponseWriter: response, options: options}
+
+			// not deferred: after a panic, what was held back is dropped for
+			// Recover to answer instead
+			next(ctx, writer, request)
+			writer.close()
+		}
+	}
+}
+
+// compressWriter holds back the response until MinSize bytes are written,
+// then decides whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	options CompressOptions
+	status  int
+	buffer  []byte
+	decided bool
+	gzip    *gzip.Writer
+}
+
+func (writer *compressWriter) WriteHeader(status int) {
+	if writer.decided {
+		writer.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	// informational responses are sent right away
+	if status < 200 {
+		writer.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if writer.status == 0 {
+		writer.status = status
+	}
+}
+
+func (writer *compressWriter) Write(data []byte) (int, error) {
+	if !writer.decided {
+		writer.buffer = append(writer.buffer, data...)
+		if len(writer.buffer) < writer.options.MinSize {
+			return len(data), nil
+		}
+
+		if err := writer.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(data), nil
+	}
+
+	if writer.gzip != nil {
+		return writer.gzip.Write(data)
+	}
+
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *compressWriter) Flush() {
+	if !writer.decided {
+		writer.decide(false)
+	}
+
+	if writer.gzip != nil {
+		writer.gzip.Flush()
+	}
+
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *compressWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// decide sends the header, compressing the response when large is set and
+// its type allows, then the buffered body.
+func (writer *compressWriter) decide(large bool) error {
+	writer.decided = true
+
+	header := writer.Header()
+
+	if header.Get("Content-Type") == "" && len(writer.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(writer.buffer))
+	}
+
+	status := writer.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if large && status != http.StatusNoContent && status != http.StatusNotModified && status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" && compressibleType(header.Get("Content-Type")) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+
+		writer.gzip, _ = gzip.NewWriterLevel(writer.ResponseWriter, writer.options.Level)
+	}
+
+	if writer.status != 0 {
+		writer.ResponseWriter.WriteHeader(writer.status)
+	}
+
+	buffer := writer.buffer
+	writer.buffer = nil
+
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	var err error
+	if writer.gzip != nil {
+		_, err = writer.gzip.Write(buffer)
+	} else {
+		_, err = writer.ResponseWriter.Write(buffer)
+	}
+
+	return err
+}
+
+func (writer *compressWriter) close() {
+	if !writer.decided {
+		writer.decide(false)
+	}
+
+	if writer.gzip != nil {
+		writer.gzip.Close()
+	}
+}
+
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package ibnsina
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"id":1},`, 200)
+
+	router := NewRouter(Compress(CompressOptions{}))
+
+	router.Handle("/json", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(http.StatusCreated)
+		response.Write([]byte(large[:500]))
+		response.Write([]byte(large[500:]))
+	}, "GET")
+
+	router.Handle("/small", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("<p>hi</p>"))
+	}, "GET")
+
+	router.Handle("/image", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "image/png")
+		response.Write([]byte(large))
+	}, "GET")
+
+	router.Handle("/stream", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "text/event-stream")
+		response.Write([]byte("data: 1\n\n"))
+		response.(http.Flusher).Flush()
+		response.Write([]byte(large))
+	}, "GET")
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		status         int
+		encoding       string
+		body           string
+	}{
+		{"/json", "gzip", http.StatusCreated, "gzip", large},
+		{"/json", "br", http.StatusCreated, "", large},
+		{"/small", "gzip", http.StatusOK, "", "<p>hi</p>"},
+		{"/image", "gzip", http.StatusOK, "", large},
+		{"/stream", "gzip", http.StatusOK, "", "data: 1\n\n" + large},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", test.path, nil)
+		request.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		body := recorder.Body.String()
+
+		if test.encoding == "gzip" {
+			reader, err := gzip.NewReader(recorder.Body)
+			if err != nil {
+				t.Fatalf("%s: gzip.NewReader: %s", test.path, err)
+			}
+
+			decompressed, _ := io.ReadAll(reader)
+			body = string(decompressed)
+		}
+
+		header := recorder.Header()
+
+		if recorder.Code != test.status || header.Get("Content-Encoding") != test.encoding || body != test.body || header.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s %q: unexpected response %d %v %q", test.path, test.acceptEncoding, recorder.Code, header, body)
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/small", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected the content type to be sniffed but was %q", recorder.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,57 @@
+package ibnsina
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrPreconditionRequired is returned by RequireIfMatch for requests
+	// without an If-Match header.
+	ErrPreconditionRequired = &StatusError{Status: http.StatusPreconditionRequired, Message: "the request must be conditional, with an If-Match header"}
+
+	// ErrPreconditionFailed is returned by RequireIfMatch when the resource
+	// changed since the client read it.
+	ErrPreconditionFailed = &StatusError{Status: http.StatusPreconditionFailed, Message: "the resource was modified since it was last read"}
+)
+
+// ETag returns a strong entity tag for a representation, e.g. "3f0a...".
+func ETag(representation []byte) string {
+	sum := sha256.Sum256(representation)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// RequireIfMatch guards updates against lost updates: it returns nil only
+// when the If-Match header of request lists currentETag, or is "*" and the
+// resource exists, currentETag being "" otherwise. It returns
+// ErrPreconditionRequired without If-Match and ErrPreconditionFailed on a
+// mismatch, which Typed handlers answer with 428 and 412.
+//
+// Tags are compared strongly, as RFC 9110 requires for If-Match: weak tags,
+// W/"...", never match.
+func RequireIfMatch(request *http.Request, currentETag string) error {
+	header := strings.Join(request.Header.Values("If-Match"), ",")
+	if strings.TrimSpace(header) == "" {
+		return ErrPreconditionRequired
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			if currentETag != "" {
+				return nil
+			}
+
+			continue
+		}
+
+		if !strings.HasPrefix(tag, "W/") && !strings.HasPrefix(currentETag, "W/") && tag == currentETag {
+			return nil
+		}
+	}
+
+	return ErrPreconditionFailed
+}
@@ -0,0 +1,36 @@
+package ibnsina
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireIfMatch(t *testing.T) {
+	current := ETag([]byte(`{"name":"ibn"}`))
+
+	tests := []struct {
+		ifMatch string
+		current string
+
+		expected error
+	}{
+		{"", current, ErrPreconditionRequired},
+		{current, current, nil},
+		{`"other", ` + current, current, nil},
+		{`"other"`, current, ErrPreconditionFailed},
+		{"W/" + current, current, ErrPreconditionFailed},
+		{"*", current, nil},
+		{"*", "", ErrPreconditionFailed},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("PUT", "/users/1", nil)
+		if test.ifMatch != "" {
+			request.Header.Set("If-Match", test.ifMatch)
+		}
+
+		if err := RequireIfMatch(request, test.current); err != test.expected {
+			t.Errorf("If-Match %s: expected %v but got %v", test.ifMatch, test.expected, err)
+		}
+	}
+}
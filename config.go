@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"math"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,11 +15,59 @@ import (
 )
 
 type Config struct {
+	// Clock resolves time values relative to "now", SystemClock if nil.
+	Clock Clock
+
+	// Snapshots is how many versions of the config are kept for Rollback,
+	// 10 if zero, the current one alone if negative.
+	Snapshots int
+
 	m  map[string]string
 	mu sync.RWMutex
+
+	// used holds the keys read or set, for Unused
+	used sync.Map
+
+	watchers []func(keys []string)
+	version  uint64
+	history  []configSnapshot
+}
+
+// ConfigOption configures NewConfig.
+type ConfigOption func(config *Config) error
+
+// StrictKeys makes NewConfig fail when the file holds keys other than keys,
+// so that stale and misspelled settings are caught at deploy time. Keys
+// ending with * allow any key starting with the rest, such as "slow.*" for
+// SlowThresholds:
+//
+//	config, err := ibnsina.NewConfig(file, ibnsina.StrictKeys("port", "timeout.request", "slow.*"))
+func StrictKeys(keys ...string) ConfigOption {
+	return func(config *Config) error {
+		var unknown []string
+
+		for key := range config.m {
+			if !slices.ContainsFunc(keys, func(allowed string) bool {
+				if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+					return strings.HasPrefix(key, prefix)
+				}
+
+				return key == allowed
+			}) {
+				unknown = append(unknown, key)
+			}
+		}
+
+		if len(unknown) > 0 {
+			slices.Sort(unknown)
+			return fmt.Errorf("unknown keys %s", strings.Join(unknown, ", "))
+		}
+
+		return nil
+	}
 }
 
-func NewConfig(file *os.File) (*Config, error) {
+func NewConfig(file *os.File, options ...ConfigOption) (*Config, error) {
 	config := &Config{
 		m: make(map[string]string),
 		// sync.Mutex can be used without initialization
@@ -49,6 +99,12 @@ func NewConfig(file *os.File) (*Config, error) {
 		config.m[line[:index]] = line[index+1:]
 	}
 
+	for _, option := range options {
+		if err := option(config); err != nil {
+			return nil, err
+		}
+	}
+
 	return config, nil
 }
 
@@ -66,11 +122,45 @@ func (config *Config) Log() string {
 	return buf.String()
 }
 
-func (config *Config) String(key string) (string, error) {
+// Unused returns the keys of the config that were never read nor set, in
+// order, typically misspelled ones. Call it once the application has read
+// its configuration:
+//
+//	if unused := config.Unused(); len(unused) > 0 {
+//		log.Printf("unused config keys: %s", strings.Join(unused, ", "))
+//	}
+func (config *Config) Unused() []string {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
+	var unused []string
+
+	for key := range config.m {
+		if _, ok := config.used.Load(key); !ok {
+			unused = append(unused, key)
+		}
+	}
+
+	slices.Sort(unused)
+
+	return unused
+}
+
+// get returns the value of key, marking it as used. It must be called with
+// config.mu held.
+func (config *Config) get(key string) (string, bool) {
+	config.used.Store(key, true)
+
 	value, exists := config.m[key]
+
+	return value, exists
+}
+
+func (config *Config) String(key string) (string, error) {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
 	if !exists {
 		return "", fmt.Errorf("unknown key %s", key)
 	}
@@ -82,7 +172,7 @@ func (config *Config) StringOrDefault(key string, def string) string {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
@@ -94,7 +184,7 @@ func (config *Config) MustString(key string) string {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Sprintf("unknown key %s !", key))
 	}
@@ -103,17 +193,16 @@ func (config *Config) MustString(key string) string {
 }
 
 func (config *Config) SetString(key string, value string) {
-	config.mu.Lock()
-	defer config.mu.Unlock()
-
-	config.m[key] = value
+	config.Update(func(tx *ConfigTx) {
+		tx.SetString(key, value)
+	})
 }
 
 func (config *Config) Int(key string) (int, error) {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return 0, fmt.Errorf("unknown key %s", key)
 	}
@@ -130,7 +219,7 @@ func (config *Config) IntOrDefault(key string, def int) int {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
@@ -147,7 +236,7 @@ func (config *Config) MustInt(key string) int {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Sprintf("unknown key %s !", key))
 	}
@@ -161,22 +250,21 @@ func (config *Config) MustInt(key string) int {
 }
 
 func (config *Config) SetInt(key string, value int) {
-	config.mu.Lock()
-	defer config.mu.Unlock()
-
-	config.m[key] = strconv.Itoa(value)
+	config.Update(func(tx *ConfigTx) {
+		tx.SetInt(key, value)
+	})
 }
 
 func (config *Config) Time(key string) (time.Time, error) {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return time.Time{}, fmt.Errorf("unknown key %s", key)
 	}
 
-	time, err := time.Parse(time.UnixDate, value)
+	time, err := config.parseTime(value)
 	if err != nil {
 		return time, err
 	}
@@ -188,12 +276,12 @@ func (config *Config) TimeOrDefault(key string, def time.Time) time.Time {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
 
-	time, err := time.Parse(time.UnixDate, value)
+	time, err := config.parseTime(value)
 	if err != nil {
 		return def
 	}
@@ -205,12 +293,12 @@ func (config *Config) MustTime(key string) time.Time {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Sprintf("unknown key %s", key))
 	}
 
-	time, err := time.Parse(time.UnixDate, value)
+	time, err := config.parseTime(value)
 	if err != nil {
 		panic(fmt.Sprintf("key %q value is not a Time", key))
 	}
@@ -218,18 +306,42 @@ func (config *Config) MustTime(key string) time.Time {
 	return time
 }
 
-func (config *Config) SetTime(key string, value time.Time) {
-	config.mu.Lock()
-	defer config.mu.Unlock()
+// parseTime parses value in time.UnixDate format, or as "now" optionally
+// followed by a signed duration, such as "now-24h", read from the Clock.
+func (config *Config) parseTime(value string) (time.Time, error) {
+	offset, ok := strings.CutPrefix(value, "now")
+	if !ok {
+		return time.Parse(time.UnixDate, value)
+	}
+
+	now := clockOrSystem(config.Clock).Now()
+	if offset == "" {
+		return now, nil
+	}
+
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+
+	duration, err := time.ParseDuration(offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return now.Add(duration), nil
+}
 
-	config.m[key] = value.Format(time.UnixDate)
+func (config *Config) SetTime(key string, value time.Time) {
+	config.Update(func(tx *ConfigTx) {
+		tx.SetTime(key, value)
+	})
 }
 
 func (config *Config) Bool(key string) (bool, error) {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return false, fmt.Errorf("unknown key %s", key)
 	}
@@ -254,7 +366,7 @@ func (config *Config) BoolOrDefault(key string, def bool) bool {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
@@ -279,7 +391,7 @@ func (config *Config) MustBool(key string) bool {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Sprintf("unknown key %s", key))
 	}
@@ -301,22 +413,16 @@ func (config *Config) MustBool(key string) bool {
 }
 
 func (config *Config) SetBool(key string, value bool) {
-	str := "false"
-	if value {
-		str = "true"
-	}
-
-	config.mu.Lock()
-	defer config.mu.Unlock()
-
-	config.m[key] = str
+	config.Update(func(tx *ConfigTx) {
+		tx.SetBool(key, value)
+	})
 }
 
 func (config *Config) URL(key string) (*url.URL, error) {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return nil, fmt.Errorf("unknown key %s", key)
 	}
@@ -333,7 +439,7 @@ func (config *Config) URLOrDefault(key string, def *url.URL) *url.URL {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
@@ -350,7 +456,7 @@ func (config *Config) MustURL(key string) *url.URL {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Sprintf("unknown key %s", key))
 	}
@@ -364,17 +470,16 @@ func (config *Config) MustURL(key string) *url.URL {
 }
 
 func (config *Config) SetURL(key string, value *url.URL) {
-	config.mu.Lock()
-	defer config.mu.Unlock()
-
-	config.m[key] = value.String()
+	config.Update(func(tx *ConfigTx) {
+		tx.SetURL(key, value)
+	})
 }
 
 func (config *Config) Duration(key string) (time.Duration, error) {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return time.Duration(0), fmt.Errorf("unknown key %s", key)
 	}
@@ -391,7 +496,7 @@ func (config *Config) DurationOrDefault(key string, def time.Duration) time.Dura
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		return def
 	}
@@ -408,7 +513,7 @@ func (config *Config) MustDuration(key string) time.Duration {
 	config.mu.RLock()
 	defer config.mu.RUnlock()
 
-	value, exists := config.m[key]
+	value, exists := config.get(key)
 	if !exists {
 		panic(fmt.Errorf("unknown key %s", key))
 	}
@@ -422,8 +527,159 @@ func (config *Config) MustDuration(key string) time.Duration {
 }
 
 func (config *Config) SetDuration(key string, value time.Duration) {
-	config.mu.Lock()
-	defer config.mu.Unlock()
+	config.Update(func(tx *ConfigTx) {
+		tx.SetDuration(key, value)
+	})
+}
+
+// Percent returns the value of key as a fraction from 0 to 1, written as a
+// percentage, "12%", or as the fraction itself, "0.12".
+func (config *Config) Percent(key string) (float64, error) {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return 0, fmt.Errorf("unknown key %s", key)
+	}
+
+	return parsePercent(value)
+}
+
+func (config *Config) PercentOrDefault(key string, def float64) float64 {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return def
+	}
+
+	percent, err := parsePercent(value)
+	if err != nil {
+		return def
+	}
+
+	return percent
+}
+
+func (config *Config) MustPercent(key string) float64 {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		panic(fmt.Sprintf("unknown key %s", key))
+	}
+
+	percent, err := parsePercent(value)
+	if err != nil {
+		panic(fmt.Sprintf("key %q value is not a Percent", key))
+	}
+
+	return percent
+}
+
+// SetPercent sets key to the fraction value, written as a percentage.
+func (config *Config) SetPercent(key string, value float64) {
+	config.Update(func(tx *ConfigTx) {
+		tx.SetPercent(key, value)
+	})
+}
+
+func parsePercent(value string) (float64, error) {
+	number, percentage := strings.CutSuffix(value, "%")
+
+	percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percent %q", value)
+	}
+
+	if percentage {
+		percent /= 100
+	}
+
+	if !(percent >= 0 && percent <= 1) {
+		return 0, fmt.Errorf("percent %q is not between 0%% and 100%%", value)
+	}
+
+	return percent, nil
+}
+
+// Ratio returns the value of key as a non-negative number, written as a
+// decimal, "1.5", or a quotient, "3/2".
+func (config *Config) Ratio(key string) (float64, error) {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return 0, fmt.Errorf("unknown key %s", key)
+	}
+
+	return parseRatio(value)
+}
+
+func (config *Config) RatioOrDefault(key string, def float64) float64 {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		return def
+	}
+
+	ratio, err := parseRatio(value)
+	if err != nil {
+		return def
+	}
+
+	return ratio
+}
+
+func (config *Config) MustRatio(key string) float64 {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	value, exists := config.get(key)
+	if !exists {
+		panic(fmt.Sprintf("unknown key %s", key))
+	}
+
+	ratio, err := parseRatio(value)
+	if err != nil {
+		panic(fmt.Sprintf("key %q value is not a Ratio", key))
+	}
+
+	return ratio
+}
+
+func (config *Config) SetRatio(key string, value float64) {
+	config.Update(func(tx *ConfigTx) {
+		tx.SetRatio(key, value)
+	})
+}
+
+func parseRatio(value string) (float64, error) {
+	numerator, denominator, quotient := strings.Cut(value, "/")
+
+	ratio, err := strconv.ParseFloat(strings.TrimSpace(numerator), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ratio %q", value)
+	}
+
+	if quotient {
+		divisor, err := strconv.ParseFloat(strings.TrimSpace(denominator), 64)
+		if err != nil || divisor == 0 {
+			return 0, fmt.Errorf("invalid ratio %q", value)
+		}
+
+		ratio /= divisor
+	}
+
+	if !(ratio >= 0 && ratio <= math.MaxFloat64) {
+		return 0, fmt.Errorf("ratio %q is not a non-negative number", value)
+	}
 
-	config.m[key] = value.String()
+	return ratio, nil
 }
@@ -0,0 +1,168 @@
+package ibnsina
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BindConfig returns a T, a struct, whose fields are read from the keys of
+// config named after prefix and the field, in upper snake case:
+//
+//	type DatabaseConfig struct {
+//		Host     string        // DB_HOST
+//		MaxConns int           // DB_MAX_CONNS
+//		Timeout  time.Duration // DB_TIMEOUT
+//		Replicas []string      `config:"READ_REPLICAS"` // DB_READ_REPLICAS, comma separated
+//		TLS      TLSConfig     // DB_TLS_CERT, DB_TLS_KEY...
+//	}
+//
+//	db, err := ibnsina.BindConfig[DatabaseConfig](config, "DB")
+//
+// The config tag names the key in place of the field, "-" skipping it, and
+// nested structs add their name to the prefix. Fields may be strings,
+// booleans, numbers, durations, times as read by Time, URLs, Backoff
+// policies and slices of those. Fields without a key keep their zero value;
+// the values that cannot be parsed are all reported in the error.
+func BindConfig[T any](config *Config, prefix string) (T, error) {
+	var bound T
+
+	value := reflect.ValueOf(&bound).Elem()
+	if value.Kind() != reflect.Struct {
+		return bound, fmt.Errorf("bind config: %T is not a struct", bound)
+	}
+
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	return bound, config.bind(value, prefix)
+}
+
+func (config *Config) bind(value reflect.Value, prefix string) error {
+	var errs []error
+
+	typ := value.Type()
+
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = upperSnakeCase(field.Name)
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "_" + name
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) && field.Type != reflect.TypeOf(Backoff{}) {
+			errs = append(errs, config.bind(value.Field(index), key))
+			continue
+		}
+
+		raw, exists := config.get(key)
+		if !exists {
+			continue
+		}
+
+		if err := config.setConfigValue(value.Field(index), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (config *Config) setConfigValue(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(duration))
+		return nil
+	case time.Time:
+		parsed, err := config.parseTime(value)
+		if err != nil {
+			return err
+		}
+
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	case Backoff:
+		backoff, err := ParseBackoff(value)
+		if err != nil {
+			return err
+		}
+
+		field.Set(reflect.ValueOf(backoff))
+		return nil
+	case *url.URL:
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	if field.Kind() == reflect.Slice {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+
+		for index, item := range items {
+			if err := config.setConfigValue(slice.Index(index), item); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	return setFormValue(field, []string{value})
+}
+
+// upperSnakeCase converts a field name to a key, such as MaxConns to
+// MAX_CONNS and TLSCert to TLS_CERT.
+func upperSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var builder strings.Builder
+
+	for index, r := range runes {
+		if index > 0 && unicode.IsUpper(r) {
+			previous := runes[index-1]
+			nextLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextLower) {
+				builder.WriteByte('_')
+			}
+		}
+
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+
+	return builder.String()
+}
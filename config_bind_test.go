@@ -0,0 +1,64 @@
+package ibnsina
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindConfig(t *testing.T) {
+	type TLSConfig struct {
+		CertFile string
+		Enabled  bool
+	}
+
+	type DatabaseConfig struct {
+		Host     string
+		MaxConns int
+		Timeout  time.Duration
+		Replicas []string `config:"READ_REPLICAS"`
+		TLS      TLSConfig
+		Ignored  string `config:"-"`
+		internal string
+	}
+
+	config := &Config{m: map[string]string{
+		"DB_HOST":          "db.internal",
+		"DB_MAX_CONNS":     "20",
+		"DB_TIMEOUT":       "3s",
+		"DB_READ_REPLICAS": "r1.internal, r2.internal",
+		"DB_TLS_CERT_FILE": "/etc/db.pem",
+		"DB_TLS_ENABLED":   "true",
+		"DB_IGNORED":       "set",
+	}}
+
+	db, err := BindConfig[DatabaseConfig](config, "DB")
+	if err != nil {
+		t.Fatalf("BindConfig: %s", err)
+	}
+
+	if db.Host != "db.internal" || db.MaxConns != 20 || db.Timeout != 3*time.Second || len(db.Replicas) != 2 || db.Replicas[1] != "r2.internal" {
+		t.Errorf("unexpected config %+v", db)
+	}
+
+	if db.TLS.CertFile != "/etc/db.pem" || !db.TLS.Enabled || db.Ignored != "" {
+		t.Errorf("unexpected nested config %+v", db)
+	}
+
+	if unused := config.Unused(); len(unused) != 1 || unused[0] != "DB_IGNORED" {
+		t.Errorf("expected the bound keys to be used, got %v", unused)
+	}
+
+	config.SetString("DB_MAX_CONNS", "many")
+	config.SetString("DB_TIMEOUT", "soon")
+
+	if _, err := BindConfig[DatabaseConfig](config, "DB"); err == nil || !strings.Contains(err.Error(), "DB_MAX_CONNS") || !strings.Contains(err.Error(), "DB_TIMEOUT") {
+		t.Errorf("expected both invalid values to be reported but got %v", err)
+	}
+
+	for name, expected := range map[string]string{"MaxConns": "MAX_CONNS", "TLSCert": "TLS_CERT", "ID": "ID", "HTTPSPort": "HTTPS_PORT"} {
+		if key := upperSnakeCase(name); key != expected {
+			t.Errorf("%s: expected %s but got %s", name, expected, key)
+		}
+	}
+}
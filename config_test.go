@@ -1 +1,133 @@
-package ibnsina
\ No newline at end of file
+package ibnsina
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (clock fixedClock) Now() time.Time {
+	return time.Time(clock)
+}
+
+func TestConfigTimeNow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	config := &Config{Clock: fixedClock(now), m: map[string]string{
+		"now":     "now",
+		"past":    "now-24h",
+		"future":  "now+90m",
+		"invalid": "nowish",
+	}}
+
+	tests := map[string]time.Time{
+		"now":    now,
+		"past":   now.Add(-24 * time.Hour),
+		"future": now.Add(90 * time.Minute),
+	}
+
+	for key, expected := range tests {
+		if value := config.MustTime(key); !value.Equal(expected) {
+			t.Errorf("%s: expected %s but was %s", key, expected, value)
+		}
+	}
+
+	if _, err := config.Time("invalid"); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
+
+func TestConfigUnused(t *testing.T) {
+	config := &Config{m: map[string]string{
+		"timeout":       "5s",
+		"TIMEOUT_MS":    "5000",
+		"debug":         "true",
+		"slow./reports": "2s",
+		"port":          "8080",
+	}}
+
+	config.Duration("timeout")
+	config.BoolOrDefault("debug", false)
+	config.IntOrDefault("workers", 4)
+	config.SetString("port", "9090")
+	SlowThresholds(config, "slow.")
+
+	unused := config.Unused()
+	if len(unused) != 1 || unused[0] != "TIMEOUT_MS" {
+		t.Errorf("expected only the misspelled key to be unused but got %v", unused)
+	}
+}
+
+func TestConfigStrictKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	os.WriteFile(path, []byte("# settings\nport=8080\nslow./reports=2s\nTIMEOUT_MS=5000\nstale=1\n"), 0o600)
+
+	open := func() *os.File {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Open: %s", err)
+		}
+
+		t.Cleanup(func() { file.Close() })
+
+		return file
+	}
+
+	if _, err := NewConfig(open(), StrictKeys("port", "slow.*")); err == nil || err.Error() != "unknown keys TIMEOUT_MS, stale" {
+		t.Errorf("expected the unknown keys to be reported but got %v", err)
+	}
+
+	config, err := NewConfig(open(), StrictKeys("port", "slow.*", "TIMEOUT_MS", "stale"))
+	if err != nil || config.MustInt("port") != 8080 {
+		t.Errorf("expected the declared keys to be accepted but got %v", err)
+	}
+}
+
+func TestConfigPercentRatio(t *testing.T) {
+	config := &Config{m: map[string]string{
+		"rollout":   "12%",
+		"sampling":  "0.005",
+		"over":      "120%",
+		"negative":  "-0.1",
+		"garbage":   "12 percent",
+		"factor":    "1.5",
+		"quotient":  "3/4",
+		"by_zero":   "1/0",
+		"backwards": "-2",
+	}}
+
+	percents := map[string]float64{"rollout": 0.12, "sampling": 0.005}
+	for key, expected := range percents {
+		if value, err := config.Percent(key); err != nil || math.Abs(value-expected) > 1e-12 {
+			t.Errorf("%s: expected %v but got %v %v", key, expected, value, err)
+		}
+	}
+
+	for _, key := range []string{"over", "negative", "garbage"} {
+		if _, err := config.Percent(key); err == nil {
+			t.Errorf("%s: expected an error for an invalid percent", key)
+		}
+	}
+
+	ratios := map[string]float64{"factor": 1.5, "quotient": 0.75, "rollout": 0}
+	for key, expected := range ratios {
+		if value := config.RatioOrDefault(key, 0); value != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, value)
+		}
+	}
+
+	for _, key := range []string{"by_zero", "backwards"} {
+		if _, err := config.Ratio(key); err == nil {
+			t.Errorf("%s: expected an error for an invalid ratio", key)
+		}
+	}
+
+	config.SetPercent("rollout", 0.25)
+	if value := config.MustPercent("rollout"); value != 0.25 || config.MustString("rollout") != "25%" {
+		t.Errorf("expected the percent to be set, got %v %q", value, config.MustString("rollout"))
+	}
+}
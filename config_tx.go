@@ -0,0 +1,204 @@
+package ibnsina
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ConfigTx is a set of changes to a Config, applied together by Update.
+type ConfigTx struct {
+	changes map[string]string
+	keys    []string
+}
+
+// Update applies the changes fn makes to the config at once: readers see
+// either none or all of them, and the functions registered with OnChange
+// are called once, with all the keys changed. Nothing is applied if fn
+// panics.
+//
+//	config.Update(func(tx *ibnsina.ConfigTx) {
+//		tx.SetString("db.host", "replica.internal")
+//		tx.SetInt("db.port", 5433)
+//	})
+func (config *Config) Update(fn func(tx *ConfigTx)) {
+	tx := &ConfigTx{changes: make(map[string]string)}
+	fn(tx)
+
+	if len(tx.keys) == 0 {
+		return
+	}
+
+	config.mu.Lock()
+
+	if config.m == nil {
+		config.m = make(map[string]string)
+	}
+
+	config.snapshot()
+
+	for _, key := range tx.keys {
+		config.used.Store(key, true)
+		config.m[key] = tx.changes[key]
+	}
+
+	watchers := config.commit()
+
+	config.mu.Unlock()
+
+	notify(watchers, tx.keys)
+}
+
+// configSnapshot is a version of a Config, kept for Rollback.
+type configSnapshot struct {
+	version uint64
+	m       map[string]string
+}
+
+// Version returns the version of the config, zero as loaded and incremented
+// by every Update, Set call and Rollback.
+func (config *Config) Version() uint64 {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	return config.version
+}
+
+// Rollback restores the config as it was at version, one of the latest
+// Snapshots versions, as a new version, notifying the functions registered
+// with OnChange of the keys that differ. Nothing changes if none do, as when
+// version is the current one.
+func (config *Config) Rollback(version uint64) error {
+	config.mu.Lock()
+
+	config.snapshot()
+
+	index := slices.IndexFunc(config.history, func(snapshot configSnapshot) bool {
+		return snapshot.version == version
+	})
+
+	if index < 0 {
+		config.mu.Unlock()
+		return fmt.Errorf("version %d of the config is not kept", version)
+	}
+
+	restored := config.history[index].m
+
+	var keys []string
+
+	for key, value := range config.m {
+		if previous, ok := restored[key]; !ok || previous != value {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range restored {
+		if _, ok := config.m[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		config.mu.Unlock()
+		return nil
+	}
+
+	slices.Sort(keys)
+
+	config.m = maps.Clone(restored)
+	watchers := config.commit()
+
+	config.mu.Unlock()
+
+	notify(watchers, keys)
+
+	return nil
+}
+
+// snapshot keeps the version loaded, before the first change, the others
+// being kept as they are committed. It must be called with config.mu held.
+func (config *Config) snapshot() {
+	if len(config.history) == 0 {
+		config.history = append(config.history, configSnapshot{version: config.version, m: maps.Clone(config.m)})
+	}
+}
+
+// commit makes the changes a new version, keeping it as a snapshot, and
+// returns the watchers to notify once config.mu, which must be held, is
+// released.
+func (config *Config) commit() []func(keys []string) {
+	config.version++
+
+	config.history = append(config.history, configSnapshot{version: config.version, m: maps.Clone(config.m)})
+
+	size := config.Snapshots
+	if size == 0 {
+		size = 10
+	}
+
+	size = max(size, 1)
+
+	if len(config.history) > size {
+		config.history = slices.Delete(config.history, 0, len(config.history)-size)
+	}
+
+	return config.watchers
+}
+
+func notify(watchers []func(keys []string), keys []string) {
+	for _, watcher := range watchers {
+		watcher(slices.Clone(keys))
+	}
+}
+
+// OnChange registers fn to be called with the keys changed by every Update
+// and Set call, once the changes are applied.
+func (config *Config) OnChange(fn func(keys []string)) {
+	config.mu.Lock()
+	defer config.mu.Unlock()
+
+	config.watchers = append(slices.Clip(config.watchers), fn)
+}
+
+func (tx *ConfigTx) set(key string, value string) {
+	if _, ok := tx.changes[key]; !ok {
+		tx.keys = append(tx.keys, key)
+	}
+
+	tx.changes[key] = value
+}
+
+func (tx *ConfigTx) SetString(key string, value string) {
+	tx.set(key, value)
+}
+
+func (tx *ConfigTx) SetInt(key string, value int) {
+	tx.set(key, strconv.Itoa(value))
+}
+
+func (tx *ConfigTx) SetTime(key string, value time.Time) {
+	tx.set(key, value.Format(time.UnixDate))
+}
+
+func (tx *ConfigTx) SetBool(key string, value bool) {
+	tx.set(key, strconv.FormatBool(value))
+}
+
+func (tx *ConfigTx) SetURL(key string, value *url.URL) {
+	tx.set(key, value.String())
+}
+
+func (tx *ConfigTx) SetDuration(key string, value time.Duration) {
+	tx.set(key, value.String())
+}
+
+func (tx *ConfigTx) SetPercent(key string, value float64) {
+	tx.set(key, strconv.FormatFloat(value*100, 'f', -1, 64)+"%")
+}
+
+func (tx *ConfigTx) SetRatio(key string, value float64) {
+	tx.set(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
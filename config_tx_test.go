@@ -0,0 +1,130 @@
+package ibnsina
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConfigUpdate(t *testing.T) {
+	config := &Config{m: map[string]string{"db.host": "primary", "db.port": "5432"}}
+
+	var changes [][]string
+	config.OnChange(func(keys []string) {
+		changes = append(changes, keys)
+	})
+
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			config.mu.RLock()
+			host, port := config.m["db.host"], config.m["db.port"]
+			config.mu.RUnlock()
+
+			if (host == "primary") != (port == "5432") {
+				t.Errorf("torn config %s:%s", host, port)
+				return
+			}
+		}
+	}()
+
+	for index := range 100 {
+		config.Update(func(tx *ConfigTx) {
+			if index%2 == 0 {
+				tx.SetString("db.host", "replica-"+strconv.Itoa(index))
+				tx.SetInt("db.port", 5433)
+			} else {
+				tx.SetString("db.host", "primary")
+				tx.SetInt("db.port", 5432)
+			}
+		})
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if len(changes) != 100 || len(changes[0]) != 2 || changes[0][0] != "db.host" || changes[0][1] != "db.port" {
+		t.Errorf("expected a notification per update, got %d: %v", len(changes), changes[:min(len(changes), 1)])
+	}
+
+	func() {
+		defer func() { recover() }()
+
+		config.Update(func(tx *ConfigTx) {
+			tx.SetString("db.host", "half-applied")
+			panic("validation failed")
+		})
+	}()
+
+	if host := config.MustString("db.host"); host != "primary" {
+		t.Errorf("expected a panicking update not to be applied but the host is %s", host)
+	}
+
+	config.SetBool("db.tls", true)
+	if len(changes) != 101 || !config.MustBool("db.tls") {
+		t.Errorf("expected Set to notify too, got %d notifications", len(changes))
+	}
+}
+
+func TestConfigRollback(t *testing.T) {
+	config := &Config{Snapshots: 3, m: map[string]string{"timeout": "5s"}}
+
+	var changes [][]string
+	config.OnChange(func(keys []string) {
+		changes = append(changes, keys)
+	})
+
+	config.SetString("timeout", "1ms")
+	config.SetString("retries", "3")
+	config.SetString("timeout", "2ms")
+
+	if version := config.Version(); version != 3 {
+		t.Fatalf("expected version 3 but got %d", version)
+	}
+
+	if err := config.Rollback(0); err == nil {
+		t.Error("expected the versions beyond Snapshots to be dropped")
+	}
+
+	if err := config.Rollback(1); err != nil {
+		t.Fatalf("Rollback: %s", err)
+	}
+
+	if timeout, _ := config.String("timeout"); timeout != "1ms" || config.StringOrDefault("retries", "") != "" || config.Version() != 4 {
+		t.Errorf("expected version 1 restored as version 4, got timeout %s at %d", timeout, config.Version())
+	}
+
+	if last := changes[len(changes)-1]; len(last) != 2 || last[0] != "retries" || last[1] != "timeout" {
+		t.Errorf("expected the keys that differ to be notified, got %v", last)
+	}
+
+	if err := config.Rollback(4); err != nil || config.Version() != 4 || len(changes) != 4 {
+		t.Errorf("expected a rollback to the current version to change nothing, got %v at %d after %d changes", err, config.Version(), len(changes))
+	}
+}
+
+func TestConfigNegativeSnapshots(t *testing.T) {
+	config := &Config{m: map[string]string{"timeout": "1ms"}, Snapshots: -1}
+
+	config.SetString("timeout", "2ms")
+	config.SetString("timeout", "3ms")
+
+	if err := config.Rollback(1); err == nil {
+		t.Error("expected only the current version to be kept")
+	}
+
+	if err := config.Rollback(2); err != nil || config.StringOrDefault("timeout", "") != "3ms" {
+		t.Errorf("expected the current version to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package ibnsina
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+// InstrumentServer reports the connections of server to metrics, through
+// its ConnState hook and error log, which keep working as before:
+//
+//   - http_connections_accepted_total counts accepted connections
+//   - http_connections_active is the number of open connections, hijacked
+//     ones excluded
+//   - http_connections_hijacked_total counts connections taken over by
+//     handlers, such as WebSockets
+//   - http_tls_handshake_errors_total counts failed TLS handshakes
+//
+// Run calls it when Router.Metrics is set. It must be called before the
+// server starts.
+func InstrumentServer(server *http.Server, metrics *Metrics) {
+	accepted := metrics.Counter("http_connections_accepted_total")
+	active := metrics.Gauge("http_connections_active")
+	hijacked := metrics.Counter("http_connections_hijacked_total")
+
+	next := server.ConnState
+
+	server.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			accepted.Inc()
+			active.Add(1)
+		case http.StateHijacked:
+			hijacked.Inc()
+			active.Add(-1)
+		case http.StateClosed:
+			active.Add(-1)
+		}
+
+		if next != nil {
+			next(conn, state)
+		}
+	}
+
+	// the server only reports handshake errors to its error log, which
+	// defaults to the standard logger
+	logger := server.ErrorLog
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	server.ErrorLog = log.New(handshakeErrors{
+		writer:  logger.Writer(),
+		counter: metrics.Counter("http_tls_handshake_errors_total"),
+	}, logger.Prefix(), logger.Flags())
+}
+
+// handshakeErrors counts the TLS handshake errors logged by a server.
+type handshakeErrors struct {
+	writer  io.Writer
+	counter *Counter
+}
+
+func (handshake handshakeErrors) Write(message []byte) (int, error) {
+	if bytes.Contains(message, []byte("TLS handshake error")) {
+		handshake.counter.Inc()
+	}
+
+	return handshake.writer.Write(message)
+}
@@ -0,0 +1,53 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInstrumentServer(t *testing.T) {
+	metrics := NewMetrics()
+
+	router := NewRouter()
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	InstrumentServer(server.Config, metrics)
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).DisableKeepAlives = true
+
+	for range 2 {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get: %s", err)
+		}
+
+		response.Body.Close()
+	}
+
+	// a client not trusting the certificate fails the handshake
+	if _, err := http.Get(server.URL); err == nil {
+		t.Fatal("expected the handshake to fail")
+	}
+
+	waitFor := func(name string, condition func() bool) {
+		for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", name)
+			}
+		}
+	}
+
+	waitFor("accepted connections", func() bool { return metrics.Counter("http_connections_accepted_total").Value() == 3 })
+	waitFor("closed connections", func() bool { return metrics.Gauge("http_connections_active").Value() == 0 })
+	waitFor("handshake errors", func() bool { return metrics.Counter("http_tls_handshake_errors_total").Value() == 1 })
+}
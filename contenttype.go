@@ -0,0 +1,93 @@
+package ibnsina
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedMediaType is the error ContentTypes answers request
+	// bodies of a type the route does not consume with.
+	ErrUnsupportedMediaType = &StatusError{Status: http.StatusUnsupportedMediaType, Message: "the content type of the request body is not supported"}
+
+	// ErrBodyRequired is the error ContentTypes answers POST, PUT and PATCH
+	// requests without a body with.
+	ErrBodyRequired = &StatusError{Status: http.StatusBadRequest, Message: "the request must have a body"}
+)
+
+// Consumes declares the media types of the request bodies the route
+// accepts, checked by ContentTypes, such as
+//
+//	router.Handle("/imports", createImport, "POST").
+//		Consumes("application/json", "text/csv")
+//
+// "type/*" accepts every subtype of type. Parameters such as charset are
+// ignored.
+func (route *Route) Consumes(mediaTypes ...string) *Route {
+	parsed := make([]string, len(mediaTypes))
+
+	for index, mediaType := range mediaTypes {
+		parsed[index] = strings.ToLower(strings.TrimSpace(mediaType))
+
+		if bare, _, err := mime.ParseMediaType(mediaType); err == nil {
+			parsed[index] = bare
+		}
+	}
+
+	return route.update(func(meta *routeMeta) {
+		meta.consumes = append(slices.Clone(meta.consumes), parsed...)
+	})
+}
+
+// ContentTypes is a middleware checking requests to routes declaring
+// Route.Consumes: POST, PUT and PATCH requests without a body are answered
+// with ErrBodyRequired, and bodies of another Content-Type with
+// ErrUnsupportedMediaType. Requests to other routes pass through.
+func ContentTypes(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		values := GetValues(ctx)
+		if values == nil || values.meta == nil || len(values.meta.consumes) == 0 {
+			next(ctx, response, request)
+			return
+		}
+
+		if request.ContentLength == 0 {
+			if request.Method == "POST" || request.Method == "PUT" || request.Method == "PATCH" {
+				writeError(ctx, response, ErrBodyRequired)
+				return
+			}
+
+			next(ctx, response, request)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		if err != nil || !consumes(values.meta.consumes, mediaType) {
+			writeError(ctx, response, ErrUnsupportedMediaType)
+			return
+		}
+
+		next(ctx, response, request)
+	}
+}
+
+func consumes(allowed []string, mediaType string) bool {
+	for _, candidate := range allowed {
+		if prefix, ok := strings.CutSuffix(candidate, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+
+			continue
+		}
+
+		if candidate == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
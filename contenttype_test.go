@@ -0,0 +1,56 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentTypes(t *testing.T) {
+	router := NewRouter(ContentTypes)
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router.Handle("/imports", handler, "POST", "DELETE").Consumes("application/json", "text/*")
+	router.Handle("/logout", handler, "POST")
+	router.Handle("/events", handler, "POST").Consumes("Application/JSON; charset=utf-8")
+
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"POST", "/imports", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{"POST", "/imports", "Text/CSV", "a,b", http.StatusOK},
+		{"POST", "/imports", "application/xml", "<a/>", http.StatusUnsupportedMediaType},
+		{"POST", "/imports", "", "{}", http.StatusUnsupportedMediaType},
+		{"POST", "/imports", "application/json", "", http.StatusBadRequest},
+		{"DELETE", "/imports", "", "", http.StatusOK},
+		{"POST", "/logout", "application/xml", "<a/>", http.StatusOK},
+		{"POST", "/events", "application/json", "{}", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		var body io.Reader
+		if test.body != "" {
+			body = strings.NewReader(test.body)
+		}
+
+		request := httptest.NewRequest(test.method, test.path, body)
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s %s %q: expected status %d but was %d", test.method, test.path, test.contentType, test.status, recorder.Code)
+		}
+	}
+}
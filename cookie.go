@@ -0,0 +1,67 @@
+package ibnsina
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// cookieSealer encrypts and authenticates cookie values, so that clients can
+// neither read nor forge them. The name of the cookie is authenticated too,
+// so a value cannot be moved to another cookie.
+type cookieSealer struct {
+	aead cipher.AEAD
+}
+
+func newCookieSealer(key []byte) (*cookieSealer, error) {
+	sum := sha256.Sum256(key)
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cookieSealer{aead: aead}, nil
+}
+
+// seal returns v, encoded as JSON and sealed, as the value of the cookie
+// name.
+func (sealer *cookieSealer) seal(name string, v any) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, sealer.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sealer.aead.Seal(nonce, nonce, plaintext, []byte(name))), nil
+}
+
+// open decodes the value of the cookie name into v, reporting whether it is
+// authentic.
+func (sealer *cookieSealer) open(name string, value string, v any) bool {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < sealer.aead.NonceSize() {
+		return false
+	}
+
+	size := sealer.aead.NonceSize()
+
+	plaintext, err := sealer.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(plaintext, v) == nil
+}
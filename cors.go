@@ -0,0 +1,90 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures CORS.
+type CORSOptions struct {
+	// Origins are the origins allowed to call the API, such as
+	// "https://app.example.com", or "*" for all.
+	Origins []string
+
+	// Methods are the methods allowed in cross-origin requests, GET, HEAD,
+	// POST, PUT, PATCH and DELETE if empty.
+	Methods []string
+
+	// Headers are the request headers allowed in cross-origin requests,
+	// Content-Type and Authorization if empty.
+	Headers []string
+
+	// Credentials allows cookies and authorization headers to be sent.
+	Credentials bool
+
+	// MaxAge is how long browsers may cache the answers to preflight
+	// requests.
+	MaxAge time.Duration
+}
+
+// CORS is a middleware implementing cross-origin resource sharing for the
+// origins of options. Preflight requests from these origins are answered
+// with 204 No Content without calling the handler; requests from other
+// origins are served without CORS headers, so browsers block them.
+func CORS(options CORSOptions) Middleware {
+	methods := options.Methods
+	if len(methods) == 0 {
+		methods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+
+	headers := options.Headers
+	if len(headers) == 0 {
+		headers = []string{"Content-Type", "Authorization"}
+	}
+
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	anyOrigin := slices.Contains(options.Origins, "*")
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.Header().Add("Vary", "Origin")
+
+			origin := request.Header.Get("Origin")
+			if origin == "" || !anyOrigin && !slices.Contains(options.Origins, origin) {
+				next(ctx, response, request)
+				return
+			}
+
+			header := response.Header()
+
+			if anyOrigin && !options.Credentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if options.Credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if request.Method != http.MethodOptions || request.Header.Get("Access-Control-Request-Method") == "" {
+				next(ctx, response, request)
+				return
+			}
+
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+
+			if options.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+			}
+
+			response.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	router := NewRouter(CORS(CORSOptions{
+		Origins:     []string{"https://app.example.com"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	}))
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("orders"))
+	}, "GET", "POST")
+
+	tests := []struct {
+		method        string
+		origin        string
+		requestMethod string
+		status        int
+		allowOrigin   string
+		allowMethods  string
+	}{
+		{"GET", "https://app.example.com", "", http.StatusOK, "https://app.example.com", ""},
+		{"GET", "https://evil.example.com", "", http.StatusOK, "", ""},
+		{"GET", "", "", http.StatusOK, "", ""},
+		{"OPTIONS", "https://app.example.com", "POST", http.StatusNoContent, "https://app.example.com", "GET, HEAD, POST, PUT, PATCH, DELETE"},
+		{"OPTIONS", "https://evil.example.com", "POST", http.StatusNoContent, "", ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, "/orders", nil)
+		if test.origin != "" {
+			request.Header.Set("Origin", test.origin)
+		}
+
+		if test.requestMethod != "" {
+			request.Header.Set("Access-Control-Request-Method", test.requestMethod)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		header := recorder.Header()
+
+		if recorder.Code != test.status || header.Get("Access-Control-Allow-Origin") != test.allowOrigin || header.Get("Access-Control-Allow-Methods") != test.allowMethods || header.Get("Vary") != "Origin" {
+			t.Errorf("%s %q: unexpected response %d %v", test.method, test.origin, recorder.Code, header)
+		}
+
+		if test.allowMethods != "" && (header.Get("Access-Control-Max-Age") != "600" || header.Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("%s %q: unexpected preflight headers %v", test.method, test.origin, header)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ibnsina
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedEncoding is the error Decompress answers request bodies
+	// in an unknown Content-Encoding with.
+	ErrUnsupportedEncoding = &StatusError{Status: http.StatusUnsupportedMediaType, Message: "the content encoding of the request body is not supported"}
+
+	// ErrBodyTooLarge is the error Decompress answers request bodies
+	// exceeding its limits with.
+	ErrBodyTooLarge = &StatusError{Status: http.StatusRequestEntityTooLarge, Message: "the request body is too large"}
+
+	// ErrInvalidEncoding is the error Decompress answers request bodies that
+	// cannot be decompressed with.
+	ErrInvalidEncoding = &StatusError{Status: http.StatusBadRequest, Message: "the request body could not be decompressed"}
+)
+
+// ratioFloor is the decompressed size below which DecompressOptions.MaxRatio
+// is not enforced, since small repetitive payloads compress very well.
+const ratioFloor = 64 << 10
+
+// DecompressOptions configures Decompress.
+type DecompressOptions struct {
+	// MaxBytes bounds the size of bodies, compressed and decompressed,
+	// MaxBodyBytes if zero or less.
+	MaxBytes int64
+
+	// MaxRatio bounds how many times larger than its compressed size a body
+	// may decompress to, 100 if zero or less. Bodies under 64 KiB are
+	// exempt.
+	MaxRatio int64
+}
+
+// Decompress is a middleware decompressing request bodies sent with a gzip
+// or deflate Content-Encoding, so handlers and Bind read them as if they
+// were sent as is. The body is decompressed before calling the handler:
+// bodies exceeding the limits of options are answered with ErrBodyTooLarge,
+// corrupt ones with ErrInvalidEncoding and other encodings with
+// ErrUnsupportedEncoding.
+func Decompress(options DecompressOptions) Middleware {
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = MaxBodyBytes
+	}
+
+	maxRatio := options.MaxRatio
+	if maxRatio <= 0 {
+		maxRatio = 100
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			encoding := request.Header.Get("Content-Encoding")
+			if encoding == "" || request.Body == nil || request.Body == http.NoBody {
+				next(ctx, response, request)
+				return
+			}
+
+			body, err := decompress(request.Body, encoding, maxBytes, maxRatio)
+			if err != nil {
+				writeError(ctx, response, err)
+				return
+			}
+
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			request.ContentLength = int64(len(body))
+			request.Header.Del("Content-Encoding")
+			request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+func decompress(body io.Reader, encoding string, maxBytes, maxRatio int64) ([]byte, error) {
+	compressed := &countingReader{ReadCloser: io.NopCloser(io.LimitReader(body, maxBytes+1))}
+
+	// encodings are listed in the order they were applied
+	var reader io.Reader = compressed
+	encodings := strings.Split(encoding, ",")
+
+	for index := len(encodings) - 1; index >= 0; index-- {
+		var err error
+
+		switch strings.ToLower(strings.TrimSpace(encodings[index])) {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(reader)
+		case "deflate":
+			reader, err = newDeflateReader(reader)
+		case "identity":
+		default:
+			return nil, ErrUnsupportedEncoding
+		}
+
+		if err != nil {
+			return nil, decompressError(compressed, maxBytes)
+		}
+	}
+
+	var buffer bytes.Buffer
+	chunk := make([]byte, 32<<10)
+
+	for {
+		n, err := reader.Read(chunk)
+		buffer.Write(chunk[:n])
+
+		size := int64(buffer.Len())
+		if size > maxBytes || size > ratioFloor && size > maxRatio*compressed.read {
+			return nil, ErrBodyTooLarge
+		}
+
+		if err == io.EOF {
+			return buffer.Bytes(), nil
+		}
+
+		if err != nil {
+			return nil, decompressError(compressed, maxBytes)
+		}
+	}
+}
+
+// decompressError reports a failure to decompress a body, which is
+// truncated when it exceeds maxBytes.
+func decompressError(compressed *countingReader, maxBytes int64) error {
+	if compressed.read > maxBytes {
+		return ErrBodyTooLarge
+	}
+
+	return ErrInvalidEncoding
+}
+
+// newDeflateReader reads the deflate encoding, which is zlib-wrapped but
+// sent raw by some clients.
+func newDeflateReader(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
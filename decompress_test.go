@@ -0,0 +1,80 @@
+package ibnsina
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	compress := func(newWriter func(io.Writer) io.WriteCloser, data string) string {
+		var buffer bytes.Buffer
+		writer := newWriter(&buffer)
+		writer.Write([]byte(data))
+		writer.Close()
+
+		return buffer.String()
+	}
+
+	gzipped := func(writer io.Writer) io.WriteCloser { return gzip.NewWriter(writer) }
+	zlibbed := func(writer io.Writer) io.WriteCloser { return zlib.NewWriter(writer) }
+	deflated := func(writer io.Writer) io.WriteCloser {
+		deflater, _ := flate.NewWriter(writer, flate.DefaultCompression)
+		return deflater
+	}
+
+	router := NewRouter(Decompress(DecompressOptions{MaxBytes: 1 << 20}))
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		var order struct {
+			ID string `json:"id"`
+		}
+
+		validator := NewValidator()
+		if err := Bind(request, &order, validator); err != nil || !validator.Ok() {
+			t.Errorf("unexpected Bind failure %v %v", err, validator.NonFieldErrors)
+		}
+
+		response.Write([]byte(order.ID + " " + request.Header.Get("Content-Encoding")))
+	}, "POST")
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     string
+		status   int
+		response string
+	}{
+		{"plain", "", `{"id":"a"}`, http.StatusOK, "a "},
+		{"gzip", "gzip", compress(gzipped, `{"id":"b"}`), http.StatusOK, "b "},
+		{"zlib", "deflate", compress(zlibbed, `{"id":"c"}`), http.StatusOK, "c "},
+		{"raw deflate", "deflate", compress(deflated, `{"id":"d"}`), http.StatusOK, "d "},
+		{"stacked", "deflate, gzip", compress(gzipped, compress(zlibbed, `{"id":"e"}`)), http.StatusOK, "e "},
+		{"unsupported", "br", `{"id":"f"}`, http.StatusUnsupportedMediaType, ""},
+		{"corrupt", "gzip", "not gzip", http.StatusBadRequest, ""},
+		{"too large", "gzip", compress(gzipped, `{"id":"`+strings.Repeat("x", 2<<20)+`"}`), http.StatusRequestEntityTooLarge, ""},
+		{"bomb", "gzip", compress(gzipped, `{"id":"`+strings.Repeat("x", 512<<10)+`"}`), http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("POST", "/orders", strings.NewReader(test.body))
+		request.Header.Set("Content-Type", "application/json")
+		if test.encoding != "" {
+			request.Header.Set("Content-Encoding", test.encoding)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status || test.status == http.StatusOK && recorder.Body.String() != test.response {
+			t.Errorf("%s: expected %d %q but was %d %q", test.name, test.status, test.response, recorder.Code, recorder.Body)
+		}
+	}
+}
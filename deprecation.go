@@ -0,0 +1,68 @@
+package ibnsina
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type deprecation struct {
+	sunset time.Time
+	uses   atomic.Uint64
+}
+
+// Deprecated marks the route as deprecated, to be removed at sunset, which
+// may be zero when no date is set yet. Responses carry the Deprecation
+// header, Sunset and, when link is not empty, a Link to the migration
+// notes. Requests are annotated with deprecated=true in the access log and
+// counted, see DeprecatedRoutes, to track the clients still relying on the
+// route.
+func (route *Route) Deprecated(sunset time.Time, link string) *Route {
+	return route.update(func(meta *routeMeta) {
+		meta.deprecation = &deprecation{sunset: sunset}
+		meta.headers = withHeader(meta.headers, "Deprecation", "true")
+
+		if !sunset.IsZero() {
+			meta.headers = withHeader(meta.headers, "Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+
+		if link != "" {
+			meta.headers = withHeader(meta.headers, "Link", "<"+link+`>; rel="deprecation"`)
+		}
+	})
+}
+
+// DeprecatedRoute reports the use of a deprecated route.
+type DeprecatedRoute struct {
+	Method  string
+	Pattern string
+	Sunset  time.Time
+	Uses    uint64
+}
+
+// DeprecatedRoutes lists the deprecated routes, in the order of Routes, with
+// the number of requests they served since they were marked deprecated.
+func (router *Router) DeprecatedRoutes() []DeprecatedRoute {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	var routes []DeprecatedRoute
+
+	for _, route := range router.routes {
+		for _, method := range route.methods {
+			deprecation := route.endpoints[method].meta.deprecation
+			if deprecation == nil {
+				continue
+			}
+
+			routes = append(routes, DeprecatedRoute{
+				Method:  method,
+				Pattern: route.pattern.String(),
+				Sunset:  deprecation.sunset,
+				Uses:    deprecation.uses.Load(),
+			})
+		}
+	}
+
+	return routes
+}
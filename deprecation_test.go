@@ -0,0 +1,53 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeprecated(t *testing.T) {
+	sunset := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	logger := &testLogger{}
+
+	router := NewRouter(AccessLog(logger))
+	router.Handle("/v1/users", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET").
+		Deprecated(sunset, "https://example.com/migrate")
+	router.Handle("/v2/users", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/v1/users", nil))
+
+		expected := map[string]string{
+			"Deprecation": "true",
+			"Sunset":      "Mon, 30 Jun 2025 00:00:00 GMT",
+			"Link":        `<https://example.com/migrate>; rel="deprecation"`,
+		}
+
+		for name, value := range expected {
+			if header := recorder.Header().Get(name); header != value {
+				t.Errorf("expected %s %q but was %q", name, value, header)
+			}
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/users", nil))
+
+	if header := recorder.Header().Get("Deprecation"); header != "" {
+		t.Errorf("unexpected Deprecation %q", header)
+	}
+
+	routes := router.DeprecatedRoutes()
+	if len(routes) != 2 || routes[0] != (DeprecatedRoute{Method: "GET", Pattern: "/v1/users", Sunset: sunset, Uses: 2}) {
+		t.Errorf("unexpected deprecated routes %+v", routes)
+	}
+
+	if !strings.HasSuffix(logger.lines[0], " deprecated=true") || strings.Contains(logger.lines[2], "deprecated") {
+		t.Errorf("unexpected access log %q", logger.lines)
+	}
+}
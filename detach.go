@@ -0,0 +1,67 @@
+package ibnsina
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Detach returns a copy of ctx that is never cancelled and has no deadline,
+// for work that must outlive the request, such as audit writes or emails:
+//
+//	go audit.Write(ibnsina.Detach(ctx), event)
+//
+// The values of ctx are kept. The record of the request, read with
+// GetValues, is copied, since the router reuses it once the handler
+// returns: the copy keeps the trace ID, logger, params, locale, tenant,
+// principal and annotations, but not the response or the session.
+func Detach(ctx context.Context) context.Context {
+	detached := context.WithoutCancel(ctx)
+
+	values := GetValues(ctx)
+	if values == nil {
+		return detached
+	}
+
+	logger := values.Logger
+	if quiet, ok := logger.(quietLogger); ok {
+		logger = quiet.logger
+	}
+
+	return context.WithValue(detached, valuesKey, &Values{
+		TraceID: values.TraceID,
+		Now:     values.Now,
+		Status:  values.Status,
+		Logger:  logger,
+
+		params:       maps.Clone(values.params),
+		meta:         values.meta,
+		groupHeaders: values.groupHeaders,
+		encoder:      values.encoder,
+		locale:       values.locale,
+		tenant:       values.tenant,
+		principal:    values.principal,
+		annotations:  slices.Clone(values.annotations),
+		buckets:      maps.Clone(values.buckets),
+	})
+}
+
+// WithTimeoutFromConfig is like context.WithTimeout with the duration of
+// config under key, such as "timeout.payments" = "3s". The error reports a
+// missing, invalid or non-positive duration, in which case ctx is returned
+// without deadline, along with a cancel function that must still be called.
+func WithTimeoutFromConfig(ctx context.Context, config *Config, key string) (context.Context, context.CancelFunc, error) {
+	timeout, err := config.Duration(key)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("%s must be positive, got %s", key, timeout)
+	}
+
+	if err != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
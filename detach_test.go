@@ -0,0 +1,76 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDetach(t *testing.T) {
+	router := NewRouter()
+
+	var detached context.Context
+	var cancelled context.Context
+
+	router.Handle("/orders/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		SetPrincipal(ctx, &Principal{ID: "alice"})
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		cancelled = ctx
+		detached = Detach(ctx)
+	}, "POST")
+
+	router.Handle("/other", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/orders/42", nil))
+
+	if cancelled.Err() == nil || detached.Err() != nil {
+		t.Fatalf("expected only the request context to be cancelled, got %v and %v", cancelled.Err(), detached.Err())
+	}
+
+	if _, ok := detached.Deadline(); ok {
+		t.Error("expected the detached context to have no deadline")
+	}
+
+	// the record of the request goes back to the pool once served
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other", nil))
+
+	values := GetValues(detached)
+	if values == nil || values.TraceID != recorder.Header().Get(TraceIDHeader) {
+		t.Fatalf("expected the trace ID %q to be kept", recorder.Header().Get(TraceIDHeader))
+	}
+
+	if principal := GetPrincipal(detached); principal == nil || principal.ID != "alice" {
+		t.Errorf("expected the principal to be kept, got %+v", principal)
+	}
+
+	if id := Param(detached, "id"); id != "42" {
+		t.Errorf("expected param id 42 but was %q", id)
+	}
+}
+
+func TestWithTimeoutFromConfig(t *testing.T) {
+	config := &Config{m: map[string]string{"timeout.payments": "3s", "timeout.broken": "soon", "timeout.zero": "0s"}}
+
+	ctx, cancel, err := WithTimeoutFromConfig(context.Background(), config, "timeout.payments")
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if err != nil || !ok || time.Until(deadline) > 3*time.Second || time.Until(deadline) < 2*time.Second {
+		t.Errorf("expected a deadline in 3s, got %v %t %v", time.Until(deadline), ok, err)
+	}
+
+	for _, key := range []string{"timeout.missing", "timeout.broken", "timeout.zero"} {
+		ctx, cancel, err := WithTimeoutFromConfig(context.Background(), config, key)
+		cancel()
+
+		if _, ok := ctx.Deadline(); err == nil || ok {
+			t.Errorf("%s: expected an error and no deadline, got %v %t", key, err, ok)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ibnsina
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"time"
+)
+
+// RunDevTLS is Run over HTTPS with a self-signed certificate generated on
+// the fly, valid for localhost, for trying secure cookies and HSTS during
+// development. Browsers warn about the certificate, which is never written
+// to disk. It must not be used in production.
+func (router *Router) RunDevTLS(addr string) error {
+	certificate, err := DevCertificate()
+	if err != nil {
+		return err
+	}
+
+	srv := router.server(addr, 0, nil)
+	srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+
+	return router.serve(srv, func() error {
+		return srv.ListenAndServeTLS("", "")
+	})
+}
+
+// DevCertificate generates a self-signed certificate valid for a month for
+// hosts, names or IP addresses, and for "localhost", "127.0.0.1" and "::1".
+func DevCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"ibnsina development"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 1, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, host := range append([]string{"localhost", "127.0.0.1", "::1"}, hosts...) {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
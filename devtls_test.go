@@ -0,0 +1,48 @@
+package ibnsina
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDevCertificate(t *testing.T) {
+	certificate, err := DevCertificate("app.test")
+	if err != nil {
+		t.Fatalf("DevCertificate: %s", err)
+	}
+
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "app.test"} {
+		if err := certificate.Leaf.VerifyHostname(host); err != nil {
+			t.Errorf("expected the certificate to be valid for %s: %s", host, err)
+		}
+	}
+
+	router := NewRouter()
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("secure"))
+	}, "GET")
+
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate.Leaf)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 but was %d", response.StatusCode)
+	}
+}
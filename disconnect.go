@@ -0,0 +1,67 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is recorded as the status of requests whose
+// client went away before they were answered, after the nginx convention.
+// It is never sent.
+const StatusClientClosedRequest = 499
+
+// Disconnected reports whether the client of the request ctx belongs to has
+// gone away, which cancels the request context.
+func Disconnected(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// Disconnects is a middleware keeping cancelled requests out of error rates.
+// While the handlers it wraps run, Values.Logger drops messages once the
+// client has gone away, since the errors they report are most likely the
+// cancellation itself. When the client has gone away by the time the
+// handler returns, the status in Values becomes StatusClientClosedRequest,
+// for AccessLog and other middlewares running outside of this one, and the
+// http_client_disconnects_total counter of metrics, if not nil, goes up.
+func Disconnects(metrics *Metrics) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			values := GetValues(ctx)
+			if values != nil && values.Logger != nil {
+				logger := values.Logger
+				values.Logger = quietLogger{ctx: ctx, logger: logger}
+
+				defer func() {
+					values.Logger = logger
+				}()
+			}
+
+			next(ctx, response, request)
+
+			if !Disconnected(ctx) {
+				return
+			}
+
+			if values != nil {
+				values.Status = StatusClientClosedRequest
+			}
+
+			if metrics != nil {
+				metrics.Counter("http_client_disconnects_total").Inc()
+			}
+		}
+	}
+}
+
+// quietLogger drops messages once ctx is cancelled.
+type quietLogger struct {
+	ctx    context.Context
+	logger Logger
+}
+
+func (logger quietLogger) Printf(format string, args ...any) {
+	if !Disconnected(logger.ctx) {
+		logger.logger.Printf(format, args...)
+	}
+}
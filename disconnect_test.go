@@ -0,0 +1,62 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisconnects(t *testing.T) {
+	logger := &testLogger{}
+	metrics := NewMetrics()
+
+	var status int
+	var restored bool
+
+	router := NewRouter(func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			next(ctx, response, request)
+			status = GetValues(ctx).Status
+			restored = GetValues(ctx).Logger == Logger(logger)
+		}
+	}, Disconnects(metrics))
+	router.Logger = logger
+
+	router.Handle("/slow", Typed(func(ctx context.Context, input struct{}) (struct{}, error) {
+		GetValues(ctx).Logger.Printf("query failed: %v", ctx.Err())
+		return struct{}{}, ctx.Err()
+	}), "GET")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	request := httptest.NewRequest("GET", "/slow", nil).WithContext(ctx)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if status != StatusClientClosedRequest {
+		t.Errorf("expected status %d but was %d", StatusClientClosedRequest, status)
+	}
+
+	if len(logger.lines) != 0 {
+		t.Errorf("expected no log lines but got %q", logger.lines)
+	}
+
+	if !restored {
+		t.Error("expected the logger to be restored once the handler returned")
+	}
+
+	if count := metrics.Counter("http_client_disconnects_total").Value(); count != 1 {
+		t.Errorf("expected 1 disconnect but counted %d", count)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+
+	if status != http.StatusOK {
+		t.Errorf("expected status %d but was %d", http.StatusOK, status)
+	}
+
+	if len(logger.lines) != 1 {
+		t.Errorf("expected 1 log line but got %q", logger.lines)
+	}
+}
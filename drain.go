@@ -0,0 +1,28 @@
+package ibnsina
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// drainable reports whether the body of request is worth draining within
+// limit bytes: not when it is known to be larger, nor when the client waits
+// for a 100 Continue before sending it, which draining would trigger.
+func drainable(request *http.Request, limit int64) bool {
+	if request.Body == nil || request.Body == http.NoBody || request.ContentLength > limit {
+		return false
+	}
+
+	return !strings.EqualFold(request.Header.Get("Expect"), "100-continue")
+}
+
+// drainBody reads and discards up to limit bytes of what is left of body.
+// Handlers bailing out early, on a validation failure say, leave the body
+// unread, and the server closes connections whose request body it cannot
+// skip cheaply.
+func drainBody(body io.ReadCloser, limit int64) {
+	// errors mean the body was closed, the connection hijacked or the
+	// client gone, none of which draining can help
+	io.CopyN(io.Discard, body, limit)
+}
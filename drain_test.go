@@ -0,0 +1,66 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// trackedBody records how much of a request body was read.
+type trackedBody struct {
+	io.Reader
+	read int
+}
+
+func (body *trackedBody) Read(data []byte) (int, error) {
+	n, err := body.Reader.Read(data)
+	body.read += n
+
+	return n, err
+}
+
+func (body *trackedBody) Close() error {
+	return nil
+}
+
+func TestDrainBodies(t *testing.T) {
+	router := NewRouter()
+	router.DrainBodies = 1 << 20
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusUnprocessableEntity)
+	}, "POST")
+
+	tests := []struct {
+		name          string
+		size          int
+		contentLength int64
+		expect        string
+		drained       bool
+	}{
+		{"small", 4096, 4096, "", true},
+		{"chunked", 4096, -1, "", true},
+		{"too large", 2 << 20, 2 << 20, "", false},
+		{"expect continue", 4096, 4096, "100-continue", false},
+	}
+
+	for _, test := range tests {
+		body := &trackedBody{Reader: strings.NewReader(strings.Repeat("x", test.size))}
+
+		request := httptest.NewRequest("POST", "/orders", nil)
+		request.Body = body
+		request.ContentLength = test.contentLength
+		if test.expect != "" {
+			request.Header.Set("Expect", test.expect)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		if drained := body.read == test.size; drained != test.drained {
+			t.Errorf("%s: expected drained %t but %d of %d bytes were read", test.name, test.drained, body.read, test.size)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+)
+
+// ResponseEncoder writes the results and errors of Typed handlers, so each
+// API can follow its own conventions. The encoder of a request is the one of
+// the group its route was registered through, if set, then the one of the
+// router, then JSONEncoder.
+type ResponseEncoder interface {
+	// Encode writes a successful result.
+	Encode(ctx context.Context, response http.ResponseWriter, status int, v any) error
+
+	// EncodeError writes a failure. validator is set when the request failed
+	// validation and holds the errors found.
+	EncodeError(ctx context.Context, response http.ResponseWriter, err *StatusError, validator *Validator) error
+}
+
+// Encoder returns the ResponseEncoder of the request ctx belongs to.
+func Encoder(ctx context.Context) ResponseEncoder {
+	if values := GetValues(ctx); values != nil && values.encoder != nil {
+		return values.encoder
+	}
+
+	return JSONEncoder{}
+}
+
+type errorBody struct {
+	Message string `json:"message"`
+	TraceID string `json:"trace_id,omitempty"`
+	*validationReport
+}
+
+func newErrorBody(ctx context.Context, err *StatusError, validator *Validator) errorBody {
+	body := errorBody{Message: err.Message}
+
+	if values := GetValues(ctx); values != nil {
+		body.TraceID = values.TraceID
+	}
+
+	if validator != nil {
+		if locale := Locale(ctx); locale != "" {
+			validator.Localize(DefaultCatalog, locale)
+		}
+
+		report := validator.report()
+		body.validationReport = &report
+	}
+
+	return body
+}
+
+// JSONEncoder writes results as bare JSON and errors as
+//
+//	{"message": "...", "trace_id": "..."}
+//
+// with the fields of ValidationFailed for validation failures.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(ctx context.Context, response http.ResponseWriter, status int, v any) error {
+	return WriteJSON(ctx, response, status, v)
+}
+
+func (JSONEncoder) EncodeError(ctx context.Context, response http.ResponseWriter, err *StatusError, validator *Validator) error {
+	return WriteJSON(ctx, response, err.Status, newErrorBody(ctx, err, validator))
+}
+
+// EnvelopeEncoder wraps results and errors in an envelope:
+//
+//	{"data": ...}
+//	{"error": {"message": "...", "trace_id": "...", "fields": {...}}}
+type EnvelopeEncoder struct{}
+
+func (EnvelopeEncoder) Encode(ctx context.Context, response http.ResponseWriter, status int, v any) error {
+	return WriteJSON(ctx, response, status, struct {
+		Data any `json:"data"`
+	}{v})
+}
+
+func (EnvelopeEncoder) EncodeError(ctx context.Context, response http.ResponseWriter, err *StatusError, validator *Validator) error {
+	return WriteJSON(ctx, response, err.Status, struct {
+		Error errorBody `json:"error"`
+	}{newErrorBody(ctx, err, validator)})
+}
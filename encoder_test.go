@@ -0,0 +1,46 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncoders(t *testing.T) {
+	type input struct {
+		Name string `query:"name" validate:"required"`
+	}
+
+	handler := Typed(func(ctx context.Context, in input) (map[string]string, error) {
+		return map[string]string{"name": in.Name}, nil
+	})
+
+	router := NewRouter()
+	router.Handle("/bare", handler, http.MethodGet)
+
+	group := router.Group()
+	group.Encoder = EnvelopeEncoder{}
+	group.Handle("/envelope", handler, http.MethodGet)
+
+	tests := []struct {
+		path         string
+		expectedBody string
+	}{
+		{"/bare?name=ibn", `{"name":"ibn"}` + "\n"},
+		{"/envelope?name=ibn", `{"data":{"name":"ibn"}}` + "\n"},
+		{"/envelope", `{"error":{"message":"the request failed validation","trace_id":"%s","fields":{"name":{"code":"required","message":"must be provided"}},"errors":[]}}` + "\n"},
+	}
+
+	for _, test := range tests {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		expected := strings.ReplaceAll(test.expectedBody, "%s", response.Header().Get(TraceIDHeader))
+
+		if body := response.Body.String(); body != expected {
+			t.Errorf("%s: expected body %q but was %q", test.path, expected, body)
+		}
+	}
+}
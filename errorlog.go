@@ -0,0 +1,130 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// LoggedError is an error response kept by an ErrorLog.
+type LoggedError struct {
+	TraceID string    `json:"trace_id"`
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	URI     string    `json:"uri"`
+	Route   string    `json:"route,omitempty"`
+	Status  int       `json:"status"`
+
+	// Error is the error or panic the response was answered for, if the
+	// router saw one, and Stack the stack trace where it was.
+	Error string `json:"error,omitempty"`
+	Stack string `json:"stack,omitempty"`
+}
+
+// ErrorLog keeps the latest error responses, those with a 5xx status, in
+// memory, for a quick look when the logs lag behind. Its middleware is
+// CaptureErrors.
+type ErrorLog struct {
+	mu     sync.Mutex
+	errors []LoggedError
+	next   int
+	size   int
+}
+
+// NewErrorLog returns an ErrorLog keeping the latest size errors, 100 if
+// not positive.
+func NewErrorLog(size int) *ErrorLog {
+	if size <= 0 {
+		size = 100
+	}
+
+	return &ErrorLog{size: size}
+}
+
+// Errors returns the errors kept, latest first.
+func (errorLog *ErrorLog) Errors() []LoggedError {
+	errorLog.mu.Lock()
+	defer errorLog.mu.Unlock()
+
+	logged := make([]LoggedError, 0, len(errorLog.errors))
+
+	for index := range errorLog.errors {
+		logged = append(logged, errorLog.errors[(errorLog.next-1-index+len(errorLog.errors))%len(errorLog.errors)])
+	}
+
+	return logged
+}
+
+// ServeErrors is a handler serving the errors kept, latest first, as JSON.
+func (errorLog *ErrorLog) ServeErrors(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	WriteJSON(ctx, response, http.StatusOK, errorLog.Errors())
+}
+
+func (errorLog *ErrorLog) add(logged LoggedError) {
+	errorLog.mu.Lock()
+	defer errorLog.mu.Unlock()
+
+	if len(errorLog.errors) < errorLog.size {
+		errorLog.errors = append(errorLog.errors, logged)
+	} else {
+		errorLog.errors[errorLog.next] = logged
+	}
+
+	errorLog.next = (errorLog.next + 1) % errorLog.size
+}
+
+// CaptureErrors is a middleware keeping the requests answered with a 5xx
+// status in errorLog, along with the error or panic behind them, as seen by
+// Recover and the error handling of the router. Put it in front of Recover.
+func CaptureErrors(errorLog *ErrorLog) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			values := GetValues(ctx)
+			if values == nil {
+				next(ctx, response, request)
+				return
+			}
+
+			values.captureErrors = true
+
+			next(ctx, response, request)
+
+			if values.Status < 500 {
+				return
+			}
+
+			logged := LoggedError{
+				TraceID: values.TraceID,
+				Time:    values.Now,
+				Method:  request.Method,
+				URI:     redactURI(request.URL),
+				Route:   RoutePattern(ctx),
+				Status:  values.Status,
+				Stack:   string(values.stack),
+			}
+
+			if values.err != nil {
+				logged.Error = values.err.Error()
+			}
+
+			errorLog.add(logged)
+		}
+	}
+}
+
+// failed records err as the cause of the error response of the request, with
+// stack, or the current stack if nil, when the errors are captured. The
+// first cause is kept.
+func (values *Values) failed(err error, stack []byte) {
+	if !values.captureErrors || values.err != nil {
+		return
+	}
+
+	if stack == nil {
+		stack = debug.Stack()
+	}
+
+	values.err, values.stack = err, stack
+}
@@ -0,0 +1,63 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureErrors(t *testing.T) {
+	errorLog := NewErrorLog(2)
+
+	router := NewRouter(CaptureErrors(errorLog), Recover)
+	router.Logger = &testLogger{}
+
+	router.Handle("/panic/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		panic("nil order")
+	}, "GET")
+
+	router.Handle("/broken", Typed(func(ctx context.Context, in struct{}) (struct{}, error) {
+		return struct{}{}, errors.New("database down")
+	}), "GET")
+
+	router.Handle("/missing", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusNotFound)
+	}, "GET")
+
+	router.Handle("/unavailable", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+	}, "GET")
+
+	for _, path := range []string{"/unavailable", "/panic/1", "/missing", "/broken"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	logged := errorLog.Errors()
+	if len(logged) != 2 {
+		t.Fatalf("expected the latest 2 errors but got %+v", logged)
+	}
+
+	broken, panicked := logged[0], logged[1]
+
+	if broken.URI != "/broken" || broken.Status != http.StatusInternalServerError || broken.Error != "database down" || broken.TraceID == "" || !strings.Contains(broken.Stack, "goroutine") {
+		t.Errorf("unexpected error %+v", broken)
+	}
+
+	if panicked.Route != "/panic/:id" || panicked.Error != "panic: nil order" || !strings.Contains(panicked.Stack, "TestCaptureErrors") {
+		t.Errorf("unexpected panic %+v", panicked)
+	}
+}
+
+func TestNewErrorLogSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		errorLog := NewErrorLog(size)
+		errorLog.add(LoggedError{Status: http.StatusInternalServerError})
+
+		if logged := errorLog.Errors(); len(logged) != 1 || errorLog.size != 100 {
+			t.Errorf("expected a size of %d to default to 100 but got %d and %+v", size, errorLog.size, logged)
+		}
+	}
+}
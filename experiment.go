@@ -0,0 +1,129 @@
+package ibnsina
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// Experiment is an A/B test splitting visitors between buckets, in
+// proportion to Weights when set, evenly otherwise.
+type Experiment struct {
+	Name    string
+	Buckets []string
+	Weights []int
+}
+
+// ExperimentOptions configures Experiments.
+type ExperimentOptions struct {
+	// Cookie holds the visitor id, "visitor" if empty. Visitors without one
+	// get a new id.
+	Cookie string
+
+	Experiments []Experiment
+}
+
+// Experiments is a middleware assigning every visitor a bucket in each
+// experiment, read by handlers with Bucket. Buckets are derived from a hash
+// of the visitor id and the experiment name, so a visitor keeps its buckets
+// across requests and servers without any storage, and experiments are
+// independent of each other. Assignments are added to the access log as
+// exp.<name>=<bucket>.
+func Experiments(options ExperimentOptions) Middleware {
+	if options.Cookie == "" {
+		options.Cookie = "visitor"
+	}
+
+	for _, experiment := range options.Experiments {
+		total := 0
+		for _, weight := range experiment.Weights {
+			total += weight
+		}
+
+		if len(experiment.Buckets) == 0 || (experiment.Weights != nil && (len(experiment.Weights) != len(experiment.Buckets) || total <= 0)) {
+			panic("ibnsina: experiment " + experiment.Name + " needs buckets, and as many positive weights if any")
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			visitor := ""
+			if cookie, err := request.Cookie(options.Cookie); err == nil {
+				visitor = cookie.Value
+			}
+
+			if visitor == "" {
+				visitor = uuid.New()
+
+				http.SetCookie(response, &http.Cookie{
+					Name:     options.Cookie,
+					Value:    visitor,
+					Path:     "/",
+					MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			values := GetValues(ctx)
+			if values == nil {
+				ctx = WithValues(ctx, Values{})
+				request = request.WithContext(ctx)
+				values = GetValues(ctx)
+			}
+
+			if values.buckets == nil {
+				values.buckets = make(map[string]string, len(options.Experiments))
+			}
+
+			for _, experiment := range options.Experiments {
+				bucket := experiment.assign(visitor)
+
+				values.buckets[experiment.Name] = bucket
+				Annotate(ctx, "exp."+experiment.Name, bucket)
+			}
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+func (experiment Experiment) assign(visitor string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(experiment.Name + "\x00" + visitor))
+	sum := hash.Sum32()
+
+	if experiment.Weights == nil {
+		return experiment.Buckets[sum%uint32(len(experiment.Buckets))]
+	}
+
+	total := 0
+	for _, weight := range experiment.Weights {
+		total += weight
+	}
+
+	point := int(sum % uint32(total))
+
+	for index, weight := range experiment.Weights {
+		if point < weight {
+			return experiment.Buckets[index]
+		}
+
+		point -= weight
+	}
+
+	return experiment.Buckets[len(experiment.Buckets)-1]
+}
+
+// Bucket returns the bucket of the visitor in experiment, or "" when the
+// request was not assigned one.
+func Bucket(ctx context.Context, experiment string) string {
+	if values := GetValues(ctx); values != nil {
+		return values.buckets[experiment]
+	}
+
+	return ""
+}
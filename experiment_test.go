@@ -0,0 +1,66 @@
+package ibnsina
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (logger *testLogger) Printf(format string, v ...any) {
+	logger.lines = append(logger.lines, fmt.Sprintf(format, v...))
+}
+
+func TestExperiments(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter(AccessLog(logger), Experiments(ExperimentOptions{
+		Experiments: []Experiment{
+			{Name: "checkout", Buckets: []string{"a", "b"}},
+			{Name: "banner", Buckets: []string{"off", "on"}, Weights: []int{0, 1}},
+		},
+	}))
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(Bucket(ctx, "checkout") + " " + Bucket(ctx, "banner")))
+	}, http.MethodGet)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := response.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "visitor" {
+		t.Fatalf("expected a visitor cookie, got %v", cookies)
+	}
+
+	first := response.Body.String()
+	if !strings.HasSuffix(first, " on") {
+		t.Errorf("expected the weighted bucket, got %q", first)
+	}
+
+	for index := 0; index < 5; index++ {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.AddCookie(cookies[0])
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if body := response.Body.String(); body != first {
+			t.Fatalf("expected stable buckets %q, got %q", first, body)
+		}
+
+		if len(response.Result().Cookies()) != 0 {
+			t.Error("expected no new cookie for a known visitor")
+		}
+	}
+
+	if len(logger.lines) != 6 || !strings.Contains(logger.lines[0], "GET / 200") || !strings.Contains(logger.lines[0], "exp.checkout="+strings.Fields(first)[0]+" exp.banner=on") {
+		t.Errorf("expected access log lines with the buckets, got %q", logger.lines)
+	}
+}
@@ -0,0 +1,276 @@
+package ibnsina
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// flushSize is how many bytes the streaming writers send between flushes.
+const flushSize = 32 << 10
+
+// JSONStream responds with a JSON array of the values yielded by iter,
+// encoding and flushing them as they come, so large collections are never
+// held in memory:
+//
+//	return ibnsina.JSONStream(ctx, response, func(yield func(any) bool) {
+//		for rows.Next() {
+//			var order Order
+//			rows.Scan(&order.ID, &order.Total)
+//			if !yield(order) {
+//				return
+//			}
+//		}
+//	})
+//
+// iter stops being called once yield returns false, when a value fails to
+// encode, the response fails or the client goes away; the error is
+// returned. The status is sent with the first bytes, so an error halfway
+// fails the response, leaving the array truncated.
+func JSONStream(ctx context.Context, response http.ResponseWriter, iter func(yield func(v any) bool)) (err error) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+
+	defer func() {
+		if err != nil {
+			failResponse(ctx, err)
+		}
+	}()
+
+	writer := &flushingWriter{response: response}
+	buffer := Scratch(ctx)
+	encoder := json.NewEncoder(buffer)
+
+	if _, err := writer.Write([]byte{'['}); err != nil {
+		return err
+	}
+
+	first := true
+	iter(func(v any) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		buffer.Reset()
+
+		if !first {
+			buffer.WriteByte(',')
+		}
+
+		first = false
+
+		if err = encoder.Encode(v); err != nil {
+			return false
+		}
+
+		// Encode ends values with a newline, kept between elements so the
+		// array reads one value per line
+		_, err = writer.Write(buffer.Bytes())
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write([]byte("]\n")); err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+// flushingWriter writes to a response, flushing it every flushSize bytes.
+type flushingWriter struct {
+	response http.ResponseWriter
+	pending  int
+}
+
+func (writer *flushingWriter) Write(data []byte) (int, error) {
+	n, err := writer.response.Write(data)
+	if err != nil {
+		return n, err
+	}
+
+	writer.pending += n
+	if writer.pending < flushSize {
+		return n, nil
+	}
+
+	return n, writer.flush()
+}
+
+func (writer *flushingWriter) flush() error {
+	writer.pending = 0
+
+	err := http.NewResponseController(writer.response).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+
+	return err
+}
+
+// WriteNDJSON responds with the values yielded by rows as newline-delimited
+// JSON, one value per line, flushing as JSONStream does.
+func WriteNDJSON(ctx context.Context, response http.ResponseWriter, rows func(yield func(v any) bool)) (err error) {
+	response.Header().Set("Content-Type", "application/x-ndjson")
+	response.WriteHeader(http.StatusOK)
+
+	defer func() {
+		if err != nil {
+			failResponse(ctx, err)
+		}
+	}()
+
+	writer := &flushingWriter{response: response}
+	encoder := json.NewEncoder(writer)
+
+	rows(func(v any) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		err = encoder.Encode(v)
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+// WriteCSV responds with a CSV file of header, when not nil, followed by
+// the records yielded by rows, flushing as JSONStream does. Set a
+// Content-Disposition header first to have browsers download it.
+func WriteCSV(ctx context.Context, response http.ResponseWriter, header []string, rows func(yield func(record []string) bool)) (err error) {
+	response.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	response.WriteHeader(http.StatusOK)
+
+	defer func() {
+		if err != nil {
+			failResponse(ctx, err)
+		}
+	}()
+
+	writer := &flushingWriter{response: response}
+	records := csv.NewWriter(writer)
+
+	if header != nil {
+		if err := records.Write(header); err != nil {
+			return err
+		}
+	}
+
+	rows(func(record []string) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		err = records.Write(record)
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	records.Flush()
+	if err := records.Error(); err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+// ZipEntry is a file of the archive written by WriteZip.
+type ZipEntry struct {
+	// Name is the slash-separated path of the file in the archive, such as
+	// "invoices/2024-01.pdf". It must be valid for fs.ValidPath.
+	Name     string
+	Modified time.Time
+
+	// Open returns the content of the file, closed once copied. Files are
+	// opened one at a time, as the archive is written.
+	Open func() (io.ReadCloser, error)
+}
+
+// WriteZip responds with a zip archive named filename, for download, of
+// the entries yielded by entries, compressing and flushing them as they
+// come, so bulk exports are never held in memory or on disk. The status is
+// sent with the first bytes, so an error halfway fails the response,
+// leaving the archive truncated; it is returned.
+func WriteZip(ctx context.Context, response http.ResponseWriter, filename string, entries func(yield func(entry ZipEntry) bool)) (err error) {
+	response.Header().Set("Content-Type", "application/zip")
+	response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	response.WriteHeader(http.StatusOK)
+
+	defer func() {
+		if err != nil {
+			failResponse(ctx, err)
+		}
+	}()
+
+	writer := &flushingWriter{response: response}
+	archive := zip.NewWriter(writer)
+
+	entries(func(entry ZipEntry) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		err = writeZipEntry(archive, entry)
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+func writeZipEntry(archive *zip.Writer, entry ZipEntry) error {
+	if !fs.ValidPath(entry.Name) || entry.Name == "." {
+		return fmt.Errorf("zip: invalid entry name %q", entry.Name)
+	}
+
+	content, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("zip: opening %s: %w", entry.Name, err)
+	}
+	defer content.Close()
+
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     entry.Name,
+		Method:   zip.Deflate,
+		Modified: entry.Modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		return fmt.Errorf("zip: copying %s: %w", entry.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package ibnsina
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONStream(t *testing.T) {
+	var streamErr error
+
+	router := NewRouter()
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		streamErr = JSONStream(ctx, response, func(yield func(any) bool) {
+			for id := range 5000 {
+				if !yield(map[string]any{"id": id, "note": strings.Repeat("x", 20)}) {
+					return
+				}
+			}
+		})
+	}, "GET")
+
+	router.Handle("/broken", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		streamErr = JSONStream(ctx, response, func(yield func(any) bool) {
+			yield(1)
+			if yield(func() {}) {
+				t.Error("expected yield to stop after an encoding error")
+			}
+		})
+	}, "GET")
+
+	router.Handle("/empty", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		streamErr = JSONStream(ctx, response, func(yield func(any) bool) {})
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/orders", nil))
+
+	var orders []struct{ ID int }
+	if err := json.Unmarshal(recorder.Body.Bytes(), &orders); err != nil || streamErr != nil {
+		t.Fatalf("unexpected stream %v %v", err, streamErr)
+	}
+
+	if len(orders) != 5000 || orders[4999].ID != 4999 || !recorder.Flushed || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected response of %d orders, flushed %t", len(orders), recorder.Flushed)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/empty", nil))
+
+	if recorder.Body.String() != "[]\n" || streamErr != nil {
+		t.Errorf("unexpected empty stream %q %v", recorder.Body, streamErr)
+	}
+
+	recorder = httptest.NewRecorder()
+	serveAborted(t, router, recorder, httptest.NewRequest("GET", "/broken", nil))
+
+	var unsupported *json.UnsupportedTypeError
+	if !errors.As(streamErr, &unsupported) || recorder.Body.String() != "[1\n" {
+		t.Errorf("expected a truncated stream and the encoding error but got %q %v", recorder.Body, streamErr)
+	}
+}
+
+func TestWriteNDJSONAndCSV(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/orders.ndjson", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		WriteNDJSON(ctx, response, func(yield func(any) bool) {
+			for id := range 3 {
+				if !yield(map[string]int{"id": id}) {
+					return
+				}
+			}
+		})
+	}, "GET")
+
+	router.Handle("/orders.csv", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		WriteCSV(ctx, response, []string{"id", "note"}, func(yield func([]string) bool) {
+			yield([]string{"1", "plain"})
+			yield([]string{"2", `with "quotes", and commas`})
+		})
+	}, "GET")
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/orders.ndjson", "application/x-ndjson", "{\"id\":0}\n{\"id\":1}\n{\"id\":2}\n"},
+		{"/orders.csv", "text/csv; charset=utf-8", "id,note\n1,plain\n2,\"with \"\"quotes\"\", and commas\"\n"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if recorder.Header().Get("Content-Type") != test.contentType || recorder.Body.String() != test.body || !recorder.Flushed {
+			t.Errorf("%s: unexpected response %v %q", test.path, recorder.Header(), recorder.Body)
+		}
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	report := strings.Repeat("row,value\n", 10000)
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entry := func(name string, content string) ZipEntry {
+		return ZipEntry{Name: name, Modified: modified, Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		}}
+	}
+
+	var zipErr error
+
+	router := NewRouter()
+
+	router.Handle("/export", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		zipErr = WriteZip(ctx, response, "export 2024.zip", func(yield func(ZipEntry) bool) {
+			if yield(entry("reports/january.csv", report)) {
+				yield(entry("README.txt", "hello"))
+			}
+		})
+	}, "GET")
+
+	router.Handle("/unsafe", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		zipErr = WriteZip(ctx, response, "unsafe.zip", func(yield func(ZipEntry) bool) {
+			yield(entry("../etc/passwd", "root"))
+		})
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/export", nil))
+
+	if zipErr != nil || recorder.Header().Get("Content-Disposition") != `attachment; filename="export 2024.zip"` || !recorder.Flushed {
+		t.Fatalf("unexpected response %v %v", zipErr, recorder.Header())
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %s", err)
+	}
+
+	if len(archive.File) != 2 || archive.File[0].Name != "reports/january.csv" || !archive.File[0].Modified.Equal(modified) {
+		t.Fatalf("unexpected archive %v", archive.File)
+	}
+
+	file, _ := archive.File[0].Open()
+	if content, _ := io.ReadAll(file); string(content) != report {
+		t.Errorf("unexpected content of %d bytes", len(content))
+	}
+
+	recorder = httptest.NewRecorder()
+	serveAborted(t, router, recorder, httptest.NewRequest("GET", "/unsafe", nil))
+
+	if zipErr == nil || !strings.Contains(zipErr.Error(), "invalid entry name") {
+		t.Errorf("expected an invalid name error but got %v", zipErr)
+	}
+}
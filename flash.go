@@ -0,0 +1,47 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// flashKey is the session key holding the flash messages.
+const flashKey = "_flash"
+
+// FlashMessage is a message shown once, on the next page the client
+// renders, such as "the order was placed" after a redirect.
+type FlashMessage struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Flash adds a message of kind, such as "success" or "error", to show on
+// the next page of the client. It needs the Sessions middleware.
+func Flash(ctx context.Context, kind string, message string) {
+	messages := readFlashes(ctx)
+	messages = append(messages, FlashMessage{Kind: kind, Message: message})
+
+	data, _ := json.Marshal(messages)
+	SetSessionValue(ctx, flashKey, string(data))
+}
+
+// Flashes returns the flash messages of the client, in the order they were
+// added, and removes them from the session.
+func Flashes(ctx context.Context) []FlashMessage {
+	messages := readFlashes(ctx)
+	if len(messages) > 0 {
+		DeleteSessionValue(ctx, flashKey)
+	}
+
+	return messages
+}
+
+func readFlashes(ctx context.Context) []FlashMessage {
+	var messages []FlashMessage
+
+	if data := SessionValue(ctx, flashKey); data != "" {
+		json.Unmarshal([]byte(data), &messages)
+	}
+
+	return messages
+}
@@ -0,0 +1,103 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// FormState is a submitted form with its validation errors, kept across the
+// redirect of the POST-redirect-GET flow so the form can be shown again as
+// the user filled it:
+//
+//	// POST /signup
+//	if !validator.Ok() {
+//		ibnsina.SaveForm(ctx, "signup", ibnsina.NewFormState(request.PostForm, validator))
+//		http.Redirect(response, request, "/signup", http.StatusSeeOther)
+//		return
+//	}
+//
+//	// GET /signup
+//	renderer.Render(ctx, response, http.StatusOK, "signup.html", ibnsina.RestoreForm(ctx, "signup"))
+//
+// and in the template
+//
+//	<input name="email" value="{{.Value "email"}}">
+//	{{with .Error "email"}}<p class="error">{{.}}</p>{{end}}
+type FormState struct {
+	Values         map[string]string `json:"values,omitempty"`
+	Errors         map[string]string `json:"errors,omitempty"`
+	NonFieldErrors []string          `json:"non_field_errors,omitempty"`
+}
+
+// NewFormState captures the first value of every field of form, except
+// those named in omit and those whose name contains "password", "token" or
+// "secret", which are never sent back, with the errors of validator, which
+// may be nil.
+func NewFormState(form url.Values, validator *Validator, omit ...string) *FormState {
+	state := &FormState{Values: make(map[string]string), Errors: make(map[string]string)}
+
+	for name, values := range form {
+		if len(values) == 0 || sensitiveField(name) || slices.Contains(omit, name) {
+			continue
+		}
+
+		state.Values[name] = values[0]
+	}
+
+	if validator != nil {
+		for key, message := range validator.FieldErrors {
+			state.Errors[key] = message
+		}
+
+		state.NonFieldErrors = append(state.NonFieldErrors, validator.NonFieldErrors...)
+	}
+
+	return state
+}
+
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// Value returns the submitted value of the field name.
+func (state *FormState) Value(name string) string {
+	return state.Values[name]
+}
+
+// Error returns the error of the field name, "" if it is valid.
+func (state *FormState) Error(name string) string {
+	return state.Errors[name]
+}
+
+// Ok reports whether the form has no errors.
+func (state *FormState) Ok() bool {
+	return len(state.Errors) == 0 && len(state.NonFieldErrors) == 0
+}
+
+// SaveForm keeps state in the session of the client under name, until
+// RestoreForm reads it. It needs the Sessions middleware.
+func SaveForm(ctx context.Context, name string, state *FormState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	SetSessionValue(ctx, "_form."+name, string(data))
+}
+
+// RestoreForm returns the state saved under name and removes it from the
+// session, or an empty state when there is none, as on the first visit.
+func RestoreForm(ctx context.Context, name string) *FormState {
+	state := &FormState{}
+
+	if data := SessionValue(ctx, "_form."+name); data != "" {
+		json.Unmarshal([]byte(data), state)
+		DeleteSessionValue(ctx, "_form."+name)
+	}
+
+	return state
+}
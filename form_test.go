@@ -0,0 +1,69 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFormState(t *testing.T) {
+	router := NewRouter(Sessions(SessionOptions{Key: []byte("0123456789abcdef0123456789abcdef")}))
+
+	router.Handle("/signup", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		request.ParseForm()
+
+		validator := NewValidator()
+		validator.CheckCode(strings.Contains(request.PostForm.Get("email"), "@"), "email", CodeInvalid, "must be an email address")
+		validator.AddNonFieldError("signups are closed")
+
+		SaveForm(ctx, "signup", NewFormState(request.PostForm, validator, "captcha"))
+		http.Redirect(response, request, "/signup", http.StatusSeeOther)
+	}, "POST")
+
+	var restored []*FormState
+
+	router.Handle("/signup", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		restored = append(restored, RestoreForm(ctx, "signup"))
+	}, "GET")
+
+	form := url.Values{"email": {"ibn"}, "name": {"Ibn Sina"}, "password": {"hunter2"}, "captcha": {"x"}}
+
+	request := httptest.NewRequest("POST", "/signup", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	cookies := recorder.Result().Cookies()
+
+	for range 2 {
+		request := httptest.NewRequest("GET", "/signup", nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if len(recorder.Result().Cookies()) > 0 {
+			cookies = recorder.Result().Cookies()
+		}
+	}
+
+	state := restored[0]
+
+	if state.Value("email") != "ibn" || state.Value("name") != "Ibn Sina" || state.Value("password") != "" || state.Value("captcha") != "" {
+		t.Errorf("unexpected values %v", state.Values)
+	}
+
+	if state.Error("email") != "must be an email address" || state.Error("name") != "" || state.Ok() || len(state.NonFieldErrors) != 1 {
+		t.Errorf("unexpected errors %v %v", state.Errors, state.NonFieldErrors)
+	}
+
+	if !restored[1].Ok() || restored[1].Value("email") != "" {
+		t.Errorf("expected the form restored once but got %+v", restored[1])
+	}
+}
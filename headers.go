@@ -0,0 +1,98 @@
+package ibnsina
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Header declares a response header of the route, such as
+//
+//	router.Handle("/articles", listArticles, "GET").
+//		Header("Cache-Control", "public, max-age=60").
+//		Header("Vary", "Accept-Language")
+//
+// Declared headers are added when the response is written, unless the
+// handler set the header itself; Vary values are merged with those of the
+// handler instead. Route headers take precedence over group ones. They are
+// left out of 5xx responses, so that errors are not cached as declared for
+// the content.
+func (route *Route) Header(name string, value string) *Route {
+	return route.update(func(meta *routeMeta) {
+		meta.headers = withHeader(meta.headers, name, value)
+	})
+}
+
+// Header declares a response header for the routes of the group, registered
+// before and after, as Route.Header does for a single route.
+func (group *Group) Header(name string, value string) *Group {
+	group.router.mu.Lock()
+	defer group.router.mu.Unlock()
+
+	group.headers = withHeader(group.headers, name, value)
+
+	return group
+}
+
+// withHeader returns a copy of headers with value added to name, so that
+// requests holding the original keep reading it without locking.
+func withHeader(headers http.Header, name string, value string) http.Header {
+	headers = headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+
+	headers.Add(name, value)
+
+	return headers
+}
+
+// applyHeaders adds the headers declared for the route and its group, once,
+// before the response header is written.
+func (writer *responseWriter) applyHeaders() {
+	if writer.applied {
+		return
+	}
+
+	writer.applied = true
+
+	var declared, group http.Header
+	if writer.values.Status < 500 {
+		group = writer.values.groupHeaders
+
+		if writer.values.meta != nil {
+			declared = writer.values.meta.headers
+		}
+	}
+
+	header := writer.ResponseWriter.Header()
+
+	for _, policy := range []http.Header{declared, group} {
+		for name, values := range policy {
+			if name == "Vary" {
+				for _, value := range values {
+					if !slices.Contains(header.Values("Vary"), value) {
+						header.Add("Vary", value)
+					}
+				}
+
+				continue
+			}
+
+			if _, ok := header[name]; !ok {
+				header[name] = slices.Clone(values)
+			}
+		}
+	}
+
+	if writer.values.serverTiming {
+		if timing := writer.values.serverTimingHeader(); timing != "" {
+			header.Add("Server-Timing", timing)
+		}
+	}
+
+	if timings, ok := writer.values.timings.Load().(*Timings); ok {
+		if timing := timings.header(); timing != "" {
+			header.Add("Server-Timing", timing)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	router := NewRouter()
+
+	group := router.Group().
+		Header("Cache-Control", "no-store").
+		Header("Vary", "Accept-Language")
+
+	group.Handle("/articles", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Vary", "Accept")
+		response.Write([]byte("articles"))
+	}, "GET").Header("Cache-Control", "public, max-age=60")
+
+	group.Handle("/drafts", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Cache-Control", "private")
+	}, "GET")
+
+	group.Handle("/empty", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	group.Handle("/broken", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusInternalServerError)
+	}, "GET").Header("Cache-Control", "public, max-age=60")
+
+	tests := []struct {
+		path         string
+		cacheControl string
+		vary         []string
+	}{
+		{"/articles", "public, max-age=60", []string{"Accept", "Accept-Language"}},
+		{"/drafts", "private", []string{"Accept-Language"}},
+		{"/empty", "no-store", []string{"Accept-Language"}},
+		{"/broken", "", nil},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != test.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q but was %q", test.path, test.cacheControl, cacheControl)
+		}
+
+		if vary := recorder.Header().Values("Vary"); !slices.Equal(vary, test.vary) {
+			t.Errorf("%s: expected Vary %q but was %q", test.path, test.vary, vary)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package ibnsina
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pborman/uuid"
@@ -27,26 +31,91 @@ var (
 		response.Write([]byte("the method " + request.Method + " is not supported for the requested resource\n"))
 	}
 
+	defaultBadPath = func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("the requested path is not allowed\n"))
+	}
+
 	defaultOptions = func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
 		response.WriteHeader(http.StatusNoContent)
 	}
 
 	allMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace}
-
-	rxPatterns = map[string]*regexp.Regexp{}
 )
 
-type ctxKey string
-
+// Values is the record the router keeps for every request, carried by the
+// request context and read with GetValues. Records are pooled: once the
+// handler returns, the record may be overwritten by another request at any
+// time, so handlers must not use it, or the context, beyond that.
 type Values struct {
 	TraceID string
 	Now     time.Time
-	Status  int
+
+	// Status is the status code written so far, zero until the handler
+	// writes the header. It becomes 500 when the response fails halfway.
+	Status int
+
+	Logger Logger
+
+	params       map[string]string
+	meta         *routeMeta
+	groupHeaders http.Header
+	body         any
+	encoder      ResponseEncoder
+	locale       string
+	tenant       string
+	principal    *Principal
+	authorized   bool
+	session      *session
+	writer       responseWriter
+
+	annotations []string
+	buckets     map[string]string
+	after       []func(context.Context)
+	cache       *Cache
+
+	// untimed is the context Timeout gave a deadline to, which streams
+	// outlive it with
+	untimed context.Context
+
+	// err and stack are the cause of an error response, for CaptureErrors
+	captureErrors bool
+	err           error
+	stack         []byte
+
+	stages       []stage
+	depth        int
+	serverTiming bool
+	timings      atomic.Value // of *Timings, set by Timing
+	scratch      []*bytes.Buffer
+	ints         []intParam
+}
+
+// Logger is the logging interface of the router, satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+var valuesPool = sync.Pool{
+	New: func() any {
+		return new(Values)
+	},
 }
 
 type contextKey int
 
+const (
+	valuesKey contextKey = iota + 1
+	accessTokenKey
+)
+
 func (router *Router) Run(addr string, timeout time.Duration, logger *log.Logger) error {
+	srv := router.server(addr, timeout, logger)
+
+	return router.serve(srv, srv.ListenAndServe)
+}
+
+func (router *Router) server(addr string, timeout time.Duration, logger *log.Logger) *http.Server {
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      router,
@@ -56,42 +125,212 @@ func (router *Router) Run(addr string, timeout time.Duration, logger *log.Logger
 		ErrorLog:     logger,
 	}
 
+	if router.Metrics != nil {
+		InstrumentServer(srv, router.Metrics)
+	}
+
+	return srv
+}
+
+// serve starts the modules, then srv with listen, and the admin router if
+// any, until either fails or an interrupt shuts them down gracefully.
+func (router *Router) serve(srv *http.Server, listen func() error) error {
+	if err := router.Start(context.Background()); err != nil {
+		return err
+	}
+
 	errs := make(chan error, 1)
 
 	go func() {
-		errs <- srv.ListenAndServe()
+		errs <- listen()
 	}()
 
+	admin := router.adminServer(srv)
+	adminErrs := make(chan error, 1)
+
+	if admin != nil {
+		go func() {
+			adminErrs <- admin.ListenAndServe()
+		}()
+	}
+
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt)
 
 	select {
 	case err := <-errs:
-		return err
+		if admin != nil {
+			admin.Close()
+		}
+
+		return router.stopAfter(err)
+	case err := <-adminErrs:
+		srv.Close()
+		<-errs
+
+		return router.stopAfter(err)
 	case <-signals:
-		timeout := 5 * time.Second
+		err := router.shutdown(srv)
+		if admin != nil {
+			err = errors.Join(err, router.Admin.shutdown(admin))
+		}
+
+		if err != nil {
+			return router.stopAfter(err)
+		}
+
+		// the listener is closed by now, so modules can release what
+		// handlers were using
+		return router.stopAfter(<-errs)
+	}
+}
+
+// adminServer returns the server of the admin router, configured as srv,
+// or nil if there is none.
+func (router *Router) adminServer(srv *http.Server) *http.Server {
+	if router.Admin == nil {
+		return nil
+	}
+
+	return &http.Server{
+		Addr:         router.AdminAddr,
+		Handler:      router.Admin,
+		ReadTimeout:  srv.ReadTimeout,
+		WriteTimeout: srv.WriteTimeout,
+		IdleTimeout:  srv.IdleTimeout,
+		ErrorLog:     srv.ErrorLog,
+	}
+}
+
+// shutdown shuts srv down gracefully, after ShutdownDelay, closing it if
+// that takes too long.
+func (router *Router) shutdown(srv *http.Server) error {
+	router.shuttingDown.Store(true)
+	time.Sleep(router.ShutdownDelay)
+
+	// streams never end on their own, and hijacked connections are not
+	// waited for by Shutdown, so they are drained alongside it
+	drained := make(chan struct{})
+
+	go func() {
+		router.streams.drain(router.streamGrace())
+		close(drained)
+	}()
+
+	defer func() {
+		<-drained
+	}()
+
+	timeout := 5*time.Second + router.streamGrace()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		// kill 9: kill hard
+		return srv.Close()
+	}
+
+	return nil
+}
+
+// ShuttingDown reports whether Run was interrupted, for readiness probes to
+// answer that the server is going away while it still serves.
+func (router *Router) ShuttingDown() bool {
+	return router.shuttingDown.Load()
+}
+
+// stopAfter stops the modules once the server returned err, which is
+// returned as is when they stop cleanly.
+func (router *Router) stopAfter(err error) error {
+	if stopErr := router.Stop(context.Background()); stopErr != nil {
+		return errors.Join(err, stopErr)
+	}
+
+	return err
+}
+
+// WithValues returns a copy of ctx carrying values, as the router does for
+// every request. It lets handlers be called directly, e.g. in tests. The
+// params already in ctx are kept.
+func WithValues(ctx context.Context, values Values) context.Context {
+	if existing := GetValues(ctx); existing != nil && values.params == nil {
+		values.params = existing.params
+	}
+
+	return context.WithValue(ctx, valuesKey, &values)
+}
+
+// GetValues returns the record of the request ctx belongs to, or nil when ctx
+// does not come from the router, WithValues or WithParams.
+func GetValues(ctx context.Context) *Values {
+	values, _ := ctx.Value(valuesKey).(*Values)
+	return values
+}
+
+// WithParams returns a copy of ctx carrying the given path params on top of
+// those already in ctx, readable with Param. The router uses it to hand the
+// params of the matched route to handlers; tests and handlers re-dispatching
+// a request can use it to set params explicitly.
+func WithParams(ctx context.Context, params map[string]string) context.Context {
+	var values Values
+
+	if existing := GetValues(ctx); existing != nil {
+		values = *existing
+
+		if len(existing.params) > 0 {
+			merged := make(map[string]string, len(existing.params)+len(params))
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
+			for name, value := range existing.params {
+				merged[name] = value
+			}
 
-		if err := srv.Shutdown(ctx); err != nil {
-			// kill 9: kill hard
-			if err := srv.Close(); err != nil {
-				return err
+			for name, value := range params {
+				merged[name] = value
 			}
+
+			params = merged
 		}
 
-		return <-errs
+		// int params given again are parsed by ParamInt
+		values.ints = slices.DeleteFunc(slices.Clone(existing.ints), func(param intParam) bool {
+			_, ok := params[param.name]
+			return ok
+		})
 	}
+
+	values.params = params
+
+	return context.WithValue(ctx, valuesKey, &values)
 }
 
 func Param(ctx context.Context, name string) string {
-	value, ok := ctx.Value(ctxKey(name)).(string)
-	if !ok {
-		return ""
+	if values := GetValues(ctx); values != nil {
+		return values.params[name]
+	}
+
+	return ""
+}
+
+// ParamInt returns the value of the param name, declared ":name|int" in the
+// pattern of the route, as parsed when the path was matched. Params not
+// declared so, such as those set with WithParams, are parsed. ok is false
+// when the param is not set or not an integer.
+func ParamInt(ctx context.Context, name string) (value int64, ok bool) {
+	values := GetValues(ctx)
+	if values == nil {
+		return 0, false
+	}
+
+	for _, param := range values.ints {
+		if param.name == name {
+			return param.value, true
+		}
 	}
 
-	return value
+	value, err := strconv.ParseInt(values.params[name], 10, 64)
+
+	return value, err == nil
 }
 
 type Handler func(context.Context, http.ResponseWriter, *http.Request)
@@ -102,8 +341,94 @@ type Router struct {
 	NotFound         Handler
 	MethodNotAllowed Handler
 	Options          Handler
-	routes           []*route
-	middlewares      []Middleware
+
+	// Encoder writes the results of Typed handlers, JSONEncoder if nil.
+	Encoder ResponseEncoder
+
+	// Logger is handed to handlers through Values. NewRouter sets it to
+	// log.Default().
+	Logger Logger
+
+	// RawParams disables percent-decoding of path params, which then hold the
+	// segment exactly as it appears in the escaped request path.
+	RawParams bool
+
+	// EncodedSlashes sets how "%2F" in request paths is handled before
+	// matching. The default passes it through as part of a segment.
+	EncodedSlashes EncodedSlashes
+
+	// CleanPath removes "." and ".." segments, encoded or not, from request
+	// paths before matching, so "/static/../admin" matches "/admin".
+	CleanPath bool
+
+	// StopTimeout bounds the time each module gets to stop. Zero means no
+	// limit besides the context given to Stop.
+	StopTimeout time.Duration
+
+	// Clock sets Values.Now for every request, SystemClock if nil.
+	Clock Clock
+
+	// NewTraceID generates the TraceID of every request, a random UUID if
+	// nil. It may be called concurrently.
+	NewTraceID func() string
+
+	// StreamGrace is how long the connections registered with Stream get to
+	// close once Run starts shutting down, 5 seconds if zero.
+	StreamGrace time.Duration
+
+	// ShutdownDelay is how long Run keeps serving once interrupted, with
+	// ShuttingDown reporting true, before it starts shutting down, so that
+	// load balancers polling a readiness probe stop sending requests first.
+	ShutdownDelay time.Duration
+
+	// Metrics, when set, receives the connection metrics of the server Run
+	// starts, as InstrumentServer reports them.
+	Metrics *Metrics
+
+	// Admin, when set, is served by Run on AdminAddr alongside the router,
+	// and shut down after it, for the endpoints meant for operators only,
+	// which must not be reachable on the public listener.
+	Admin     *Router
+	AdminAddr string
+
+	// Cache is the cache handlers reach with GetCache.
+	Cache *Cache
+
+	// DrainBodies is how many bytes of request bodies left unread by
+	// handlers are read and discarded once they return, so that the
+	// connection can serve the next request. Larger bodies make the server
+	// close the connection, after reading up to 256 KiB itself. Zero leaves
+	// it all to the server.
+	DrainBodies int64
+
+	// TraceStages records the time requests spend in each middleware and
+	// handler, read with Stages.
+	TraceStages bool
+
+	// ServerTiming, with TraceStages, picks the requests whose stages are
+	// also sent in a Server-Timing header, timed up to when the header is
+	// written. The stages are named after the functions serving the request,
+	// so it should only accept trusted callers, such as those carrying a
+	// debug token.
+	ServerTiming func(request *http.Request) bool
+
+	// RouteStats counts the requests, errors and latency of every route,
+	// read with Stats or served as a report by ServeStats, for a quick look
+	// at where the load goes without a metrics pipeline.
+	RouteStats bool
+
+	// mu guards the route table and the middlewares, so routes can be
+	// registered and middlewares added while requests are served.
+	mu          sync.RWMutex
+	routes      []*route
+	middlewares []Middleware
+	cache       *matchCache
+	versioned   bool
+	frozen      bool
+	modules     []Module
+	streams     streamRegistry
+
+	shuttingDown atomic.Bool
 }
 
 func NewRouter(middlewares ...Middleware) *Router {
@@ -111,98 +436,476 @@ func NewRouter(middlewares ...Middleware) *Router {
 		NotFound:         defaultNotFound,
 		MethodNotAllowed: defaultMethodNotAllowed,
 		Options:          defaultOptions,
+		Logger:           log.Default(),
 		routes:           []*route{},
 		middlewares:      middlewares,
 	}
 }
 
+func (router *Router) traceID() string {
+	if router.NewTraceID != nil {
+		return router.NewTraceID()
+	}
+
+	return uuid.New()
+}
+
 func (router *Router) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	segments := strings.Split(request.URL.EscapedPath(), "/")
-	methods := []string{}
+	path, ok := router.requestPath(request.URL.EscapedPath())
+
+	if router.DrainBodies > 0 && drainable(request, router.DrainBodies) {
+		defer drainBody(request.Body, router.DrainBodies)
+	}
+
+	values := valuesPool.Get().(*Values)
+	defer values.release()
 
-	values := Values{
-		TraceID: uuid.New(),
-		Now:     time.Now(),
+	// the scratch and int params slices are kept along with the record
+	scratch := values.scratch
+	ints := values.ints[:0]
+
+	*values = Values{
+		TraceID: router.traceID(),
+		Now:     clockOrSystem(router.Clock).Now(),
+		Logger:  router.Logger,
+
+		serverTiming: router.TraceStages && router.ServerTiming != nil && router.ServerTiming(request),
+		cache:        router.Cache,
+		scratch:      scratch,
+		ints:         ints,
 	}
 
+	values.writer.reset(response, values)
+	response = &values.writer
+
 	response.Header().Set(TraceIDHeader, values.TraceID)
 
-	ctx := context.WithValue(request.Context(), contextKey(1), values)
+	ctx := context.WithValue(request.Context(), valuesKey, values)
+	request = request.WithContext(ctx)
 
-	for index := 0; index < len(router.routes); index++ {
-		c, ok := router.routes[index].match(request.Context(), segments)
-		if ok {
-			if request.Method == router.routes[index].method {
-				router.routes[index].handler(ctx, response, request.WithContext(c))
-				return
-			}
+	router.mu.RLock()
 
-			if !slices.Contains(methods, router.routes[index].method) {
-				methods = append(methods, router.routes[index].method)
+	version := ""
+	if router.versioned {
+		version = requestVersion(request)
+		response.Header().Add("Vary", "Accept")
+		response.Header().Add("Vary", VersionHeader)
+	}
+
+	endpoint, params, methods := router.find(request.Method, version, path, &values.ints)
+	middlewares := router.middlewares
+
+	var handler Handler
+	if endpoint != nil {
+		handler = endpoint.chain
+		values.meta = endpoint.meta
+
+		if router.TraceStages {
+			handler = endpoint.traced
+		}
+
+		values.encoder = router.Encoder
+
+		if endpoint.group != nil {
+			values.groupHeaders = endpoint.group.headers
+
+			if endpoint.group.Encoder != nil {
+				values.encoder = endpoint.group.Encoder
 			}
 		}
 	}
 
+	router.mu.RUnlock()
+
+	if !ok {
+		router.compose(middlewares, defaultBadPath)(ctx, response, request)
+		return
+	}
+
+	if handler != nil {
+		if !router.RawParams {
+			unescapeParams(params)
+		}
+
+		values.params = params
+
+		if !values.meta.validParams(params) {
+			values.meta = nil
+			values.groupHeaders = nil
+			values.encoder = router.Encoder
+
+			router.compose(middlewares, router.NotFound)(ctx, response, request)
+			return
+		}
+
+		if deprecation := values.meta.deprecation; deprecation != nil {
+			deprecation.uses.Add(1)
+			values.annotations = append(values.annotations, "deprecated", "true")
+		}
+
+		var start time.Time
+		if router.RouteStats {
+			start = time.Now()
+		}
+
+		handler(ctx, response, request)
+		values.writer.applyHeaders()
+
+		if router.RouteStats {
+			endpoint.observe(values.Status, time.Since(start))
+		}
+
+		values.writer.finish(ctx)
+
+		if values.writer.failed {
+			// the deferred release of values runs before net/http recovers,
+			// resetting the connection or stream so that clients cannot
+			// take the truncated body for a complete one
+			panic(http.ErrAbortHandler)
+		}
+
+		return
+	}
+
 	if len(methods) > 0 {
-		response.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+		response.Header().Set("Allow", allowHeader(methods))
 
 		if request.Method == http.MethodOptions {
-			router.wrap(router.Options)(ctx, response, request)
+			router.compose(middlewares, router.Options)(ctx, response, request)
 		} else {
-			router.wrap(router.MethodNotAllowed)(ctx, response, request)
+			router.compose(middlewares, router.MethodNotAllowed)(ctx, response, request)
 		}
 
 		return
 	}
 
-	router.wrap(router.NotFound)(ctx, response, request)
+	router.compose(middlewares, router.NotFound)(ctx, response, request)
+}
+
+// find returns the endpoint of the first route matching path that handles
+// method, with its params, or, when there is none, the methods of the routes
+// matching path. The int params of the endpoint are appended to ints. Routes
+// of version come first, then unversioned ones. It must be called with
+// router.mu held.
+func (router *Router) find(method string, version string, path string, ints *[]intParam) (*endpoint, map[string]string, []string) {
+	if router.cache != nil {
+		if endpoint, params, ok := router.cache.get(method, version, path, ints); ok {
+			return endpoint, params, nil
+		}
+	}
+
+	count := strings.Count(path, "/") + 1
+	methods := []string{}
+
+	versions := []string{""}
+	if version != "" {
+		versions = []string{version, ""}
+	}
+
+	for _, current := range versions {
+		for _, route := range router.routes {
+			if route.version != current {
+				continue
+			}
+
+			mark := len(*ints)
+
+			params, ok := route.pattern.match(path, count, ints)
+			if !ok {
+				*ints = (*ints)[:mark]
+				continue
+			}
+
+			if endpoint := route.endpoint(method); endpoint != nil {
+				if router.cache != nil {
+					params = router.cache.add(method, version, path, endpoint, params, (*ints)[mark:])
+				}
+
+				return endpoint, params, nil
+			}
+
+			*ints = (*ints)[:mark]
+
+			for _, routeMethod := range route.allowed() {
+				if !slices.Contains(methods, routeMethod) {
+					methods = append(methods, routeMethod)
+				}
+			}
+		}
+	}
+
+	return nil, nil, methods
 }
 
+// route is a registered pattern with the endpoints of the methods it
+// handles, in registration order. Routes registered through Router.Version
+// only serve requests of their version.
 type route struct {
-	method   string
-	segments []string
-	wildcard bool
-	handler  Handler
+	pattern   *Pattern
+	version   string
+	methods   []string
+	endpoints map[string]*endpoint
+}
+
+// endpoint is the handler of a route for one method. chain is handler
+// behind the middlewares of the router and of the group it was registered
+// through, recomposed whenever either adds middlewares.
+type endpoint struct {
+	handler Handler
+	group   *Group
+	chain   Handler
+	traced  Handler
+	meta    *routeMeta
+	stats   atomic.Pointer[routeStats]
 }
 
-func (router *Router) Handle(path string, handler Handler, methods ...string) {
-	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
-		methods = append(methods, http.MethodHead)
+func (endpoint *endpoint) middlewares(router *Router) []Middleware {
+	middlewares := slices.Clone(router.middlewares)
+
+	if endpoint.group != nil {
+		middlewares = append(middlewares, endpoint.group.middlewares...)
 	}
 
+	return middlewares
+}
+
+// compose builds the chain of the endpoint, and the one recording its
+// stages, used while the router traces them.
+func (endpoint *endpoint) compose(router *Router) {
+	middlewares := endpoint.middlewares(router)
+	handler := requireAuthorize(endpoint.handler)
+
+	endpoint.chain = chain(middlewares, handler)
+	endpoint.traced = traceChain(middlewares, funcName(endpoint.handler), handler)
+}
+
+// Handle registers handler for path and methods, all methods if none are
+// given. GET implies HEAD. Registering a pattern again adds its methods to
+// the existing route, keeping the handlers already registered for a method.
+//
+// Handle is safe to call while the router serves requests, which see either
+// all or none of the methods registered by one call. The returned Route
+// describes the handler further.
+func (router *Router) Handle(path string, handler Handler, methods ...string) *Route {
+	return router.handle(nil, path, handler, methods, false)
+}
+
+// Replace is like Handle but swaps the handlers already registered for path
+// and methods, HEAD included when it was implied by GET. Requests being
+// served keep the handler they started with.
+func (router *Router) Replace(path string, handler Handler, methods ...string) *Route {
+	return router.handle(nil, path, handler, methods, true)
+}
+
+// Remove unregisters the handler of path for method, along with HEAD when it
+// was implied by GET, and reports whether there was one. path must be the
+// pattern as registered, not a path it matches. HEAD requests are answered
+// by GET for as long as it is registered. Routes registered through
+// Router.Version are removed with Group.Remove.
+func (router *Router) Remove(method string, path string) bool {
+	return router.remove("", method, path)
+}
+
+func (router *Router) remove(version string, method string, path string) bool {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	method = strings.ToUpper(method)
+
+	index := slices.IndexFunc(router.routes, func(route *route) bool {
+		return route.version == version && route.pattern.String() == path
+	})
+
+	if index == -1 {
+		return false
+	}
+
+	route := router.routes[index]
+
+	removed, ok := route.endpoints[method]
+	if !ok {
+		return false
+	}
+
+	route.remove(method)
+
+	if method == http.MethodGet && route.endpoints[http.MethodHead] == removed {
+		route.remove(http.MethodHead)
+	}
+
+	if len(route.methods) == 0 {
+		router.routes = slices.Delete(router.routes, index, index+1)
+	}
+
+	router.cache.clear()
+
+	return true
+}
+
+// endpoint returns the endpoint of method, HEAD falling back to GET.
+func (route *route) endpoint(method string) *endpoint {
+	if endpoint, ok := route.endpoints[method]; ok {
+		return endpoint
+	}
+
+	if method == http.MethodHead {
+		return route.endpoints[http.MethodGet]
+	}
+
+	return nil
+}
+
+// allowed returns the methods the route serves, in registration order, HEAD
+// included for GET.
+func (route *route) allowed() []string {
+	_, get := route.endpoints[http.MethodGet]
+	_, head := route.endpoints[http.MethodHead]
+
+	if !get || head {
+		return route.methods
+	}
+
+	return append(slices.Clip(route.methods), http.MethodHead)
+}
+
+// allowHeader formats the methods allowed for a path as an Allow header,
+// OPTIONS included, which the router always answers.
+func allowHeader(methods []string) string {
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	return strings.Join(methods, ", ")
+}
+
+func (route *route) remove(method string) {
+	delete(route.endpoints, method)
+	route.methods = slices.DeleteFunc(route.methods, func(m string) bool {
+		return m == method
+	})
+}
+
+func (router *Router) handle(group *Group, path string, handler Handler, methods []string, replace bool) *Route {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
 	if len(methods) == 0 {
 		methods = allMethods
 	}
 
-	segments := strings.Split(path, "/")
+	version := ""
+	if group != nil {
+		version = group.version
+	}
 
-	for index := 0; index < len(methods); index++ {
-		route := &route{
-			method:   strings.ToUpper(methods[index]),
-			segments: segments,
-			wildcard: strings.HasSuffix(path, "/..."),
-			handler:  router.wrap(handler),
-		}
+	route := router.route(path, version)
 
-		router.routes = append(router.routes, route)
+	endpoint := &endpoint{
+		handler: handler,
+		group:   group,
+		meta:    &routeMeta{pattern: route.pattern.String(), scratch: newScratchPool()},
 	}
 
-	for index := 0; index < len(segments); index++ {
-		if strings.HasPrefix(segments[index], ":") {
-			if _, rx, contains := strings.Cut(segments[index], "|"); contains {
-				rxPatterns[rx] = regexp.MustCompile(rx)
+	endpoint.compose(router)
+
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+
+		if existing, ok := route.endpoints[method]; ok {
+			if !replace {
+				continue
+			}
+
+			if method == http.MethodGet && route.endpoints[http.MethodHead] == existing {
+				route.endpoints[http.MethodHead] = endpoint
 			}
+		} else {
+			route.methods = append(route.methods, method)
+		}
+
+		route.endpoints[method] = endpoint
+	}
+
+	if _, ok := route.endpoints[http.MethodGet]; ok {
+		if _, ok := route.endpoints[http.MethodHead]; !ok {
+			route.methods = append(route.methods, http.MethodHead)
+			route.endpoints[http.MethodHead] = route.endpoints[http.MethodGet]
+		}
+	}
+
+	router.cache.clear()
+
+	return &Route{router: router, endpoint: endpoint}
+}
+
+// route returns the route registered for pattern and version, adding it if
+// needed. It must be called with router.mu held.
+func (router *Router) route(pattern string, version string) *route {
+	for _, route := range router.routes {
+		if route.version == version && route.pattern.String() == pattern {
+			return route
 		}
 	}
+
+	route := &route{
+		pattern:   MustParsePattern(pattern),
+		version:   version,
+		endpoints: make(map[string]*endpoint),
+	}
+
+	if version != "" {
+		router.versioned = true
+	}
+
+	router.routes = append(router.routes, route)
+
+	return route
 }
 
+// Use adds middlewares to the router. They apply to every route, including
+// those registered before, and run after the middlewares added earlier. Use
+// panics once the router is frozen.
 func (router *Router) Use(middlewares ...Middleware) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	if router.frozen {
+		panic("ibnsina: Use called on a frozen router")
+	}
+
 	router.middlewares = append(router.middlewares, middlewares...)
+	router.recompose()
+}
+
+// Freeze fixes the middlewares of the router and its groups: any later call
+// to Use panics. Call it once setup is done to make sure no middleware is
+// added after requests started being served. Routes can still be registered.
+func (router *Router) Freeze() {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	router.frozen = true
+}
+
+func (router *Router) recompose() {
+	for _, route := range router.routes {
+		for _, endpoint := range route.endpoints {
+			endpoint.compose(router)
+		}
+	}
 }
 
+// Group is a set of routes sharing middlewares, which run after those of the
+// router.
 type Group struct {
+	// Encoder overrides the encoder of the router for the routes of the
+	// group.
+	Encoder ResponseEncoder
+
 	router      *Router
+	version     string
 	middlewares []Middleware
+	headers     http.Header
 }
 
 func (router *Router) Group(middlewares ...Middleware) *Group {
@@ -212,53 +915,36 @@ func (router *Router) Group(middlewares ...Middleware) *Group {
 	}
 }
 
-func (group *Group) Handle(path string, handler Handler, methods ...string) {
-	group.router.Handle(path, group.router.wrap(handler), methods...)
+func (group *Group) Handle(path string, handler Handler, methods ...string) *Route {
+	return group.router.handle(group, path, handler, methods, false)
 }
 
-func (route *route) match(ctx context.Context, segments []string) (context.Context, bool) {
-	if !route.wildcard && len(segments) != len(route.segments) {
-		return ctx, false
-	}
-
-	for index, rs := range route.segments {
-		if index > len(segments)-1 {
-			return ctx, false
-		}
-
-		if rs == "..." {
-			ctx = context.WithValue(ctx, ctxKey("..."), strings.Join(segments[index:], "/"))
-			return ctx, true
-		}
-
-		if strings.HasPrefix(rs, ":") {
-			key, rx, contains := strings.Cut(strings.TrimPrefix(rs, ":"), "|")
-			if contains {
-				if rxPatterns[rx].MatchString(segments[index]) {
-					ctx = context.WithValue(ctx, ctxKey(key), segments[index])
-					continue
-				}
-			}
-
-			if !contains && segments[index] != "" {
-				ctx = context.WithValue(ctx, ctxKey(key), segments[index])
-				continue
-			}
+// Remove is like Router.Remove for the routes of the version of the group,
+// unversioned unless it comes from Router.Version. The route is removed for
+// the whole router, whichever group registered it.
+func (group *Group) Remove(method string, path string) bool {
+	return group.router.remove(group.version, method, path)
+}
 
-			return ctx, false
-		}
+// Use adds middlewares to the group, applying to the routes registered
+// through it before and after. Like Router.Use, it panics once the router is
+// frozen.
+func (group *Group) Use(middlewares ...Middleware) {
+	group.router.mu.Lock()
+	defer group.router.mu.Unlock()
 
-		if rs != segments[index] {
-			return ctx, false
-		}
+	if group.router.frozen {
+		panic("ibnsina: Use called on a frozen router")
 	}
 
-	return ctx, true
+	group.middlewares = append(group.middlewares, middlewares...)
+	group.router.recompose()
 }
 
-func (router *Router) wrap(handler Handler) Handler {
-	for index := len(router.middlewares) - 1; index > -1; index-- {
-		handler = router.middlewares[index](handler)
+// chain wraps handler in middlewares, the first one outermost.
+func chain(middlewares []Middleware, handler Handler) Handler {
+	for index := len(middlewares) - 1; index > -1; index-- {
+		handler = middlewares[index](handler)
 	}
 
 	return handler
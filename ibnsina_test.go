@@ -2,6 +2,7 @@ package ibnsina
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -98,7 +99,12 @@ func TestMatch(t *testing.T) {
 		{
 			[]string{"GET"}, "/path-params/:era",
 			"GET", "/path-params/a%3A%2F%2Fb%2Fc",
-			http.StatusOK, map[string]string{"era": "a%3A%2F%2Fb%2Fc"}, "",
+			http.StatusOK, map[string]string{"era": "a://b/c"}, "",
+		},
+		{
+			[]string{"GET"}, "/path-params/:name",
+			"GET", "/path-params/caf%C3%A9",
+			http.StatusOK, map[string]string{"name": "café"}, "",
 		},
 		// regexp
 		{
@@ -566,3 +572,303 @@ func TestParams(t *testing.T) {
 		}
 	}
 }
+
+func TestWithParams(t *testing.T) {
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(Param(ctx, "group") + "/" + Param(request.Context(), "member")))
+	}
+
+	ctx := WithParams(context.Background(), map[string]string{"group": "beatles", "member": "ringo"})
+	ctx = WithParams(ctx, map[string]string{"member": "lennon"})
+
+	request, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler(ctx, rr, request.WithContext(ctx))
+
+	if body := rr.Body.String(); body != "beatles/lennon" {
+		t.Errorf("expected body %q but was %q", "beatles/lennon", body)
+	}
+
+	if value := Param(context.Background(), "group"); value != "" {
+		t.Errorf("expected no param on an empty context, got %q", value)
+	}
+}
+
+func TestRawParams(t *testing.T) {
+	router := NewRouter()
+	router.RawParams = true
+
+	var name string
+
+	router.Handle("/users/:name", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		name = Param(ctx, "name")
+	}, http.MethodGet)
+
+	request := httptest.NewRequest(http.MethodGet, "/users/caf%C3%A9%2Fx", nil)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if name != "caf%C3%A9%2Fx" {
+		t.Errorf("expected the raw param but was %q", name)
+	}
+}
+
+func TestGetValues(t *testing.T) {
+	router := NewRouter(func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			next(ctx, response, request)
+
+			values := GetValues(ctx)
+			if values.Status != http.StatusCreated {
+				t.Errorf("expected status %d but was %d", http.StatusCreated, values.Status)
+			}
+
+			if _, ok := response.(http.Flusher); !ok {
+				t.Error("expected the response writer to remain a Flusher")
+			}
+		}
+	})
+
+	router.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		values := GetValues(request.Context())
+		if values == nil || values.TraceID == "" || values.Logger == nil {
+			t.Fatalf("expected the router to fill the request values, got %+v", values)
+		}
+
+		if values.Status != 0 {
+			t.Errorf("expected no status before writing, got %d", values.Status)
+		}
+
+		response.WriteHeader(http.StatusCreated)
+	}, http.MethodPost)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+
+	if response.Code != http.StatusCreated {
+		t.Errorf("expected status %d but was %d", http.StatusCreated, response.Code)
+	}
+
+	if GetValues(context.Background()) != nil {
+		t.Error("expected no values on an empty context")
+	}
+}
+
+func TestHandleSamePattern(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(body string) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.Write([]byte(body))
+		}
+	}
+
+	router.Handle("/users", handler("list"), http.MethodGet)
+	router.Handle("/users", handler("create"), http.MethodPost, http.MethodOptions)
+	router.Handle("/users", handler("ignored"), http.MethodGet)
+
+	if length := len(router.routes); length != 1 {
+		t.Fatalf("expected a single route entry but got %d", length)
+	}
+
+	tests := []struct {
+		method string
+		body   string
+	}{
+		{http.MethodGet, "list"},
+		{http.MethodPost, "create"},
+		{http.MethodOptions, "create"},
+	}
+
+	for _, test := range tests {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(test.method, "/users", nil))
+
+		if body := response.Body.String(); body != test.body {
+			t.Errorf("%s: expected body %q but was %q", test.method, test.body, body)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/users", nil))
+
+	if allow := response.Header().Get("Allow"); allow != "GET, HEAD, POST, OPTIONS" {
+		t.Errorf("expected Allow header %q but was %q", "GET, HEAD, POST, OPTIONS", allow)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var used string
+
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+				used += name
+				next(ctx, response, request)
+			}
+		}
+	}
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router := NewRouter(mw("1"))
+	router.Handle("/", handler, "GET")
+
+	group := router.Group(mw("3"))
+	group.Handle("/group", handler, "GET")
+
+	router.Use(mw("2"))
+	group.Use(mw("4"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/", "12"},
+		{"/group", "1234"},
+		{"/missing", "12"},
+	}
+
+	for _, test := range tests {
+		used = ""
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if used != test.expected {
+			t.Errorf("%s: expected middlewares %q but ran %q", test.path, test.expected, used)
+		}
+	}
+
+	router.Freeze()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Use to panic on a frozen router")
+		}
+	}()
+
+	router.Use(mw("5"))
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	router := NewRouter()
+	router.EnableMatchCache(10)
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router.Handle("/plugins", handler, http.MethodGet)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for index := 0; index < 100; index++ {
+			router.Handle(fmt.Sprintf("/plugins/%d", index), handler, http.MethodGet)
+
+			if index%10 == 0 {
+				router.Use(func(next Handler) Handler { return next })
+			}
+		}
+	}()
+
+	for index := 0; index < 100; index++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plugins", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/plugins/%d", index), nil))
+		router.Routes()
+	}
+
+	<-done
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/plugins/99", nil))
+
+	if response.Code != http.StatusOK {
+		t.Errorf("expected status %d but was %d", http.StatusOK, response.Code)
+	}
+}
+
+func TestRemoveAndReplace(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(body string) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.Write([]byte(body))
+		}
+	}
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(method, "/beta", nil))
+		return response
+	}
+
+	router.Handle("/beta", handler("v1"), http.MethodGet, http.MethodPost)
+
+	router.Replace("/beta", handler("v2"), http.MethodGet)
+
+	if body := serve(http.MethodGet).Body.String(); body != "v2" {
+		t.Errorf("expected the replaced handler to answer GET, got %q", body)
+	}
+
+	if body := serve(http.MethodHead).Body.String(); body != "v2" {
+		t.Errorf("expected the replaced handler to answer HEAD, got %q", body)
+	}
+
+	if !router.Remove("get", "/beta") {
+		t.Fatal("expected Remove to find the GET handler")
+	}
+
+	if router.Remove(http.MethodGet, "/beta") {
+		t.Error("expected a second Remove to report nothing removed")
+	}
+
+	response := serve(http.MethodHead)
+	if response.Code != http.StatusMethodNotAllowed || response.Header().Get("Allow") != "POST, OPTIONS" {
+		t.Errorf("expected HEAD to go with GET, got status %d and Allow %q", response.Code, response.Header().Get("Allow"))
+	}
+
+	router.Remove(http.MethodPost, "/beta")
+
+	if code := serve(http.MethodPost).Code; code != http.StatusNotFound {
+		t.Errorf("expected status %d once every method is removed but was %d", http.StatusNotFound, code)
+	}
+
+	if length := len(router.routes); length != 0 {
+		t.Errorf("expected the empty route to be dropped, %d left", length)
+	}
+}
+
+func TestAllowHeader(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router.Handle("/items", handler, http.MethodGet, http.MethodOptions)
+	router.Handle("/:name", handler, http.MethodPut)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/items", nil))
+
+	if allow := response.Header().Get("Allow"); allow != "GET, OPTIONS, HEAD, PUT" {
+		t.Errorf("expected OPTIONS once across routes, got Allow %q", allow)
+	}
+
+	router.Remove(http.MethodHead, "/items")
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodHead, "/items", nil))
+
+	if response.Code != http.StatusOK {
+		t.Errorf("expected GET to answer HEAD, got status %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/items", nil))
+
+	if allow := response.Header().Get("Allow"); allow != "GET, OPTIONS, HEAD, PUT" {
+		t.Errorf("expected HEAD to stay allowed with GET, got Allow %q", allow)
+	}
+}
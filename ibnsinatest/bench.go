@@ -0,0 +1,75 @@
+package ibnsinatest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// discardWriter is a ResponseWriter that throws the response away, so that
+// benchmarks measure the handler rather than a recorder buffering the body.
+type discardWriter struct {
+	header http.Header
+}
+
+func (writer *discardWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *discardWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (writer *discardWriter) WriteHeader(int) {}
+
+func (writer *discardWriter) reset() {
+	clear(writer.header)
+}
+
+// Benchmark serves requests to handler in turn, b.N times in total, reporting
+// allocations. Requests are reused, so their bodies should be nil.
+//
+//	func BenchmarkRoutes(b *testing.B) {
+//		ibnsinatest.Benchmark(b, router,
+//			httptest.NewRequest("GET", "/users/42", nil),
+//			httptest.NewRequest("GET", "/static/css/site.css", nil),
+//		)
+//	}
+func Benchmark(b *testing.B, handler http.Handler, requests ...*http.Request) {
+	b.Helper()
+
+	if len(requests) == 0 {
+		b.Fatal("ibnsinatest: no requests to benchmark")
+	}
+
+	writer := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for index := 0; index < b.N; index++ {
+		writer.reset()
+		handler.ServeHTTP(writer, requests[index%len(requests)])
+	}
+}
+
+// AllocsPerRequest returns the average number of allocations handler makes
+// serving request.
+func AllocsPerRequest(handler http.Handler, request *http.Request) float64 {
+	writer := &discardWriter{header: make(http.Header)}
+
+	return testing.AllocsPerRun(100, func() {
+		writer.reset()
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// AssertAllocs fails the test when handler makes more than max allocations
+// serving request, which makes routing regressions fail CI rather than only
+// show up in benchmark numbers.
+func AssertAllocs(t testing.TB, handler http.Handler, request *http.Request, max float64) {
+	t.Helper()
+
+	if allocs := AllocsPerRequest(handler, request); allocs > max {
+		t.Errorf("ibnsinatest: %s %s: expected at most %.0f allocations but got %.1f", request.Method, request.URL.Path, max, allocs)
+	}
+}
@@ -0,0 +1,41 @@
+package ibnsinatest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is an ibnsina.Clock that only moves when told to, so time-dependent
+// handlers and middlewares can be tested deterministically.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock stopped at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (clock *Clock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+// Set moves the clock to now.
+func (clock *Clock) Set(now time.Time) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.now = now
+}
+
+// Advance moves the clock forward by duration.
+func (clock *Clock) Advance(duration time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.now = clock.now.Add(duration)
+}
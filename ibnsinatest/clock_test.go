@@ -0,0 +1,35 @@
+package ibnsinatest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/i33ym/ibnsina"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	router := NewTestRouter(t)
+	router.Clock = clock
+
+	var now time.Time
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		now = ibnsina.GetValues(ctx).Now
+	}, "GET")
+
+	Request("GET", "/", nil).Serve(router)
+	if !now.Equal(start) {
+		t.Errorf("expected %s but was %s", start, now)
+	}
+
+	clock.Advance(time.Hour)
+
+	Request("GET", "/", nil).Serve(router)
+	if !now.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected %s but was %s", start.Add(time.Hour), now)
+	}
+}
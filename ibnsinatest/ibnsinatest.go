@@ -0,0 +1,243 @@
+// Package ibnsinatest provides utilities for testing ibnsina routers and
+// handlers, in the spirit of net/http/httptest.
+package ibnsinatest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/i33ym/ibnsina"
+)
+
+// TraceID is the trace id of the Values built by NewContext.
+const TraceID = "00000000-0000-0000-0000-000000000000"
+
+// SequentialTraceIDs returns a generator for Router.NewTraceID counting up
+// from TraceID, so golden files and log assertions see the same trace ids
+// on every run.
+func SequentialTraceIDs() func() string {
+	var count atomic.Uint64
+
+	return func() string {
+		return fmt.Sprintf("00000000-0000-0000-0000-%012d", count.Add(1))
+	}
+}
+
+// NewTestRouter returns a router whose not found and method not allowed
+// handlers fail the test, which catches typos in request paths early. Reset
+// them on the returned router for tests that expect those statuses. Trace
+// ids are generated by SequentialTraceIDs.
+func NewTestRouter(t testing.TB, middlewares ...ibnsina.Middleware) *ibnsina.Router {
+	t.Helper()
+
+	router := ibnsina.NewRouter(middlewares...)
+	router.NewTraceID = SequentialTraceIDs()
+
+	router.NotFound = func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		t.Errorf("ibnsinatest: no route for %s %s", request.Method, request.URL.Path)
+		response.WriteHeader(http.StatusNotFound)
+	}
+
+	router.MethodNotAllowed = func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		t.Errorf("ibnsinatest: method %s not allowed for %s", request.Method, request.URL.Path)
+		response.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+	return router
+}
+
+// NewContext returns a background context carrying values and params, as
+// handlers receive it from the router. A zero TraceID or Now is filled in.
+func NewContext(values ibnsina.Values, params map[string]string) context.Context {
+	if values.TraceID == "" {
+		values.TraceID = TraceID
+	}
+
+	if values.Now.IsZero() {
+		values.Now = time.Now()
+	}
+
+	return ibnsina.WithParams(ibnsina.WithValues(context.Background(), values), params)
+}
+
+type TestRequest struct {
+	*http.Request
+	values ibnsina.Values
+	params map[string]string
+}
+
+// Request builds a request for a handler or router test. body may be nil, a
+// string, a []byte, an io.Reader, or any other value, which is encoded as
+// JSON and sets the Content-Type accordingly.
+func Request(method string, path string, body any) *TestRequest {
+	var reader io.Reader
+	contentType := ""
+
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	case io.Reader:
+		reader = b
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			panic("ibnsinatest: " + err.Error())
+		}
+
+		reader = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	request := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	return &TestRequest{
+		Request: request,
+		params:  make(map[string]string),
+	}
+}
+
+func (request *TestRequest) WithParam(name string, value string) *TestRequest {
+	request.params[name] = value
+	return request
+}
+
+func (request *TestRequest) WithHeader(name string, value string) *TestRequest {
+	request.Header.Set(name, value)
+	return request
+}
+
+func (request *TestRequest) WithValues(values ibnsina.Values) *TestRequest {
+	request.values = values
+	return request
+}
+
+// Context returns the context the handler is called with by Run.
+func (request *TestRequest) Context() context.Context {
+	return NewContext(request.values, request.params)
+}
+
+// Run calls handler directly, without any router or middleware, with the
+// params and values set on the request.
+func (request *TestRequest) Run(handler ibnsina.Handler) *Response {
+	ctx := request.Context()
+	recorder := httptest.NewRecorder()
+
+	handler(ctx, recorder, request.Request.WithContext(ctx))
+
+	return &Response{recorder}
+}
+
+// Serve sends the request through handler, usually a router. Params set with
+// WithParam are ignored since the router extracts them from the path.
+func (request *TestRequest) Serve(handler http.Handler) *Response {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request.Request)
+
+	return &Response{recorder}
+}
+
+type Response struct {
+	*httptest.ResponseRecorder
+}
+
+func (response *Response) AssertStatus(t testing.TB, status int) {
+	t.Helper()
+
+	if response.Code != status {
+		t.Errorf("expected status %d but was %d: %s", status, response.Code, response.Body.String())
+	}
+}
+
+func (response *Response) AssertHeader(t testing.TB, name string, value string) {
+	t.Helper()
+
+	if actual := response.Header().Get(name); actual != value {
+		t.Errorf("expected header %s %q but was %q", name, value, actual)
+	}
+}
+
+func (response *Response) AssertBody(t testing.TB, body string) {
+	t.Helper()
+
+	if actual := response.Body.String(); actual != body {
+		t.Errorf("expected body %q but was %q", body, actual)
+	}
+}
+
+// AssertJSON compares the response body with the JSON encoding of expected,
+// ignoring formatting and key order.
+func (response *Response) AssertJSON(t testing.TB, expected any) {
+	t.Helper()
+
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("ibnsinatest: %s", err)
+	}
+
+	var want, got any
+
+	if err := json.Unmarshal(encoded, &want); err != nil {
+		t.Fatalf("ibnsinatest: %s", err)
+	}
+
+	if err := json.Unmarshal(response.Body.Bytes(), &got); err != nil {
+		t.Errorf("expected a JSON body but got %q: %s", response.Body.String(), err)
+		return
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected JSON body %s but was %s", encoded, strings.TrimSpace(response.Body.String()))
+	}
+}
+
+// DecodeJSON decodes the response body into dst, failing the test on error.
+func (response *Response) DecodeJSON(t testing.TB, dst any) {
+	t.Helper()
+
+	if err := json.Unmarshal(response.Body.Bytes(), dst); err != nil {
+		t.Fatalf("ibnsinatest: decoding %q: %s", response.Body.String(), err)
+	}
+}
+
+// AssertSnapshot compares the route table of router, as rendered by
+// Router.Snapshot, with the golden file at path. Run the tests with
+// IBNSINA_UPDATE_SNAPSHOTS=1 to write the current table to the file instead.
+func AssertSnapshot(t testing.TB, router *ibnsina.Router, path string) {
+	t.Helper()
+
+	snapshot := router.Snapshot()
+
+	if os.Getenv("IBNSINA_UPDATE_SNAPSHOTS") != "" {
+		if err := os.WriteFile(path, []byte(snapshot), 0o644); err != nil {
+			t.Fatalf("ibnsinatest: %s", err)
+		}
+
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ibnsinatest: %s (run with IBNSINA_UPDATE_SNAPSHOTS=1 to create it)", err)
+	}
+
+	if string(golden) != snapshot {
+		t.Errorf("route table does not match %s\nexpected:\n%s\nactual:\n%s", path, golden, snapshot)
+	}
+}
@@ -0,0 +1,88 @@
+package ibnsinatest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/i33ym/ibnsina"
+)
+
+func TestRun(t *testing.T) {
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+
+		validator := ibnsina.NewValidator()
+		ibnsina.Bind(request, &body, validator)
+
+		ibnsina.WriteJSON(ctx, response, http.StatusCreated, map[string]string{
+			"id":   ibnsina.Param(request.Context(), "id"),
+			"name": body.Name,
+		})
+	}
+
+	response := Request("PUT", "/users/42", map[string]string{"name": "ibn"}).
+		WithParam("id", "42").
+		Run(handler)
+
+	response.AssertStatus(t, http.StatusCreated)
+	response.AssertHeader(t, "Content-Type", "application/json")
+	response.AssertJSON(t, map[string]string{"id": "42", "name": "ibn"})
+}
+
+func TestServe(t *testing.T) {
+	router := NewTestRouter(t)
+
+	router.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(ibnsina.Param(request.Context(), "id")))
+	}, "GET")
+
+	response := Request("GET", "/users/7", nil).Serve(router)
+
+	response.AssertStatus(t, http.StatusOK)
+	response.AssertBody(t, "7")
+}
+
+func TestAssertSnapshot(t *testing.T) {
+	router := ibnsina.NewRouter()
+	router.Handle("/health", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	path := filepath.Join(t.TempDir(), "routes.golden")
+
+	t.Setenv("IBNSINA_UPDATE_SNAPSHOTS", "1")
+	AssertSnapshot(t, router, path)
+
+	t.Setenv("IBNSINA_UPDATE_SNAPSHOTS", "")
+	AssertSnapshot(t, router, path)
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+
+	if string(golden) != "GET /health []\nHEAD /health []\n" {
+		t.Errorf("unexpected golden file %q", golden)
+	}
+}
+
+func TestSequentialTraceIDs(t *testing.T) {
+	router := NewTestRouter(t)
+
+	var traceIDs []string
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		traceIDs = append(traceIDs, ibnsina.GetValues(ctx).TraceID)
+	}, "GET")
+
+	Request("GET", "/", nil).Serve(router)
+	Request("GET", "/", nil).Serve(router)
+
+	expected := []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"}
+	if !slices.Equal(traceIDs, expected) {
+		t.Errorf("expected trace ids %v but were %v", expected, traceIDs)
+	}
+}
@@ -0,0 +1,130 @@
+package ibnsinatest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/i33ym/ibnsina"
+)
+
+// Replay sends the interactions recorded by ibnsina.Record in the file at
+// path to handler, one subtest each, and checks the responses keep the
+// recorded contract: the same status, Content-Type and body. JSON bodies are
+// compared as values; recorded ibnsina.Redacted values match anything and
+// trace_id fields are ignored, as they differ on every request.
+func Replay(t *testing.T, handler http.Handler, path string) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("ibnsinatest: %s", err)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var interaction ibnsina.Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			t.Fatalf("ibnsinatest: %s:%d: %s", path, line, err)
+		}
+
+		name := interaction.Request.Method + " " + interaction.Request.URI
+
+		t.Run(name, func(t *testing.T) {
+			request := httptest.NewRequest(interaction.Request.Method, interaction.Request.URI, strings.NewReader(interaction.Request.Body))
+			request.Header = interaction.Request.Header.Clone()
+			if request.Header == nil {
+				request.Header = http.Header{}
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			expected := interaction.Response
+
+			if recorder.Code != expected.Status {
+				t.Errorf("expected status %d but was %d: %s", expected.Status, recorder.Code, recorder.Body.String())
+			}
+
+			if want, got := expected.Header.Get("Content-Type"), recorder.Header().Get("Content-Type"); want != got {
+				t.Errorf("expected Content-Type %q but was %q", want, got)
+			}
+
+			if !contractBodyEqual(expected.Body, recorder.Body.String()) {
+				t.Errorf("expected body %s but was %s", strings.TrimSpace(expected.Body), strings.TrimSpace(recorder.Body.String()))
+			}
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("ibnsinatest: %s: %s", path, err)
+	}
+}
+
+func contractBodyEqual(expected string, actual string) bool {
+	var want, got any
+
+	if json.Unmarshal([]byte(expected), &want) != nil || json.Unmarshal([]byte(actual), &got) != nil {
+		return expected == actual
+	}
+
+	return contractValueEqual(want, got)
+}
+
+func contractValueEqual(want any, got any) bool {
+	if want == ibnsina.Redacted {
+		return true
+	}
+
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		delete(w, "trace_id")
+		delete(g, "trace_id")
+
+		if len(w) != len(g) {
+			return false
+		}
+
+		for key, value := range w {
+			other, ok := g[key]
+			if !ok || !contractValueEqual(value, other) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(w) != len(g) {
+			return false
+		}
+
+		for index := range w {
+			if !contractValueEqual(w[index], g[index]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(want, got)
+}
@@ -0,0 +1,48 @@
+package ibnsinatest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/i33ym/ibnsina"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var recorded bytes.Buffer
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		ibnsina.WriteJSON(ctx, response, http.StatusCreated, map[string]any{
+			"id":       7,
+			"token":    "s3cr3t",
+			"trace_id": ibnsina.GetValues(ctx).TraceID,
+		})
+	}
+
+	router := ibnsina.NewRouter(ibnsina.Record(ibnsina.RecordOptions{Writer: &recorded}))
+	router.Handle("/users", handler, http.MethodPost)
+
+	request := Request(http.MethodPost, "/users", map[string]string{"name": "ibn", "password": "hunter2"}).
+		WithHeader("Authorization", "Bearer abc")
+	request.Serve(router).AssertStatus(t, http.StatusCreated)
+
+	for _, secret := range []string{"hunter2", "Bearer abc", "s3cr3t"} {
+		if strings.Contains(recorded.String(), secret) {
+			t.Errorf("expected %q to be redacted from %s", secret, recorded.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "contract.jsonl")
+	if err := os.WriteFile(path, recorded.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	replayed := ibnsina.NewRouter()
+	replayed.Handle("/users", handler, http.MethodPost)
+
+	Replay(t, replayed, path)
+}
@@ -0,0 +1,99 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultSizeBuckets are the default bucket bounds of the body size
+// histograms of Instrument, in bytes, from 100 B to 1 GB.
+var DefaultSizeBuckets = []float64{100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+
+// DefaultDurationBuckets are the default bucket bounds of the duration
+// histogram of Instrument, in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// InstrumentOptions configures Instrument.
+type InstrumentOptions struct {
+	// DurationBuckets bound the buckets of the duration histogram in
+	// seconds, DefaultDurationBuckets if nil.
+	DurationBuckets []float64
+
+	// RequestSizeBuckets and ResponseSizeBuckets bound the buckets of the
+	// body size histograms in bytes, DefaultSizeBuckets if nil.
+	RequestSizeBuckets  []float64
+	ResponseSizeBuckets []float64
+}
+
+// Instrument is a middleware recording, per route pattern, the metrics
+//
+//	http_requests_total{method,route,status}
+//	http_request_duration_seconds{route}
+//	http_request_size_bytes{route}
+//	http_response_size_bytes{route}
+//
+// Request sizes are the bytes of the body read by the handler, or its
+// Content-Length if larger. Requests matching no route have an empty route.
+func Instrument(metrics *Metrics, options InstrumentOptions) Middleware {
+	if options.DurationBuckets == nil {
+		options.DurationBuckets = DefaultDurationBuckets
+	}
+
+	if options.RequestSizeBuckets == nil {
+		options.RequestSizeBuckets = DefaultSizeBuckets
+	}
+
+	if options.ResponseSizeBuckets == nil {
+		options.ResponseSizeBuckets = DefaultSizeBuckets
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			start := time.Now()
+
+			body := &countingReader{ReadCloser: request.Body}
+			if request.Body != nil {
+				request.Body = body
+			}
+
+			next(ctx, response, request)
+
+			values := GetValues(ctx)
+			if values == nil {
+				return
+			}
+
+			status := values.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := "{route=" + labelValue(RoutePattern(ctx)) + "}"
+
+			requestSize := body.read
+			if request.ContentLength > requestSize {
+				requestSize = request.ContentLength
+			}
+
+			metrics.Counter("http_requests_total{method=" + labelValue(request.Method) + ",route=" + labelValue(RoutePattern(ctx)) + ",status=" + labelValue(strconv.Itoa(status)) + "}").Inc()
+			metrics.Histogram("http_request_duration_seconds"+route, options.DurationBuckets).Observe(time.Since(start).Seconds())
+			metrics.Histogram("http_request_size_bytes"+route, options.RequestSizeBuckets).Observe(float64(requestSize))
+			metrics.Histogram("http_response_size_bytes"+route, options.ResponseSizeBuckets).Observe(float64(values.writer.written))
+		}
+	}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	read int64
+}
+
+func (reader *countingReader) Read(data []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(data)
+	reader.read += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,49 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	metrics := NewMetrics()
+
+	router := NewRouter(Instrument(metrics, InstrumentOptions{
+		RequestSizeBuckets:  []float64{10, 1000},
+		ResponseSizeBuckets: []float64{10},
+	}))
+
+	router.Handle("/uploads/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		io.Copy(io.Discard, request.Body)
+		response.Write([]byte(strings.Repeat("x", 50)))
+	}, "PUT")
+
+	request := httptest.NewRequest("PUT", "/uploads/1", strings.NewReader(strings.Repeat("x", 500)))
+	request.ContentLength = -1
+
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	var builder strings.Builder
+	metrics.WriteTo(&builder)
+
+	for _, line := range []string{
+		`http_requests_total{method="PUT",route="/uploads/:id",status="200"} 1`,
+		`http_requests_total{method="GET",route="",status="404"} 1`,
+		`http_request_size_bytes_bucket{route="/uploads/:id",le="10"} 0`,
+		`http_request_size_bytes_bucket{route="/uploads/:id",le="1000"} 1`,
+		`http_request_size_bytes_sum{route="/uploads/:id"} 500`,
+		`http_response_size_bytes_bucket{route="/uploads/:id",le="10"} 0`,
+		`http_response_size_bytes_bucket{route="/uploads/:id",le="+Inf"} 1`,
+		`http_response_size_bytes_count{route="/uploads/:id"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(builder.String(), line+"\n") {
+			t.Errorf("expected metrics to contain %s but were\n%s", line, builder.String())
+		}
+	}
+}
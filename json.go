@@ -0,0 +1,35 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// WriteJSON encodes v before touching the response, so an encoding failure
+// can still be reported with a proper status code. It encodes into a
+// Scratch buffer. A failure to write the body fails the response.
+func WriteJSON(ctx context.Context, response http.ResponseWriter, status int, v any) error {
+	body := Scratch(ctx)
+	if err := json.NewEncoder(body).Encode(v); err != nil {
+		return err
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(status)
+
+	if _, err := response.Write(body.Bytes()); err != nil {
+		failResponse(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// ValidationFailed responds with 422 Unprocessable Entity and the errors
+// collected by validator:
+//
+//	{"message": "...", "trace_id": "...", "fields": {...}, "errors": [...]}
+func ValidationFailed(ctx context.Context, response http.ResponseWriter, validator *Validator) error {
+	return JSONEncoder{}.EncodeError(ctx, response, &StatusError{Status: http.StatusUnprocessableEntity, Message: "the request failed validation"}, validator)
+}
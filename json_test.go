@@ -0,0 +1,147 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationFailed(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/users", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		validator := NewValidator()
+		validator.CheckCode(MinRunes("", 1), "name", CodeRequired, "must be provided")
+		validator.AddNonFieldError("passwords do not match")
+
+		if !validator.Ok() {
+			ValidationFailed(ctx, response, validator)
+		}
+	}, "POST")
+
+	request, err := http.NewRequest("POST", "/users", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d but was %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected Content-Type %q but was %q", "application/json", contentType)
+	}
+
+	var body struct {
+		Message string `json:"message"`
+		TraceID string `json:"trace_id"`
+		Fields  map[string]struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"fields"`
+		Errors []string `json:"errors"`
+	}
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+
+	if body.TraceID == "" || body.TraceID != rr.Header().Get(TraceIDHeader) {
+		t.Errorf("expected trace id %q but was %q", rr.Header().Get(TraceIDHeader), body.TraceID)
+	}
+
+	if body.Fields["name"].Code != CodeRequired || body.Fields["name"].Message != "must be provided" {
+		t.Errorf("unexpected fields %+v", body.Fields)
+	}
+
+	if len(body.Errors) != 1 || body.Errors[0] != "passwords do not match" {
+		t.Errorf("unexpected errors %v", body.Errors)
+	}
+}
+
+func TestFailedResponse(t *testing.T) {
+	logger := &testLogger{}
+
+	var status int
+	var writeErr error
+
+	router := NewRouter(func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			next(ctx, response, request)
+			status = GetValues(ctx).Status
+		}
+	})
+	router.Logger = logger
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		err := JSONStream(ctx, response, func(yield func(any) bool) {
+			yield(1)
+			yield(func() {})
+		})
+
+		writeError(ctx, response, err)
+		writeErr = WriteJSON(ctx, response, http.StatusInternalServerError, map[string]string{"message": "failed"})
+	}, "GET")
+
+	rr := httptest.NewRecorder()
+	serveAborted(t, router, rr, httptest.NewRequest("GET", "/orders", nil))
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "[1\n" {
+		t.Errorf("expected the truncated body alone, got %d %q", rr.Code, rr.Body)
+	}
+
+	if status != http.StatusInternalServerError || writeErr != ErrResponseFailed {
+		t.Errorf("expected status %d and ErrResponseFailed but got %d %v", http.StatusInternalServerError, status, writeErr)
+	}
+
+	traceID := rr.Header().Get(TraceIDHeader)
+	if len(logger.lines) != 1 || !strings.HasPrefix(logger.lines[0], traceID+": the response failed after status 200") {
+		t.Errorf("expected the failure logged once with the trace ID, got %q", logger.lines)
+	}
+}
+
+// serveAborted serves request with handler, which must abort the response
+// with http.ErrAbortHandler, as the Router does for failed responses.
+func serveAborted(t *testing.T, handler http.Handler, response http.ResponseWriter, request *http.Request) {
+	t.Helper()
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected the response to be aborted but got %v", recovered)
+		}
+	}()
+
+	handler.ServeHTTP(response, request)
+}
+
+func TestFailedResponseAborts(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		JSONStream(ctx, response, func(yield func(any) bool) {
+			yield(1)
+			yield(func() {})
+		})
+	}, "GET")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// the header may not have been sent before the connection is reset
+	response, err := http.Get(server.URL + "/orders")
+	if err == nil {
+		defer response.Body.Close()
+		_, err = io.ReadAll(response.Body)
+	}
+
+	if err == nil {
+		t.Error("expected the client to see the response fail")
+	}
+}
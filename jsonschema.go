@@ -0,0 +1,653 @@
+package ibnsina
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pborman/uuid"
+)
+
+// JSONSchema is a compiled JSON Schema document. It implements the validation
+// vocabulary commonly used for request contracts: type, enum, const,
+// properties, required, additionalProperties, items, the size, range, pattern
+// and format keywords, allOf, anyOf, oneOf, not, and local $ref pointers such
+// as "#/$defs/address". Unknown keywords are ignored, as the specification
+// requires.
+type JSONSchema struct {
+	boolean *bool
+
+	types    []string
+	enum     []any
+	constant any
+	hasConst bool
+
+	properties           map[string]*JSONSchema
+	required             []string
+	additionalProperties *JSONSchema
+	minProperties        *int
+	maxProperties        *int
+
+	items       *JSONSchema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*JSONSchema
+	anyOf []*JSONSchema
+	oneOf []*JSONSchema
+	not   *JSONSchema
+
+	ref    string
+	target *JSONSchema
+}
+
+type schemaRoot struct {
+	document any
+	refs     map[string]*JSONSchema
+
+	// unresolved are the schemas compiled with a $ref not resolved yet
+	unresolved []*JSONSchema
+}
+
+// LoadJSONSchema compiles a JSON Schema document, typically once at startup.
+// Its references are resolved, so that a dangling one fails here rather than
+// when validating.
+func LoadJSONSchema(data []byte) (*JSONSchema, error) {
+	document, err := decodeJSONNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &schemaRoot{document: document, refs: make(map[string]*JSONSchema)}
+
+	schema, err := compileSchema(document, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := root.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func MustLoadJSONSchema(data []byte) *JSONSchema {
+	schema, err := LoadJSONSchema(data)
+	if err != nil {
+		panic("ibnsina: " + err.Error())
+	}
+
+	return schema
+}
+
+// Validate checks a decoded value against the schema, adding violations to
+// validator keyed by the JSON pointer of the offending value, for example
+// "/items/0/quantity". Violations of the document as a whole are added as
+// non-field errors. Values other than the output of encoding/json, such as
+// request structs, are converted through their JSON encoding first.
+func (schema *JSONSchema) Validate(value any, validator *Validator) error {
+	instance, err := normalizeJSONValue(value)
+	if err != nil {
+		return err
+	}
+
+	schema.validate(instance, "", validator)
+
+	return nil
+}
+
+// ValidateJSON checks a raw JSON document against the schema.
+func (schema *JSONSchema) ValidateJSON(data []byte, validator *Validator) error {
+	instance, err := decodeJSONNumbers(data)
+	if err != nil {
+		return err
+	}
+
+	schema.validate(instance, "", validator)
+
+	return nil
+}
+
+func compileSchema(document any, root *schemaRoot) (*JSONSchema, error) {
+	schema := &JSONSchema{}
+
+	if boolean, ok := document.(bool); ok {
+		schema.boolean = &boolean
+		return schema, nil
+	}
+
+	object, ok := document.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("json schema: expected an object or a boolean, got %T", document)
+	}
+
+	var err error
+
+	if ref, ok := object["$ref"].(string); ok {
+		schema.ref = ref
+		root.unresolved = append(root.unresolved, schema)
+	}
+
+	switch types := object["type"].(type) {
+	case string:
+		schema.types = []string{types}
+	case []any:
+		for _, t := range types {
+			if name, ok := t.(string); ok {
+				schema.types = append(schema.types, name)
+			}
+		}
+	}
+
+	if enum, ok := object["enum"].([]any); ok {
+		schema.enum = enum
+	}
+
+	if constant, exists := object["const"]; exists {
+		schema.constant, schema.hasConst = constant, true
+	}
+
+	if properties, ok := object["properties"].(map[string]any); ok {
+		schema.properties = make(map[string]*JSONSchema, len(properties))
+
+		for name, property := range properties {
+			if schema.properties[name], err = compileSchema(property, root); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if required, ok := object["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				schema.required = append(schema.required, name)
+			}
+		}
+	}
+
+	subschemas := map[string]**JSONSchema{
+		"additionalProperties": &schema.additionalProperties,
+		"items":                &schema.items,
+		"not":                  &schema.not,
+	}
+
+	for keyword, target := range subschemas {
+		if sub, exists := object[keyword]; exists {
+			if *target, err = compileSchema(sub, root); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lists := map[string]*[]*JSONSchema{
+		"allOf": &schema.allOf,
+		"anyOf": &schema.anyOf,
+		"oneOf": &schema.oneOf,
+	}
+
+	for keyword, target := range lists {
+		if subs, ok := object[keyword].([]any); ok {
+			for _, sub := range subs {
+				compiled, err := compileSchema(sub, root)
+				if err != nil {
+					return nil, err
+				}
+
+				*target = append(*target, compiled)
+			}
+		}
+	}
+
+	integers := map[string]**int{
+		"minProperties": &schema.minProperties,
+		"maxProperties": &schema.maxProperties,
+		"minItems":      &schema.minItems,
+		"maxItems":      &schema.maxItems,
+		"minLength":     &schema.minLength,
+		"maxLength":     &schema.maxLength,
+	}
+
+	for keyword, target := range integers {
+		if number, ok := toFloat(object[keyword]); ok {
+			n := int(number)
+			*target = &n
+		}
+	}
+
+	numbers := map[string]**float64{
+		"minimum":          &schema.minimum,
+		"maximum":          &schema.maximum,
+		"exclusiveMinimum": &schema.exclusiveMinimum,
+		"exclusiveMaximum": &schema.exclusiveMaximum,
+		"multipleOf":       &schema.multipleOf,
+	}
+
+	for keyword, target := range numbers {
+		if number, ok := toFloat(object[keyword]); ok {
+			*target = &number
+		}
+	}
+
+	if unique, ok := object["uniqueItems"].(bool); ok {
+		schema.uniqueItems = unique
+	}
+
+	if pattern, ok := object["pattern"].(string); ok {
+		if schema.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("json schema: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if format, ok := object["format"].(string); ok {
+		schema.format = format
+	}
+
+	return schema, nil
+}
+
+// resolveAll resolves the references of the schemas compiled so far, and of
+// the schemas they reference in turn.
+func (root *schemaRoot) resolveAll() error {
+	for len(root.unresolved) > 0 {
+		schema := root.unresolved[len(root.unresolved)-1]
+		root.unresolved = root.unresolved[:len(root.unresolved)-1]
+
+		target, err := root.resolve(schema.ref)
+		if err != nil {
+			return err
+		}
+
+		schema.target = target
+	}
+
+	return nil
+}
+
+func (root *schemaRoot) resolve(ref string) (*JSONSchema, error) {
+	if schema, exists := root.refs[ref]; exists {
+		return schema, nil
+	}
+
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("json schema: only local references are supported, got %q", ref)
+	}
+
+	document := root.document
+
+	if pointer := strings.TrimPrefix(ref, "#"); pointer != "" {
+		for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+			switch node := document.(type) {
+			case map[string]any:
+				document = node[token]
+			case []any:
+				index, err := strconv.Atoi(token)
+				if err != nil || index < 0 || index >= len(node) {
+					return nil, fmt.Errorf("json schema: unresolvable reference %q", ref)
+				}
+
+				document = node[index]
+			default:
+				return nil, fmt.Errorf("json schema: unresolvable reference %q", ref)
+			}
+		}
+	}
+
+	schema, err := compileSchema(document, root)
+	if err != nil {
+		return nil, err
+	}
+
+	root.refs[ref] = schema
+
+	return schema, nil
+}
+
+func (schema *JSONSchema) validate(instance any, pointer string, validator *Validator) {
+	report := func(code string, message string) {
+		if pointer == "" {
+			validator.AddNonFieldError(message)
+			return
+		}
+
+		validator.AddFieldError(pointer, code, message)
+	}
+
+	if schema.boolean != nil {
+		if !*schema.boolean {
+			report(CodeNotAllowed, "is not allowed")
+		}
+
+		return
+	}
+
+	if schema.target != nil {
+		schema.target.validate(instance, pointer, validator)
+	}
+
+	if len(schema.types) > 0 && !slices.ContainsFunc(schema.types, func(name string) bool { return isJSONType(instance, name) }) {
+		report(CodeInvalidType, "must be of type "+strings.Join(schema.types, " or "))
+		return
+	}
+
+	if schema.enum != nil && !slices.ContainsFunc(schema.enum, func(value any) bool { return jsonEqual(instance, value) }) {
+		report(CodeNotAllowed, "must be one of the allowed values")
+	}
+
+	if schema.hasConst && !jsonEqual(instance, schema.constant) {
+		report(CodeNotAllowed, "must be equal to the constant value")
+	}
+
+	switch value := instance.(type) {
+	case map[string]any:
+		schema.validateObject(value, pointer, validator, report)
+	case []any:
+		schema.validateArray(value, pointer, validator, report)
+	case string:
+		schema.validateString(value, report)
+	case json.Number:
+		number, _ := value.Float64()
+		schema.validateNumber(number, report)
+	}
+
+	for _, sub := range schema.allOf {
+		sub.validate(instance, pointer, validator)
+	}
+
+	if len(schema.anyOf) > 0 && countMatches(schema.anyOf, instance) == 0 {
+		report(CodeInvalid, "must match at least one of the allowed schemas")
+	}
+
+	if len(schema.oneOf) > 0 && countMatches(schema.oneOf, instance) != 1 {
+		report(CodeInvalid, "must match exactly one of the allowed schemas")
+	}
+
+	if schema.not != nil && countMatches([]*JSONSchema{schema.not}, instance) == 1 {
+		report(CodeNotAllowed, "must not match the disallowed schema")
+	}
+}
+
+func (schema *JSONSchema) validateObject(object map[string]any, pointer string, validator *Validator, report func(string, string)) {
+	for _, name := range schema.required {
+		if _, exists := object[name]; !exists {
+			validator.AddFieldError(pointer+"/"+escapePointer(name), CodeRequired, "must be provided")
+		}
+	}
+
+	if schema.minProperties != nil && len(object) < *schema.minProperties {
+		report(CodeTooShort, fmt.Sprintf("must have at least %d properties", *schema.minProperties))
+	}
+
+	if schema.maxProperties != nil && len(object) > *schema.maxProperties {
+		report(CodeTooLong, fmt.Sprintf("must have at most %d properties", *schema.maxProperties))
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+
+	// deterministic order keeps the first error per key stable
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := pointer + "/" + escapePointer(name)
+
+		if property, exists := schema.properties[name]; exists {
+			property.validate(object[name], child, validator)
+			continue
+		}
+
+		if schema.additionalProperties != nil {
+			schema.additionalProperties.validate(object[name], child, validator)
+		}
+	}
+}
+
+func (schema *JSONSchema) validateArray(array []any, pointer string, validator *Validator, report func(string, string)) {
+	if schema.minItems != nil && len(array) < *schema.minItems {
+		report(CodeTooShort, fmt.Sprintf("must contain at least %d items", *schema.minItems))
+	}
+
+	if schema.maxItems != nil && len(array) > *schema.maxItems {
+		report(CodeTooLong, fmt.Sprintf("must contain at most %d items", *schema.maxItems))
+	}
+
+	if schema.uniqueItems {
+		for i := 0; i < len(array); i++ {
+			for j := i + 1; j < len(array); j++ {
+				if jsonEqual(array[i], array[j]) {
+					report(CodeDuplicate, "must not contain duplicates")
+					i = len(array)
+					break
+				}
+			}
+		}
+	}
+
+	if schema.items != nil {
+		for index, item := range array {
+			schema.items.validate(item, pointer+"/"+strconv.Itoa(index), validator)
+		}
+	}
+}
+
+func (schema *JSONSchema) validateString(value string, report func(string, string)) {
+	length := utf8.RuneCountInString(value)
+
+	if schema.minLength != nil && length < *schema.minLength {
+		report(CodeTooShort, fmt.Sprintf("must be at least %d characters", *schema.minLength))
+	}
+
+	if schema.maxLength != nil && length > *schema.maxLength {
+		report(CodeTooLong, fmt.Sprintf("must be at most %d characters", *schema.maxLength))
+	}
+
+	if schema.pattern != nil && !schema.pattern.MatchString(value) {
+		report(CodeInvalidFormat, "must match the pattern "+schema.pattern.String())
+	}
+
+	if schema.format != "" && !isJSONFormat(value, schema.format) {
+		report(CodeInvalidFormat, "must be a valid "+schema.format)
+	}
+}
+
+func (schema *JSONSchema) validateNumber(number float64, report func(string, string)) {
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	if schema.minimum != nil && number < *schema.minimum {
+		report(CodeOutOfRange, "must be at least "+format(*schema.minimum))
+	}
+
+	if schema.maximum != nil && number > *schema.maximum {
+		report(CodeOutOfRange, "must be at most "+format(*schema.maximum))
+	}
+
+	if schema.exclusiveMinimum != nil && number <= *schema.exclusiveMinimum {
+		report(CodeOutOfRange, "must be greater than "+format(*schema.exclusiveMinimum))
+	}
+
+	if schema.exclusiveMaximum != nil && number >= *schema.exclusiveMaximum {
+		report(CodeOutOfRange, "must be less than "+format(*schema.exclusiveMaximum))
+	}
+
+	if schema.multipleOf != nil && *schema.multipleOf > 0 {
+		quotient := number / *schema.multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			report(CodeInvalid, "must be a multiple of "+format(*schema.multipleOf))
+		}
+	}
+}
+
+func countMatches(schemas []*JSONSchema, instance any) int {
+	matches := 0
+
+	for _, schema := range schemas {
+		probe := NewValidator()
+		schema.validate(instance, "", probe)
+
+		if probe.Ok() {
+			matches++
+		}
+	}
+
+	return matches
+}
+
+func isJSONType(instance any, name string) bool {
+	switch name {
+	case "null":
+		return instance == nil
+	case "boolean":
+		_, ok := instance.(bool)
+		return ok
+	case "object":
+		_, ok := instance.(map[string]any)
+		return ok
+	case "array":
+		_, ok := instance.([]any)
+		return ok
+	case "string":
+		_, ok := instance.(string)
+		return ok
+	case "number":
+		_, ok := instance.(json.Number)
+		return ok
+	case "integer":
+		number, ok := instance.(json.Number)
+		if !ok {
+			return false
+		}
+
+		f, err := number.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+
+	return false
+}
+
+func isJSONFormat(value string, format string) bool {
+	switch format {
+	case "email":
+		return Matches(value, EmailRX)
+	case "uri":
+		return IsURL(value)
+	case "hostname":
+		return IsHostname(value)
+	case "date-time":
+		return IsRFC3339(value)
+	case "date":
+		return IsDate(value, time.DateOnly)
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case "ipv6":
+		return net.ParseIP(value) != nil && strings.Contains(value, ":")
+	case "uuid":
+		return uuid.Parse(value) != nil && len(value) == 36
+	}
+
+	// unknown formats are annotations only
+	return true
+}
+
+func jsonEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for index := range x {
+			if !jsonEqual(x[index], y[index]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for key := range x {
+			if _, exists := y[key]; !exists || !jsonEqual(x[key], y[key]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		f, err := number.Float64()
+		return f, err == nil
+	case float64:
+		return number, true
+	}
+
+	return 0, false
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func decodeJSONNumbers(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func normalizeJSONValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeJSONNumbers(data)
+}
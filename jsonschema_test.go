@@ -0,0 +1,113 @@
+package ibnsina
+
+import (
+	"testing"
+)
+
+var testOrderSchema = []byte(`{
+	"type": "object",
+	"required": ["customer", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"customer": {"$ref": "#/$defs/customer"},
+		"currency": {"enum": ["UZS", "USD"]},
+		"note": {"type": ["string", "null"], "maxLength": 10},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["sku"],
+				"properties": {
+					"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+					"quantity": {"type": "integer", "minimum": 1, "exclusiveMaximum": 100}
+				}
+			}
+		}
+	},
+	"$defs": {
+		"customer": {
+			"type": "object",
+			"required": ["email"],
+			"properties": {
+				"email": {"type": "string", "format": "email"},
+				"id": {"oneOf": [{"type": "integer"}, {"type": "string", "format": "uuid"}]}
+			}
+		}
+	}
+}`)
+
+func TestJSONSchemaDanglingReference(t *testing.T) {
+	for _, document := range []string{
+		`{"$ref": "#/$defs/missing"}`,
+		`{"properties": {"address": {"$ref": "#/$defs/address"}}, "$defs": {"address": {"items": {"$ref": "https://example.com/item"}}}}`,
+	} {
+		if _, err := LoadJSONSchema([]byte(document)); err == nil {
+			t.Errorf("expected %s to fail to load", document)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := MustLoadJSONSchema(testOrderSchema)
+
+	validator := NewValidator()
+	valid := `{"customer": {"email": "ibn@sina.org", "id": 7}, "currency": "UZS", "note": null, "items": [{"sku": "ABC-1", "quantity": 2}]}`
+
+	if err := schema.ValidateJSON([]byte(valid), validator); err != nil {
+		t.Fatalf("ValidateJSON: %s", err)
+	}
+
+	if !validator.Ok() {
+		t.Fatalf("valid document: unexpected errors %v %v", validator.FieldErrors, validator.NonFieldErrors)
+	}
+
+	validator = NewValidator()
+	invalid := `{"customer": {"email": "nope", "id": true}, "currency": "EUR", "note": "far too long", "items": [{"sku": "abc", "quantity": 1.5}, {"quantity": 100}], "extra": 1}`
+
+	if err := schema.ValidateJSON([]byte(invalid), validator); err != nil {
+		t.Fatalf("ValidateJSON: %s", err)
+	}
+
+	expected := map[string]string{
+		"/customer/email":   CodeInvalidFormat,
+		"/customer/id":      CodeInvalid,
+		"/currency":         CodeNotAllowed,
+		"/note":             CodeTooLong,
+		"/items/0/sku":      CodeInvalidFormat,
+		"/items/0/quantity": CodeInvalidType,
+		"/items/1/sku":      CodeRequired,
+		"/items/1/quantity": CodeOutOfRange,
+		"/extra":            CodeNotAllowed,
+	}
+
+	for key, code := range expected {
+		if validator.FieldCodes[key] != code {
+			t.Errorf("%s: expected code %q but was %q (%q)", key, code, validator.FieldCodes[key], validator.FieldErrors[key])
+		}
+	}
+
+	if len(validator.FieldErrors) != len(expected) {
+		t.Errorf("expected %d errors, got %v", len(expected), validator.FieldErrors)
+	}
+
+	validator = NewValidator()
+	schema.Validate(struct {
+		Items []int `json:"items"`
+	}{}, validator)
+
+	if len(validator.NonFieldErrors) != 0 || validator.FieldCodes["/customer"] != CodeRequired || validator.FieldCodes["/items"] != CodeInvalidType {
+		t.Errorf("Validate: unexpected errors %v %v", validator.FieldErrors, validator.NonFieldErrors)
+	}
+
+	validator = NewValidator()
+	schema.ValidateJSON([]byte(`[]`), validator)
+
+	if len(validator.NonFieldErrors) != 1 {
+		t.Errorf("root errors should be non field errors, got %v", validator.NonFieldErrors)
+	}
+
+	if _, err := LoadJSONSchema([]byte(`{"pattern": "("}`)); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
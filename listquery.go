@@ -0,0 +1,133 @@
+package ibnsina
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SortField is a field to sort by, descending when Desc is set.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery is the sorting and filtering requested for a list endpoint.
+// Every field in it is in the allowlists it was parsed with, so it can be
+// handed to a database layer as is.
+type ListQuery struct {
+	Sort []SortField
+
+	// Filters maps fields to their values, in the order they were given.
+	Filters map[string][]string
+}
+
+// ListOptions are the fields a list endpoint sorts and filters by.
+type ListOptions struct {
+	Sortable   []string
+	Filterable []string
+
+	// DefaultSort is used when the query has no sort parameter, in the same
+	// syntax, e.g. "-created_at".
+	DefaultSort string
+}
+
+// ParseListQuery parses the sort and filter parameters of a query string:
+//
+//	?sort=-created_at,name&filter[status]=active&filter[status]=pending
+//
+// sorts by created_at descending then by name, and keeps the items whose
+// status is active or pending. Fields missing from the allowlists of options
+// and fields sorted by twice are added to validator, keyed "sort" or
+// "filter[field]", and left out of the result.
+func ParseListQuery(query url.Values, options ListOptions, validator *Validator) ListQuery {
+	list := ListQuery{Filters: make(map[string][]string)}
+
+	sortParam, ok := query["sort"]
+	if !ok && options.DefaultSort != "" {
+		sortParam = []string{options.DefaultSort}
+	}
+
+	for _, param := range sortParam {
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+
+			if !slices.Contains(options.Sortable, field) {
+				validator.AddFieldError("sort", CodeNotAllowed, "cannot sort by "+field)
+				continue
+			}
+
+			if slices.ContainsFunc(list.Sort, func(existing SortField) bool { return existing.Field == field }) {
+				validator.AddFieldError("sort", CodeDuplicate, "sorts by "+field+" more than once")
+				continue
+			}
+
+			list.Sort = append(list.Sort, SortField{Field: field, Desc: desc})
+		}
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	// sorted so that errors come in a stable order
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := strings.CutPrefix(key, "filter[")
+		if !ok {
+			continue
+		}
+
+		field, ok = strings.CutSuffix(field, "]")
+		if !ok || field == "" {
+			validator.AddFieldError(key, CodeInvalidFormat, "must be of the form filter[field]")
+			continue
+		}
+
+		if !slices.Contains(options.Filterable, field) {
+			validator.AddFieldError(key, CodeNotAllowed, "cannot filter by "+field)
+			continue
+		}
+
+		list.Filters[field] = append(list.Filters[field], query[key]...)
+	}
+
+	return list
+}
+
+// OrderBy renders the sort of list as an SQL ORDER BY list, such as
+// "created_at DESC, name ASC", or "" when there is none. The fields come
+// from the allowlist, so the result is safe to splice into a query.
+func (list ListQuery) OrderBy() string {
+	clauses := make([]string, len(list.Sort))
+
+	for index, field := range list.Sort {
+		direction := " ASC"
+		if field.Desc {
+			direction = " DESC"
+		}
+
+		clauses[index] = field.Field + direction
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// Filter returns the first value of the filter on field, if any.
+func (list ListQuery) Filter(field string) (string, bool) {
+	values := list.Filters[field]
+	if len(values) == 0 {
+		return "", false
+	}
+
+	return values[0], true
+}
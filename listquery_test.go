@@ -0,0 +1,60 @@
+package ibnsina
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParseListQuery(t *testing.T) {
+	options := ListOptions{
+		Sortable:    []string{"created_at", "name"},
+		Filterable:  []string{"status"},
+		DefaultSort: "-created_at",
+	}
+
+	tests := []struct {
+		query string
+
+		expectedOrderBy string
+		expectedStatus  []string
+		expectedErrors  map[string]string
+	}{
+		{"", "created_at DESC", nil, nil},
+		{"sort=-created_at,name&filter[status]=active&filter[status]=pending", "created_at DESC, name ASC", []string{"active", "pending"}, nil},
+		{"sort=+name", "name ASC", nil, nil},
+		{"sort=password", "", nil, map[string]string{"sort": CodeNotAllowed}},
+		{"sort=name,-name", "name ASC", nil, map[string]string{"sort": CodeDuplicate}},
+		{"filter[owner]=1", "created_at DESC", nil, map[string]string{"filter[owner]": CodeNotAllowed}},
+		{"filter[status=1", "created_at DESC", nil, map[string]string{"filter[status": CodeInvalidFormat}},
+	}
+
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("ParseQuery: %s", err)
+		}
+
+		validator := NewValidator()
+		list := ParseListQuery(query, options, validator)
+
+		if orderBy := list.OrderBy(); orderBy != test.expectedOrderBy {
+			t.Errorf("%s: expected order %q but was %q", test.query, test.expectedOrderBy, orderBy)
+		}
+
+		if !slices.Equal(list.Filters["status"], test.expectedStatus) {
+			t.Errorf("%s: expected status filter %q but was %q", test.query, test.expectedStatus, list.Filters["status"])
+		}
+
+		if len(validator.FieldCodes) != len(test.expectedErrors) {
+			t.Errorf("%s: expected errors %v but got %v", test.query, test.expectedErrors, validator.FieldCodes)
+			continue
+		}
+
+		for key, code := range test.expectedErrors {
+			if validator.FieldCodes[key] != code {
+				t.Errorf("%s: expected code %q for %q but was %q", test.query, code, key, validator.FieldCodes[key])
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+)
+
+// LocaleOptions configures Localization.
+type LocaleOptions struct {
+	// Supported lists the locales of the application, DefaultCatalog.Locales()
+	// if empty.
+	Supported []string
+
+	// Fallback is the locale of requests asking for none of the supported
+	// ones.
+	Fallback string
+
+	// QueryParam and Cookie name a query parameter and a cookie overriding
+	// Accept-Language, such as "lang". Empty names are not looked at.
+	QueryParam string
+	Cookie     string
+}
+
+// Localization is a middleware resolving the locale of requests, read by
+// handlers with Locale. The query parameter comes first, then the cookie,
+// then Accept-Language, each only when it names a supported locale or a
+// regional variant of one: "uz-Latn-UZ" resolves to "uz" if only that is
+// supported. ValidationFailed and the encoders localize validation messages
+// with DefaultCatalog in the resolved locale.
+func Localization(options LocaleOptions) Middleware {
+	supported := make([]string, len(options.Supported))
+	for index, locale := range options.Supported {
+		supported[index] = normalizeLocale(locale)
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			supported := supported
+			if len(supported) == 0 {
+				supported = DefaultCatalog.Locales()
+			}
+
+			locale := resolveLocale(request, options, supported)
+
+			response.Header().Add("Vary", "Accept-Language")
+			if options.Cookie != "" {
+				response.Header().Add("Vary", "Cookie")
+			}
+
+			if values := GetValues(ctx); values != nil {
+				values.locale = locale
+			} else {
+				ctx = WithValues(ctx, Values{locale: locale})
+				request = request.WithContext(ctx)
+			}
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+func resolveLocale(request *http.Request, options LocaleOptions, supported []string) string {
+	if options.QueryParam != "" {
+		if value := request.URL.Query().Get(options.QueryParam); value != "" {
+			if locale, ok := matchLocale([]string{normalizeLocale(value)}, supported); ok {
+				return locale
+			}
+		}
+	}
+
+	if options.Cookie != "" {
+		if cookie, err := request.Cookie(options.Cookie); err == nil {
+			if locale, ok := matchLocale([]string{normalizeLocale(cookie.Value)}, supported); ok {
+				return locale
+			}
+		}
+	}
+
+	if locale, ok := matchLocale(parseAcceptLanguage(request.Header.Get("Accept-Language")), supported); ok {
+		return locale
+	}
+
+	return normalizeLocale(options.Fallback)
+}
+
+// Locale returns the locale resolved by Localization for the request ctx
+// belongs to, or "" without it.
+func Locale(ctx context.Context) string {
+	if values := GetValues(ctx); values != nil {
+		return values.locale
+	}
+
+	return ""
+}
@@ -0,0 +1,66 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalization(t *testing.T) {
+	DefaultCatalog.Add("xx-test", "required", "majburiy")
+
+	router := NewRouter(Localization(LocaleOptions{
+		Supported:  []string{"en", "uz", "xx-test"},
+		Fallback:   "en",
+		QueryParam: "lang",
+		Cookie:     "lang",
+	}))
+
+	router.Handle("/locale", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(Locale(ctx)))
+	}, http.MethodGet)
+
+	router.Handle("/validate", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		validator := NewValidator()
+		validator.AddFieldMessage("name", CodeRequired, "required", nil)
+		ValidationFailed(ctx, response, validator)
+	}, http.MethodGet)
+
+	tests := []struct {
+		query          string
+		cookie         string
+		acceptLanguage string
+
+		expected string
+	}{
+		{"", "", "", "en"},
+		{"", "", "fr, uz-Latn-UZ;q=0.8", "uz"},
+		{"", "uz", "en", "uz"},
+		{"?lang=en", "uz", "uz", "en"},
+		{"?lang=fr", "", "uz", "uz"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/locale"+test.query, nil)
+		request.Header.Set("Accept-Language", test.acceptLanguage)
+		if test.cookie != "" {
+			request.AddCookie(&http.Cookie{Name: "lang", Value: test.cookie})
+		}
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if locale := response.Body.String(); locale != test.expected {
+			t.Errorf("%q %q %q: expected locale %q but was %q", test.query, test.cookie, test.acceptLanguage, test.expected, locale)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/validate?lang=xx-test", nil))
+
+	if !strings.Contains(response.Body.String(), "majburiy") {
+		t.Errorf("expected a localized validation message, got %s", response.Body)
+	}
+}
@@ -0,0 +1,108 @@
+package ibnsina
+
+import (
+	"container/list"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// matchCache is a least recently used cache of successful matches keyed by
+// method, API version and path. A nil *matchCache is a valid, disabled cache.
+type matchCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[matchKey]*list.Element
+	order   *list.List
+}
+
+type matchKey struct {
+	method  string
+	version string
+	path    string
+}
+
+type matchEntry struct {
+	key      matchKey
+	endpoint *endpoint
+	params   map[string]string
+	ints     []intParam
+}
+
+// EnableMatchCache makes the router remember the route and params matched
+// for the size most recently requested method and path pairs, so hot
+// endpoints skip the scan of the route table. Registering routes empties the
+// cache. A size of zero or less disables it.
+//
+// Every distinct path of a param route takes an entry, so the cache helps
+// most with static routes and small, hot sets of param values.
+func (router *Router) EnableMatchCache(size int) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	if size <= 0 {
+		router.cache = nil
+		return
+	}
+
+	router.cache = &matchCache{
+		size:    size,
+		entries: make(map[matchKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the cached params, which callers may modify, and
+// appends the cached int params to ints.
+func (cache *matchCache) get(method string, version string, path string, ints *[]intParam) (*endpoint, map[string]string, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[matchKey{method, version, path}]
+	if !ok {
+		return nil, nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	entry := element.Value.(*matchEntry)
+
+	*ints = append(*ints, entry.ints...)
+
+	return entry.endpoint, maps.Clone(entry.params), true
+}
+
+// add caches a match, evicting the least recently used one when full, and
+// returns a copy of params for the caller to use.
+func (cache *matchCache) add(method string, version string, path string, endpoint *endpoint, params map[string]string, ints []intParam) map[string]string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	key := matchKey{method, version, path}
+
+	if element, ok := cache.entries[key]; ok {
+		cache.order.MoveToFront(element)
+		return maps.Clone(params)
+	}
+
+	if cache.order.Len() >= cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*matchEntry).key)
+	}
+
+	cache.entries[key] = cache.order.PushFront(&matchEntry{key: key, endpoint: endpoint, params: params, ints: slices.Clone(ints)})
+
+	return maps.Clone(params)
+}
+
+func (cache *matchCache) clear() {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	clear(cache.entries)
+	cache.order.Init()
+}
@@ -0,0 +1,77 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchCache(t *testing.T) {
+	router := NewRouter()
+	router.EnableMatchCache(2)
+
+	var name string
+
+	router.Handle("/users/:name", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		name = Param(ctx, "name")
+	}, http.MethodGet)
+
+	for _, path := range []string{"/users/caf%C3%A9", "/users/caf%C3%A9", "/users/b", "/users/c", "/users/caf%C3%A9"} {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if response.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d but was %d", path, http.StatusOK, response.Code)
+		}
+	}
+
+	if name != "café" {
+		t.Errorf("expected a decoded param from the cache but was %q", name)
+	}
+
+	if length := router.cache.order.Len(); length != 2 {
+		t.Errorf("expected the cache to hold 2 entries but held %d", length)
+	}
+
+	if _, _, ok := router.cache.get(http.MethodGet, "", "/users/b", new([]intParam)); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+
+	// a route registered later must not be hidden by the cache
+	router.Handle("/users/me", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, http.MethodPost)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/users/caf%C3%A9", nil))
+
+	if response.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d but was %d", http.StatusMethodNotAllowed, response.Code)
+	}
+
+	if length := router.cache.order.Len(); length != 0 {
+		t.Errorf("expected Handle to empty the cache but it held %d entries", length)
+	}
+}
+
+func TestMatchCacheInts(t *testing.T) {
+	router := NewRouter()
+	router.EnableMatchCache(2)
+
+	var parsed []intParam
+
+	router.Handle("/orders/:id|int", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		parsed = GetValues(ctx).ints
+
+		if id, ok := ParamInt(ctx, "id"); !ok || id != 42 {
+			t.Errorf("expected the id 42 but got %d", id)
+		}
+	}, http.MethodGet)
+
+	for range 2 {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+
+		if len(parsed) != 1 || parsed[0] != (intParam{name: "id", value: 42}) {
+			t.Errorf("expected the int param parsed when matched, even from the cache, but got %v", parsed)
+		}
+	}
+}
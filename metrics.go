@@ -0,0 +1,273 @@
+package ibnsina
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics is a registry of metrics, created on first use and exposed in the
+// Prometheus text format by ServeMetrics. Names carry their labels, as in
+//
+//	metrics.Counter(`http_requests_total{status="500"}`).Inc()
+type Metrics struct {
+	mu         sync.Mutex
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	histograms map[string]*Histogram
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counters:   make(map[string]*Counter),
+		gauges:     make(map[string]*Gauge),
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+// Counter is a metric that only goes up. It is safe for concurrent use.
+type Counter struct {
+	value atomic.Uint64
+}
+
+func (counter *Counter) Inc() {
+	counter.value.Add(1)
+}
+
+func (counter *Counter) Add(n uint64) {
+	counter.value.Add(n)
+}
+
+func (counter *Counter) Value() uint64 {
+	return counter.value.Load()
+}
+
+// Gauge is a metric that goes up and down. It is safe for concurrent use.
+type Gauge struct {
+	value atomic.Int64
+}
+
+func (gauge *Gauge) Add(n int64) {
+	gauge.value.Add(n)
+}
+
+func (gauge *Gauge) Set(n int64) {
+	gauge.value.Store(n)
+}
+
+func (gauge *Gauge) Value() int64 {
+	return gauge.value.Load()
+}
+
+// Histogram counts observations in buckets given by their upper bounds. It
+// is safe for concurrent use.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (histogram *Histogram) Observe(value float64) {
+	index := sort.SearchFloat64s(histogram.buckets, value)
+
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	if index < len(histogram.counts) {
+		histogram.counts[index]++
+	}
+
+	histogram.sum += value
+	histogram.count++
+}
+
+// Count returns the number of observations.
+func (histogram *Histogram) Count() uint64 {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	return histogram.count
+}
+
+// Counter returns the counter called name, creating it if needed.
+func (metrics *Metrics) Counter(name string) *Counter {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	counter, ok := metrics.counters[name]
+	if !ok {
+		counter = &Counter{}
+		metrics.counters[name] = counter
+	}
+
+	return counter
+}
+
+// Gauge returns the gauge called name, creating it if needed.
+func (metrics *Metrics) Gauge(name string) *Gauge {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	gauge, ok := metrics.gauges[name]
+	if !ok {
+		gauge = &Gauge{}
+		metrics.gauges[name] = gauge
+	}
+
+	return gauge
+}
+
+// Histogram returns the histogram called name, creating it with buckets if
+// needed. buckets are the upper bounds of the buckets, in increasing order;
+// observations above the last one are only counted in the total.
+func (metrics *Metrics) Histogram(name string, buckets []float64) *Histogram {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	histogram, ok := metrics.histograms[name]
+	if !ok {
+		histogram = &Histogram{
+			buckets: buckets,
+			counts:  make([]uint64, len(buckets)),
+		}
+
+		metrics.histograms[name] = histogram
+	}
+
+	return histogram
+}
+
+// WriteTo writes all metrics in the Prometheus text format, grouped by
+// family, the name without labels, and sorted by name.
+func (metrics *Metrics) WriteTo(w io.Writer) (int64, error) {
+	metrics.mu.Lock()
+
+	names := make([]string, 0, len(metrics.counters)+len(metrics.gauges)+len(metrics.histograms))
+	counters := make(map[string]*Counter, len(metrics.counters))
+	gauges := make(map[string]*Gauge, len(metrics.gauges))
+	histograms := make(map[string]*Histogram, len(metrics.histograms))
+
+	for name, counter := range metrics.counters {
+		names = append(names, name)
+		counters[name] = counter
+	}
+
+	for name, gauge := range metrics.gauges {
+		names = append(names, name)
+		gauges[name] = gauge
+	}
+
+	for name, histogram := range metrics.histograms {
+		names = append(names, name)
+		histograms[name] = histogram
+	}
+
+	metrics.mu.Unlock()
+
+	// sorting the names alone would put foo_bar between foo and foo{...}
+	slices.SortFunc(names, func(a, b string) int {
+		baseA, _ := splitMetricName(a)
+		baseB, _ := splitMetricName(b)
+
+		return cmp.Or(cmp.Compare(baseA, baseB), cmp.Compare(a, b))
+	})
+
+	var builder strings.Builder
+	typed := ""
+
+	for _, name := range names {
+		base, labels := splitMetricName(name)
+
+		if counter, ok := counters[name]; ok {
+			if base != typed {
+				builder.WriteString("# TYPE " + base + " counter\n")
+				typed = base
+			}
+
+			builder.WriteString(name + " " + strconv.FormatUint(counter.Value(), 10) + "\n")
+			continue
+		}
+
+		if gauge, ok := gauges[name]; ok {
+			if base != typed {
+				builder.WriteString("# TYPE " + base + " gauge\n")
+				typed = base
+			}
+
+			builder.WriteString(name + " " + strconv.FormatInt(gauge.Value(), 10) + "\n")
+			continue
+		}
+
+		if base != typed {
+			builder.WriteString("# TYPE " + base + " histogram\n")
+			typed = base
+		}
+
+		histograms[name].write(&builder, base, labels)
+	}
+
+	n, err := io.WriteString(w, builder.String())
+	return int64(n), err
+}
+
+func (histogram *Histogram) write(builder *strings.Builder, base string, labels string) {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	prefix := "{"
+	if labels != "" {
+		prefix = "{" + labels + ","
+	}
+
+	cumulative := uint64(0)
+
+	for index, bound := range histogram.buckets {
+		cumulative += histogram.counts[index]
+		builder.WriteString(base + "_bucket" + prefix + `le="` + formatFloat(bound) + `"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+
+	builder.WriteString(base + "_bucket" + prefix + `le="+Inf"} ` + strconv.FormatUint(histogram.count, 10) + "\n")
+
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + labels + "}"
+	}
+
+	builder.WriteString(base + "_sum" + suffix + " " + formatFloat(histogram.sum) + "\n")
+	builder.WriteString(base + "_count" + suffix + " " + strconv.FormatUint(histogram.count, 10) + "\n")
+}
+
+// ServeMetrics is a Handler exposing the metrics to Prometheus:
+//
+//	router.Handle("/metrics", metrics.ServeMetrics, "GET")
+func (metrics *Metrics) ServeMetrics(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	response.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.WriteTo(response)
+}
+
+// splitMetricName splits name into its base and its labels, without braces.
+func splitMetricName(name string) (string, string) {
+	if index := strings.IndexByte(name, '{'); index > -1 {
+		return name[:index], strings.TrimSuffix(name[index+1:], "}")
+	}
+
+	return name, ""
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labelValue quotes value for use as a label value in a metric name.
+func labelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,55 @@
+package ibnsina
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Counter(`http_requests_total{status="200"}`).Add(3)
+	metrics.Counter(`http_requests_total{status="500"}`).Inc()
+	metrics.Counter("http_client_disconnects_total").Inc()
+	metrics.Gauge("http_connections_active").Add(2)
+
+	var builder strings.Builder
+	if _, err := metrics.WriteTo(&builder); err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+
+	expected := "" +
+		"# TYPE http_client_disconnects_total counter\n" +
+		"http_client_disconnects_total 1\n" +
+		"# TYPE http_connections_active gauge\n" +
+		"http_connections_active 2\n" +
+		"# TYPE http_requests_total counter\n" +
+		`http_requests_total{status="200"} 3` + "\n" +
+		`http_requests_total{status="500"} 1` + "\n"
+
+	if builder.String() != expected {
+		t.Errorf("expected\n%s\nbut was\n%s", expected, builder.String())
+	}
+}
+
+func TestMetricsFamilies(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Counter(`jobs{queue="mail"}`).Inc()
+	metrics.Counter("jobs_failed").Inc()
+	metrics.Counter("jobs").Add(2)
+
+	var builder strings.Builder
+	if _, err := metrics.WriteTo(&builder); err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+
+	expected := "" +
+		"# TYPE jobs counter\n" +
+		"jobs 2\n" +
+		`jobs{queue="mail"} 1` + "\n" +
+		"# TYPE jobs_failed counter\n" +
+		"jobs_failed 1\n"
+
+	if builder.String() != expected {
+		t.Errorf("expected\n%s\nbut was\n%s", expected, builder.String())
+	}
+}
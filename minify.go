@@ -0,0 +1,106 @@
+package ibnsina
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// MinifyHTML removes comments from html and collapses runs of whitespace to
+// a single space, leaving the content of pre, textarea, style and script
+// alone, since whitespace may matter there and the content of strings and
+// template literals cannot be told apart without parsing. Conditional
+// comments are kept.
+func MinifyHTML(html []byte) []byte {
+	var output bytes.Buffer
+	output.Grow(len(html))
+
+	for index := 0; index < len(html); {
+		switch {
+		case bytes.HasPrefix(html[index:], []byte("<!--")) && !bytes.HasPrefix(html[index:], []byte("<!--[if")):
+			end := bytes.Index(html[index:], []byte("-->"))
+			if end == -1 {
+				return output.Bytes()
+			}
+
+			index += end + len("-->")
+		case html[index] == '<':
+			name := rawElement(html[index:])
+			if name == "" {
+				output.WriteByte('<')
+				index++
+				continue
+			}
+
+			open := bytes.IndexByte(html[index:], '>')
+			if open == -1 {
+				output.Write(html[index:])
+				return output.Bytes()
+			}
+
+			output.Write(html[index : index+open+1])
+			index += open + 1
+
+			end := indexFold(html[index:], "</"+name)
+			if end == -1 {
+				end = len(html) - index
+			}
+
+			output.Write(html[index : index+end])
+			index += end
+		case isSpace(html[index]):
+			for index < len(html) && isSpace(html[index]) {
+				index++
+			}
+
+			if output.Len() > 0 && index < len(html) {
+				output.WriteByte(' ')
+			}
+		default:
+			output.WriteByte(html[index])
+			index++
+		}
+	}
+
+	return output.Bytes()
+}
+
+// rawElement returns the name of the element opened at the start of html if
+// its content must not be collapsed.
+func rawElement(html []byte) string {
+	for _, name := range []string{"pre", "textarea", "script", "style"} {
+		if len(html) > len(name)+1 && strings.EqualFold(string(html[1:len(name)+1]), name) {
+			if next := html[len(name)+1]; next == '>' || isSpace(next) {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+func indexFold(data []byte, substring string) int {
+	return bytes.Index(bytes.ToLower(data), []byte(substring))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+var (
+	cssComments    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssWhitespace  = regexp.MustCompile(`\s+`)
+	cssPunctuation = regexp.MustCompile(`\s*([{};,>])\s*`)
+)
+
+// MinifyCSS removes comments and the whitespace that does not separate
+// tokens from css. Strings containing comment markers or braces are not
+// supported.
+func MinifyCSS(css []byte) []byte {
+	css = cssComments.ReplaceAll(css, nil)
+	css = cssWhitespace.ReplaceAll(css, []byte(" "))
+	css = cssPunctuation.ReplaceAll(css, []byte("$1"))
+	css = bytes.ReplaceAll(css, []byte(";}"), []byte("}"))
+
+	return bytes.TrimSpace(css)
+}
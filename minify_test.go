@@ -0,0 +1,34 @@
+package ibnsina
+
+import "testing"
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"  <p>\n    hello   <b>world</b>\n  </p>\n", "<p> hello <b>world</b> </p>"},
+		{"<p>a<!-- note -->b</p>", "<p>ab</p>"},
+		{"<!--[if IE]><p>old</p><![endif]-->", "<!--[if IE]><p>old</p><![endif]-->"},
+		{"<pre>  keep\n   this </pre>  <p> x </p>", "<pre>  keep\n   this </pre> <p> x </p>"},
+		{"<textarea>\n a  b\n</textarea>", "<textarea>\n a  b\n</textarea>"},
+		{"<style>\n  a::before { content: \"a ; b\" }\n</style>", "<style>\n  a::before { content: \"a ; b\" }\n</style>"},
+		{"<script>\n  let a = `\n  // kept\n  `\n</script>", "<script>\n  let a = `\n  // kept\n  `\n</script>"},
+		{"<PRE class=\"code\">  a\n  b</PRE>", "<PRE class=\"code\">  a\n  b</PRE>"},
+		{"<p>a < b</p>", "<p>a < b</p>"},
+	}
+
+	for _, test := range tests {
+		if output := string(MinifyHTML([]byte(test.input))); output != test.expected {
+			t.Errorf("%q: expected %q but was %q", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestMinifyCSS(t *testing.T) {
+	input := "/* theme */\nbody > main ,\n p {\n  margin: 0;\n  padding: 0 1em;\n}\n"
+
+	if output := string(MinifyCSS([]byte(input))); output != "body>main,p{margin: 0;padding: 0 1em}" {
+		t.Errorf("unexpected CSS %q", output)
+	}
+}
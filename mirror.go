@@ -0,0 +1,123 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MirrorOptions configures Mirror.
+type MirrorOptions struct {
+	// Target is the base URL requests are mirrored to, e.g.
+	// "http://users-v2.internal:8080".
+	Target string
+
+	// Percent of the requests to mirror, from 0 to 100.
+	Percent float64
+
+	// Client sends the mirrored requests, a client with a 5 second timeout
+	// if nil.
+	Client *http.Client
+
+	// MaxBodyBytes bounds the bodies of mirrored requests, MaxBodyBytes if
+	// zero. Requests with larger bodies are not mirrored.
+	MaxBodyBytes int64
+
+	// MaxInFlight bounds the mirrored requests in progress, 100 if zero.
+	// Requests beyond it are not mirrored rather than queued, so a slow
+	// target never affects the primary traffic.
+	MaxInFlight int
+}
+
+// hopHeaders are connection specific and not forwarded to the target.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Mirror is a middleware sending a copy of a share of the requests, method,
+// path, query, headers and body, to a secondary target, in the background.
+// The responses of the target are discarded, and its failures ignored: the
+// client is always served by the next handler. Mirrored requests carry the
+// trace id of the original one.
+func Mirror(options MirrorOptions) Middleware {
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	if options.MaxBodyBytes == 0 {
+		options.MaxBodyBytes = MaxBodyBytes
+	}
+
+	if options.MaxInFlight == 0 {
+		options.MaxInFlight = 100
+	}
+
+	target := strings.TrimSuffix(options.Target, "/")
+	inFlight := make(chan struct{}, options.MaxInFlight)
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if rand.Float64()*100 >= options.Percent {
+				next(ctx, response, request)
+				return
+			}
+
+			var body []byte
+
+			if request.Body != nil && request.Body != http.NoBody {
+				buffered, err := io.ReadAll(io.LimitReader(request.Body, options.MaxBodyBytes+1))
+
+				// the handler reads the body as if it was never touched
+				request.Body = readCloser{io.MultiReader(bytes.NewReader(buffered), request.Body), request.Body}
+
+				if err != nil || int64(len(buffered)) > options.MaxBodyBytes {
+					next(ctx, response, request)
+					return
+				}
+
+				body = buffered
+			}
+
+			mirrored, err := http.NewRequestWithContext(context.Background(), request.Method, target+request.URL.RequestURI(), bytes.NewReader(body))
+			if err != nil {
+				next(ctx, response, request)
+				return
+			}
+
+			mirrored.Header = request.Header.Clone()
+			for _, name := range hopHeaders {
+				mirrored.Header.Del(name)
+			}
+
+			if values := GetValues(ctx); values != nil {
+				mirrored.Header.Set(TraceIDHeader, values.TraceID)
+			}
+
+			select {
+			case inFlight <- struct{}{}:
+				go func() {
+					defer func() { <-inFlight }()
+
+					mirroredResponse, err := options.Client.Do(mirrored)
+					if err != nil {
+						return
+					}
+
+					io.Copy(io.Discard, mirroredResponse.Body)
+					mirroredResponse.Body.Close()
+				}()
+			default:
+			}
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+// readCloser reads from Reader and closes Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
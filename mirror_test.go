@@ -0,0 +1,62 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	type mirrored struct {
+		method, uri, body, traceID, header string
+	}
+
+	received := make(chan mirrored, 1)
+
+	target := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		received <- mirrored{request.Method, request.URL.RequestURI(), string(body), request.Header.Get(TraceIDHeader), request.Header.Get("X-Tenant")}
+		response.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer target.Close()
+
+	router := NewRouter(Mirror(MirrorOptions{Target: target.URL, Percent: 100}))
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		response.Write(body)
+	}, http.MethodPost)
+
+	request := httptest.NewRequest(http.MethodPost, "/orders?dry=1", strings.NewReader(`{"id":1}`))
+	request.Header.Set("X-Tenant", "acme")
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if body := response.Body.String(); body != `{"id":1}` {
+		t.Errorf("expected the handler to read the whole body, got %q", body)
+	}
+
+	select {
+	case got := <-received:
+		expected := mirrored{http.MethodPost, "/orders?dry=1", `{"id":1}`, response.Header().Get(TraceIDHeader), "acme"}
+		if got != expected {
+			t.Errorf("expected mirrored request %+v but got %+v", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request to be mirrored")
+	}
+
+	router = NewRouter(Mirror(MirrorOptions{Target: target.URL, Percent: 0}))
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, http.MethodPost)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	select {
+	case <-received:
+		t.Error("expected no request to be mirrored at 0 percent")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,103 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Module is a feature packaged independently of the application, such as
+// the routes and background work of a plugin. Modules are added with
+// Router.Register and started and stopped with the router.
+type Module interface {
+	// Routes registers the routes of the module.
+	Routes(router *Router)
+
+	// Middlewares returns middlewares to add to the router, applying to
+	// every route. It may return nil.
+	Middlewares() []Middleware
+
+	// OnStart is called before the router starts serving and OnStop once it
+	// stopped.
+	OnStart(ctx context.Context) error
+	OnStop(ctx context.Context) error
+}
+
+// Register adds the middlewares and routes of modules, in order, and keeps
+// them to be started and stopped by Start and Stop, which Run calls.
+func (router *Router) Register(modules ...Module) {
+	for _, module := range modules {
+		if middlewares := module.Middlewares(); len(middlewares) > 0 {
+			router.Use(middlewares...)
+		}
+
+		module.Routes(router)
+
+		router.mu.Lock()
+		router.modules = append(router.modules, module)
+		router.mu.Unlock()
+	}
+}
+
+// Start calls OnStart on the registered modules in registration order. When
+// one fails, the modules started before it are stopped, in reverse order,
+// and the errors are returned.
+func (router *Router) Start(ctx context.Context) error {
+	router.mu.RLock()
+	modules := router.modules
+	router.mu.RUnlock()
+
+	for index, module := range modules {
+		if err := module.OnStart(ctx); err != nil {
+			return errors.Join(fmt.Errorf("ibnsina: starting %T: %w", module, err), router.stop(ctx, modules[:index]))
+		}
+	}
+
+	return nil
+}
+
+// Stop calls OnStop on the registered modules in reverse registration order,
+// so that a module is stopped before those it was registered after and may
+// depend on. Each call gets at most StopTimeout, when set, and every module
+// is stopped even when some fail; the errors are joined.
+func (router *Router) Stop(ctx context.Context) error {
+	router.mu.RLock()
+	modules := router.modules
+	router.mu.RUnlock()
+
+	return router.stop(ctx, modules)
+}
+
+func (router *Router) stop(ctx context.Context, modules []Module) error {
+	var errs []error
+
+	for index := len(modules) - 1; index > -1; index-- {
+		if err := router.stopModule(ctx, modules[index]); err != nil {
+			errs = append(errs, fmt.Errorf("ibnsina: stopping %T: %w", modules[index], err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (router *Router) stopModule(ctx context.Context, module Module) error {
+	if router.StopTimeout <= 0 {
+		return module.OnStop(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, router.StopTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- module.OnStop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
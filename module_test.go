@@ -0,0 +1,143 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (log *eventLog) add(event string) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	log.events = append(log.events, event)
+}
+
+func (log *eventLog) list() []string {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	return slices.Clone(log.events)
+}
+
+type testModule struct {
+	name     string
+	startErr error
+	stopErr  error
+	stopWait time.Duration
+	events   *eventLog
+}
+
+func (module *testModule) Routes(router *Router) {
+	router.Handle("/"+module.name, func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(module.name))
+	}, http.MethodGet)
+}
+
+func (module *testModule) Middlewares() []Middleware {
+	return []Middleware{
+		func(next Handler) Handler {
+			return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+				module.events.add("serve " + module.name)
+				next(ctx, response, request)
+			}
+		},
+	}
+}
+
+func (module *testModule) OnStart(ctx context.Context) error {
+	module.events.add("start " + module.name)
+	return module.startErr
+}
+
+func (module *testModule) OnStop(ctx context.Context) error {
+	module.events.add("stop " + module.name)
+
+	if module.stopWait > 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return module.stopErr
+}
+
+func TestRegister(t *testing.T) {
+	events := &eventLog{}
+
+	router := NewRouter()
+	router.Register(&testModule{name: "users", events: events}, &testModule{name: "billing", events: events})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/billing", nil))
+
+	if body := response.Body.String(); body != "billing" {
+		t.Errorf("expected body %q but was %q", "billing", body)
+	}
+
+	if err := router.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+
+	if err := router.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %s", err)
+	}
+
+	got := events.list()
+
+	expected := []string{"serve users", "serve billing", "start users", "start billing", "stop billing", "stop users"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected events %q but got %q", expected, got)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	events := &eventLog{}
+
+	failure := errors.New("no database")
+
+	router := NewRouter()
+	router.Register(&testModule{name: "users", events: events}, &testModule{name: "billing", startErr: failure, events: events})
+
+	if err := router.Start(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("expected the start error to be returned, got %v", err)
+	}
+
+	if got := events.list(); got[len(got)-1] != "stop users" {
+		t.Errorf("expected the started module to be stopped, got events %q", got)
+	}
+}
+
+func TestStopOrderAndErrors(t *testing.T) {
+	events := &eventLog{}
+
+	closing := errors.New("flush failed")
+
+	router := NewRouter()
+	router.StopTimeout = 10 * time.Millisecond
+	router.Register(
+		&testModule{name: "database", stopErr: closing, events: events},
+		&testModule{name: "queue", stopWait: time.Second, events: events},
+		&testModule{name: "users", events: events},
+	)
+
+	err := router.Stop(context.Background())
+
+	if !errors.Is(err, closing) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected both stop errors to be reported, got %v", err)
+	}
+
+	expected := []string{"stop users", "stop queue", "stop database"}
+	if got := events.list(); !slices.Equal(got, expected) {
+		t.Errorf("expected events %q but got %q", expected, got)
+	}
+}
@@ -0,0 +1,746 @@
+package ibnsina
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig configures an OIDC module.
+type OIDCConfig struct {
+	// Issuer is the URL of the OpenID provider, whose configuration is
+	// discovered at Issuer + "/.well-known/openid-configuration".
+	Issuer string
+
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the absolute URL of the callback route, as registered
+	// with the provider.
+	RedirectURL string
+
+	// Scopes are requested in addition to "openid", "profile email" if nil.
+	Scopes []string
+
+	// SessionKey encrypts and authenticates the session cookie. It must be
+	// kept secret and should be at least 32 random bytes.
+	SessionKey []byte
+
+	// Cookie names the session cookie, "auth" if empty.
+	Cookie string
+
+	// LoginPath, CallbackPath and LogoutPath are the routes of the module,
+	// "/auth/login", the path of RedirectURL and "/auth/logout" if empty.
+	LoginPath    string
+	CallbackPath string
+	LogoutPath   string
+
+	// Permissions maps the claims of the ID token to the permissions of the
+	// principal, for Authorize. Principals have no permissions if nil.
+	Permissions func(claims map[string]any) []string
+
+	// Client makes the requests to the provider, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// OIDC is a Module signing users in with an OpenID Connect provider, with
+// the authorization code flow protected by state, nonce and PKCE. Its routes
+// are
+//
+//	GET /auth/login?return_to=/orders  redirects to the provider
+//	GET /auth/callback                 starts the session and redirects back
+//	GET /auth/logout                   ends the session
+//
+// and its middleware sets the Principal of requests with a session,
+// refreshing expired access tokens when the provider issued a refresh token;
+// the concurrent requests of a session share a single refresh, so that
+// providers rotating refresh tokens do not sign them out. Sessions live in an
+// encrypted cookie, so no server-side storage is needed, but sessions over
+// the 4 KB browsers keep fail rather than being dropped: ask the provider for
+// fewer claims if they do.
+type OIDC struct {
+	config OIDCConfig
+	sealer *cookieSealer
+
+	mu        sync.RWMutex
+	discovery oidcDiscovery
+	keys      map[string]crypto.PublicKey
+
+	refreshMu sync.Mutex
+	refreshes map[string]*oidcRefresh
+}
+
+// oidcRefresh is the refresh of the sessions holding a refresh token, shared
+// by their concurrent requests and by those following for
+// oidcRefreshReuse, which may still send the previous cookie.
+type oidcRefresh struct {
+	done    chan struct{}
+	session oidcSession
+	ok      bool
+	expires time.Time
+}
+
+const oidcRefreshReuse = 30 * time.Second
+
+// maxCookieSize is the size of the largest cookie, name and value, that
+// browsers are required to keep.
+const maxCookieSize = 4096
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcFlow is kept in a short-lived cookie between login and callback.
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+type oidcSession struct {
+	Subject      string            `json:"sub"`
+	Claims       map[string]string `json:"claims,omitempty"`
+	Permissions  []string          `json:"permissions,omitempty"`
+	AccessToken  string            `json:"access_token"`
+	RefreshToken string            `json:"refresh_token,omitempty"`
+	Expiry       time.Time         `json:"expiry"`
+}
+
+type oidcTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// NewOIDC returns the module for config. The provider is only contacted
+// once the module starts.
+func NewOIDC(config OIDCConfig) (*OIDC, error) {
+	if len(config.SessionKey) == 0 {
+		return nil, errors.New("oidc: a session key is required")
+	}
+
+	if config.Scopes == nil {
+		config.Scopes = []string{"profile", "email"}
+	}
+
+	if config.Cookie == "" {
+		config.Cookie = "auth"
+	}
+
+	if config.LoginPath == "" {
+		config.LoginPath = "/auth/login"
+	}
+
+	if config.LogoutPath == "" {
+		config.LogoutPath = "/auth/logout"
+	}
+
+	if config.CallbackPath == "" {
+		redirect, err := url.Parse(config.RedirectURL)
+		if err != nil || redirect.Path == "" {
+			return nil, fmt.Errorf("oidc: invalid redirect URL %q", config.RedirectURL)
+		}
+
+		config.CallbackPath = redirect.Path
+	}
+
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	sealer, err := newCookieSealer(config.SessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDC{config: config, sealer: sealer}, nil
+}
+
+func (oidc *OIDC) Routes(router *Router) {
+	router.Handle(oidc.config.LoginPath, oidc.login, "GET")
+	router.Handle(oidc.config.CallbackPath, oidc.callback, "GET")
+	router.Handle(oidc.config.LogoutPath, oidc.logout, "GET", "POST")
+}
+
+func (oidc *OIDC) Middlewares() []Middleware {
+	return []Middleware{oidc.authenticate}
+}
+
+// OnStart discovers the endpoints and keys of the provider.
+func (oidc *OIDC) OnStart(ctx context.Context) error {
+	var discovery oidcDiscovery
+	if err := oidc.getJSON(ctx, strings.TrimSuffix(oidc.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return err
+	}
+
+	if discovery.Issuer != oidc.config.Issuer {
+		return fmt.Errorf("oidc: provider issuer %q does not match %q", discovery.Issuer, oidc.config.Issuer)
+	}
+
+	oidc.mu.Lock()
+	oidc.discovery = discovery
+	oidc.mu.Unlock()
+
+	return oidc.fetchKeys(ctx)
+}
+
+func (oidc *OIDC) OnStop(ctx context.Context) error {
+	return nil
+}
+
+// AccessToken returns the access token of the session of the request ctx
+// belongs to, for calls to APIs on behalf of the user, or "" without one.
+func AccessToken(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenKey).(string)
+	return token
+}
+
+func (oidc *OIDC) login(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	oidc.mu.RLock()
+	endpoint := oidc.discovery.AuthorizationEndpoint
+	oidc.mu.RUnlock()
+
+	if endpoint == "" {
+		writeError(ctx, response, &StatusError{Status: http.StatusServiceUnavailable, Message: "sign in is not available"})
+		return
+	}
+
+	flow := oidcFlow{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: randomToken() + randomToken(),
+		ReturnTo: safeReturnTo(request.URL.Query().Get("return_to")),
+	}
+
+	if err := oidc.setCookie(response, oidc.config.Cookie+"_flow", oidc.config.CallbackPath, flow, 10*time.Minute); err != nil {
+		writeError(ctx, response, err)
+		return
+	}
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oidc.config.ClientID},
+		"redirect_uri":          {oidc.config.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, oidc.config.Scopes...), " ")},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+
+	http.Redirect(response, request, endpoint+separator+query.Encode(), http.StatusFound)
+}
+
+func (oidc *OIDC) callback(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	var flow oidcFlow
+	if !oidc.readCookie(request, oidc.config.Cookie+"_flow", &flow) {
+		writeError(ctx, response, &StatusError{Status: http.StatusBadRequest, Message: "the sign in expired, please try again"})
+		return
+	}
+
+	oidc.clearCookie(response, oidc.config.Cookie+"_flow", oidc.config.CallbackPath)
+
+	query := request.URL.Query()
+
+	if query.Get("state") != flow.State {
+		writeError(ctx, response, &StatusError{Status: http.StatusBadRequest, Message: "the sign in state does not match"})
+		return
+	}
+
+	if failure := query.Get("error"); failure != "" {
+		writeError(ctx, response, &StatusError{Status: http.StatusUnauthorized, Message: "the sign in failed: " + failure})
+		return
+	}
+
+	tokens, err := oidc.exchange(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {query.Get("code")},
+		"redirect_uri":  {oidc.config.RedirectURL},
+		"code_verifier": {flow.Verifier},
+	})
+	if err != nil {
+		writeError(ctx, response, &StatusError{Status: http.StatusBadGateway, Message: "the sign in could not be completed", Err: err})
+		return
+	}
+
+	now := requestTime(ctx)
+
+	claims, err := oidc.verify(ctx, tokens.IDToken, now)
+	if err != nil || claims["nonce"] != flow.Nonce {
+		writeError(ctx, response, &StatusError{Status: http.StatusUnauthorized, Message: "the identity token is invalid", Err: err})
+		return
+	}
+
+	session := oidcSession{
+		Subject:      fmt.Sprint(claims["sub"]),
+		Claims:       make(map[string]string),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		Expiry:       tokenExpiry(now, tokens.ExpiresIn),
+	}
+
+	for name, value := range claims {
+		if text, ok := value.(string); ok && !slices.Contains(registeredClaims, name) {
+			session.Claims[name] = text
+		}
+	}
+
+	if oidc.config.Permissions != nil {
+		session.Permissions = oidc.config.Permissions(claims)
+	}
+
+	if err := oidc.setCookie(response, oidc.config.Cookie, "/", session, 0); err != nil {
+		writeError(ctx, response, err)
+		return
+	}
+
+	http.Redirect(response, request, flow.ReturnTo, http.StatusFound)
+}
+
+// registeredClaims are the claims describing the token rather than the
+// user, left out of the principal.
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "iat", "nbf", "nonce", "azp", "at_hash", "auth_time"}
+
+func (oidc *OIDC) logout(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	oidc.clearCookie(response, oidc.config.Cookie, "/")
+	http.Redirect(response, request, safeReturnTo(request.URL.Query().Get("return_to")), http.StatusFound)
+}
+
+func (oidc *OIDC) authenticate(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		var session oidcSession
+		if !oidc.readCookie(request, oidc.config.Cookie, &session) {
+			next(ctx, response, request)
+			return
+		}
+
+		if now := requestTime(ctx); !session.Expiry.IsZero() && now.After(session.Expiry) {
+			if !oidc.refresh(ctx, &session, now) {
+				oidc.clearCookie(response, oidc.config.Cookie, "/")
+				next(ctx, response, request)
+				return
+			}
+
+			if err := oidc.setCookie(response, oidc.config.Cookie, "/", session, 0); err != nil {
+				writeError(ctx, response, err)
+				return
+			}
+		}
+
+		SetPrincipal(ctx, &Principal{ID: session.Subject, Permissions: session.Permissions, Claims: session.Claims})
+
+		ctx = context.WithValue(ctx, accessTokenKey, session.AccessToken)
+		next(ctx, response, request.WithContext(ctx))
+	}
+}
+
+// refresh renews the access token of session, sharing the exchange with the
+// other requests of sessions holding the same refresh token.
+func (oidc *OIDC) refresh(ctx context.Context, session *oidcSession, now time.Time) bool {
+	if session.RefreshToken == "" {
+		return false
+	}
+
+	token := session.RefreshToken
+
+	oidc.refreshMu.Lock()
+
+	for key, call := range oidc.refreshes {
+		if !call.expires.IsZero() && now.After(call.expires) {
+			delete(oidc.refreshes, key)
+		}
+	}
+
+	call, shared := oidc.refreshes[token]
+	if !shared {
+		if oidc.refreshes == nil {
+			oidc.refreshes = make(map[string]*oidcRefresh)
+		}
+
+		call = &oidcRefresh{done: make(chan struct{}), session: *session}
+		oidc.refreshes[token] = call
+	}
+
+	oidc.refreshMu.Unlock()
+
+	if !shared {
+		// the refresh goes on for the others when the client goes away
+		call.ok = oidc.exchangeRefresh(context.WithoutCancel(ctx), &call.session, now)
+
+		oidc.refreshMu.Lock()
+		if call.ok {
+			call.expires = now.Add(oidcRefreshReuse)
+		} else {
+			delete(oidc.refreshes, token)
+		}
+		oidc.refreshMu.Unlock()
+
+		close(call.done)
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return false
+	}
+
+	if call.ok {
+		session.AccessToken = call.session.AccessToken
+		session.RefreshToken = call.session.RefreshToken
+		session.Expiry = call.session.Expiry
+	}
+
+	return call.ok
+}
+
+func (oidc *OIDC) exchangeRefresh(ctx context.Context, session *oidcSession, now time.Time) bool {
+	tokens, err := oidc.exchange(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {session.RefreshToken},
+	})
+	if err != nil {
+		if values := GetValues(ctx); values != nil && values.Logger != nil {
+			values.Logger.Printf("%s: oidc: refreshing the session of %s: %v", values.TraceID, session.Subject, err)
+		}
+
+		return false
+	}
+
+	session.AccessToken = tokens.AccessToken
+	session.Expiry = tokenExpiry(now, tokens.ExpiresIn)
+
+	if tokens.RefreshToken != "" {
+		session.RefreshToken = tokens.RefreshToken
+	}
+
+	return true
+}
+
+// tokenExpiry returns when tokens issued at now expiring in expiresIn seconds
+// expire, or the zero time when the provider did not say, expires_in being
+// optional.
+func tokenExpiry(now time.Time, expiresIn int64) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(time.Duration(expiresIn) * time.Second)
+}
+
+// exchange calls the token endpoint with form.
+func (oidc *OIDC) exchange(ctx context.Context, form url.Values) (*oidcTokens, error) {
+	oidc.mu.RLock()
+	endpoint := oidc.discovery.TokenEndpoint
+	oidc.mu.RUnlock()
+
+	if oidc.config.ClientSecret == "" {
+		form.Set("client_id", oidc.config.ClientID)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	if oidc.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(oidc.config.ClientID), url.QueryEscape(oidc.config.ClientSecret))
+	}
+
+	response, err := oidc.config.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var tokens oidcTokens
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("oidc: decoding the token response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("oidc: token endpoint answered %d %s %s", response.StatusCode, tokens.Error, tokens.Description)
+	}
+
+	return &tokens, nil
+}
+
+// verify checks the signature, issuer, audience and expiry of the ID token
+// raw and returns its claims.
+func (oidc *OIDC) verify(ctx context.Context, raw string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oidc: malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("oidc: malformed ID token signature")
+	}
+
+	key, err := oidc.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("oidc: invalid ID token signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(signature) != 64 ||
+			!ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+			return nil, errors.New("oidc: invalid ID token signature")
+		}
+	default:
+		return nil, fmt.Errorf("oidc: unsupported key type %T", key)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	oidc.mu.RLock()
+	issuer := oidc.discovery.Issuer
+	oidc.mu.RUnlock()
+
+	if claims["iss"] != issuer {
+		return nil, fmt.Errorf("oidc: unexpected issuer %v", claims["iss"])
+	}
+
+	switch audience := claims["aud"].(type) {
+	case string:
+		if audience != oidc.config.ClientID {
+			return nil, fmt.Errorf("oidc: unexpected audience %s", audience)
+		}
+	case []any:
+		if !slices.Contains(audience, any(oidc.config.ClientID)) {
+			return nil, fmt.Errorf("oidc: unexpected audience %v", audience)
+		}
+	default:
+		return nil, errors.New("oidc: missing audience")
+	}
+
+	expiry, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(expiry), 0).Add(time.Minute)) {
+		return nil, errors.New("oidc: expired ID token")
+	}
+
+	return claims, nil
+}
+
+// key returns the key kid of the provider, fetching the keys again when it
+// is unknown, since providers rotate them.
+func (oidc *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	oidc.mu.RLock()
+	key, ok := oidc.keys[kid]
+	oidc.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if err := oidc.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	oidc.mu.RLock()
+	defer oidc.mu.RUnlock()
+
+	if key, ok := oidc.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("oidc: unknown key %q", kid)
+}
+
+func (oidc *OIDC) fetchKeys(ctx context.Context) error {
+	oidc.mu.RLock()
+	uri := oidc.discovery.JWKSURI
+	oidc.mu.RUnlock()
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+
+	if err := oidc.getJSON(ctx, uri, &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+
+	for _, jwk := range set.Keys {
+		switch {
+		case jwk.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case jwk.Kty == "EC" && jwk.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	oidc.mu.Lock()
+	oidc.keys = keys
+	oidc.mu.Unlock()
+
+	return nil
+}
+
+func (oidc *OIDC) getJSON(ctx context.Context, uri string, v any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := oidc.config.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s answered %d", uri, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(v)
+}
+
+// setCookie seals v into the cookie name, for the browser session when
+// maxAge is zero. It fails when the cookie is too large for browsers to keep.
+func (oidc *OIDC) setCookie(response http.ResponseWriter, name string, path string, v any, maxAge time.Duration) error {
+	value, err := oidc.sealer.seal(name, v)
+	if err != nil {
+		return err
+	}
+
+	if size := len(name) + len(value); size > maxCookieSize {
+		return fmt.Errorf("oidc: the cookie %s is %d bytes, over the %d browsers keep", name, size, maxCookieSize)
+	}
+
+	http.SetCookie(response, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(oidc.config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+// readCookie opens the cookie name into v, reporting whether it was present
+// and authentic.
+func (oidc *OIDC) readCookie(request *http.Request, name string, v any) bool {
+	cookie, err := request.Cookie(name)
+	if err != nil {
+		return false
+	}
+
+	return oidc.sealer.open(name, cookie.Value, v)
+}
+
+func (oidc *OIDC) clearCookie(response http.ResponseWriter, name string, path string) {
+	http.SetCookie(response, &http.Cookie{Name: name, Path: path, MaxAge: -1, HttpOnly: true})
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("oidc: malformed ID token")
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func randomToken() string {
+	data := make([]byte, 32)
+	rand.Read(data)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// safeReturnTo keeps redirects after sign in on the site: only local paths
+// are followed.
+func safeReturnTo(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+
+	return path
+}
+
+// requestTime returns the time of the request ctx belongs to, as given by
+// the Clock of the router.
+func requestTime(ctx context.Context) time.Time {
+	if values := GetValues(ctx); values != nil && !values.Now.IsZero() {
+		return values.Now
+	}
+
+	return time.Now()
+}
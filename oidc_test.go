@@ -0,0 +1,373 @@
+package ibnsina
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	return clock.now
+}
+
+// testProvider is an OpenID provider issuing tokens for a single user. It
+// rotates refresh tokens, only accepting the last one issued.
+type testProvider struct {
+	t         *testing.T
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	nonce     string
+	challenge string
+	claims    map[string]any
+
+	// expiresIn is the lifetime of access tokens, left out when zero.
+	expiresIn int
+
+	mu           sync.Mutex
+	refreshes    int
+	refreshToken string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err)
+	}
+
+	provider := &testProvider{t: t, key: key, expiresIn: 3600}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(response http.ResponseWriter, request *http.Request) {
+		json.NewEncoder(response).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"jwks_uri":               provider.server.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/keys", func(response http.ResponseWriter, request *http.Request) {
+		json.NewEncoder(response).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(response http.ResponseWriter, request *http.Request) {
+		request.ParseForm()
+
+		if id, secret, _ := request.BasicAuth(); id != "app" || secret != "secret" {
+			response.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(response).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		switch request.Form.Get("grant_type") {
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(request.Form.Get("code_verifier")))
+			if request.Form.Get("code") != "code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != provider.challenge {
+				response.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(response).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+
+			provider.mu.Lock()
+			provider.refreshToken = "refresh-1"
+			provider.mu.Unlock()
+
+			provider.writeTokens(response, map[string]any{
+				"access_token":  "access-1",
+				"refresh_token": "refresh-1",
+				"id_token":      provider.idToken(),
+			})
+		case "refresh_token":
+			provider.mu.Lock()
+			defer provider.mu.Unlock()
+
+			if request.Form.Get("refresh_token") != provider.refreshToken {
+				response.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(response).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+
+			provider.refreshes++
+			provider.refreshToken = fmt.Sprintf("refresh-%d", provider.refreshes+1)
+
+			provider.writeTokens(response, map[string]any{
+				"access_token":  "access-2",
+				"refresh_token": provider.refreshToken,
+			})
+		}
+	})
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (provider *testProvider) writeTokens(response http.ResponseWriter, tokens map[string]any) {
+	if provider.expiresIn != 0 {
+		tokens["expires_in"] = provider.expiresIn
+	}
+
+	json.NewEncoder(response).Encode(tokens)
+}
+
+func (provider *testProvider) idToken() string {
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	claims := map[string]any{
+		"iss":   provider.server.URL,
+		"sub":   "user-1",
+		"aud":   "app",
+		"exp":   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC).Unix(),
+		"nonce": provider.nonce,
+		"email": "ibn@example.com",
+		"roles": "admin",
+	}
+
+	for name, value := range provider.claims {
+		claims[name] = value
+	}
+
+	signed := encode(map[string]string{"alg": "RS256", "kid": "k1"}) + "." + encode(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, provider.key, crypto.SHA256, digest[:])
+	if err != nil {
+		provider.t.Fatalf("SignPKCS1v15: %s", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newTestOIDCRouter returns a router signing users in with provider, whose
+// clock starts at the issuance of its ID tokens, and a function serving the
+// requests for path with cookies.
+func newTestOIDCRouter(t *testing.T, provider *testProvider) (*testClock, func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder) {
+	oidc, err := NewOIDC(OIDCConfig{
+		Issuer:       provider.server.URL,
+		ClientID:     "app",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/auth/callback",
+		SessionKey:   []byte("0123456789abcdef0123456789abcdef"),
+		Permissions: func(claims map[string]any) []string {
+			if claims["roles"] == "admin" {
+				return []string{"*"}
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDC: %s", err)
+	}
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	router := NewRouter()
+	router.Clock = clock
+	router.Register(oidc)
+	router.Use(Authorize)
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		principal := GetPrincipal(ctx)
+		response.Write([]byte(principal.ID + " " + principal.Claims["email"] + " " + AccessToken(ctx)))
+	}, "GET").Require("orders:read")
+
+	if err := router.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+
+	serve := func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", path, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	return clock, serve
+}
+
+// signIn goes through the sign in of the user of provider and returns the
+// response of the callback.
+func signIn(provider *testProvider, serve func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	login := serve("/auth/login?return_to=/orders", nil)
+	location, _ := url.Parse(login.Header().Get("Location"))
+
+	provider.nonce = location.Query().Get("nonce")
+	provider.challenge = location.Query().Get("code_challenge")
+
+	return serve("/auth/callback?code=code&state="+location.Query().Get("state"), login.Result().Cookies())
+}
+
+func sessionCookies(response *httptest.ResponseRecorder) []*http.Cookie {
+	var session []*http.Cookie
+	for _, cookie := range response.Result().Cookies() {
+		if cookie.Name == "auth" {
+			session = append(session, cookie)
+		}
+	}
+
+	return session
+}
+
+func TestOIDC(t *testing.T) {
+	provider := newTestProvider(t)
+	clock, serve := newTestOIDCRouter(t, provider)
+
+	if recorder := serve("/orders", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without session but was %d", recorder.Code)
+	}
+
+	login := serve("/auth/login?return_to=/orders", nil)
+	if login.Code != http.StatusFound {
+		t.Fatalf("expected login to redirect but was %d", login.Code)
+	}
+
+	location, _ := url.Parse(login.Header().Get("Location"))
+	query := location.Query()
+
+	if !strings.HasPrefix(location.String(), provider.server.URL+"/authorize?") || query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid profile email" {
+		t.Fatalf("unexpected authorization request %s", location)
+	}
+
+	provider.nonce = query.Get("nonce")
+	provider.challenge = query.Get("code_challenge")
+
+	flow := login.Result().Cookies()
+
+	if recorder := serve("/auth/callback?code=code&state=forged", flow); recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a forged state but was %d", recorder.Code)
+	}
+
+	callback := serve("/auth/callback?code=code&state="+query.Get("state"), flow)
+	if callback.Code != http.StatusFound || callback.Header().Get("Location") != "/orders" {
+		t.Fatalf("expected callback to redirect to /orders but was %d %s: %s", callback.Code, callback.Header().Get("Location"), callback.Body)
+	}
+
+	session := sessionCookies(callback)
+
+	if recorder := serve("/orders", session); recorder.Body.String() != "user-1 ibn@example.com access-1" {
+		t.Errorf("unexpected response %d %q", recorder.Code, recorder.Body)
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+
+	refreshed := serve("/orders", session)
+	if refreshed.Body.String() != "user-1 ibn@example.com access-2" || provider.refreshes != 1 {
+		t.Errorf("expected a refreshed session but got %d %q", refreshed.Code, refreshed.Body)
+	}
+
+	if cookies := refreshed.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "auth" {
+		t.Errorf("expected the refreshed session cookie but got %v", cookies)
+	}
+
+	tampered := []*http.Cookie{{Name: "auth", Value: session[0].Value[:len(session[0].Value)-2] + "AA"}}
+	if recorder := serve("/orders", tampered); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a tampered session but was %d", recorder.Code)
+	}
+}
+
+func TestOIDCWithoutExpiry(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.expiresIn = 0
+
+	clock, serve := newTestOIDCRouter(t, provider)
+	session := sessionCookies(signIn(provider, serve))
+
+	clock.now = clock.now.Add(2 * time.Hour)
+
+	if recorder := serve("/orders", session); recorder.Body.String() != "user-1 ibn@example.com access-1" || provider.refreshes != 0 {
+		t.Errorf("expected sessions without expires_in to last, got %d %q after %d refreshes", recorder.Code, recorder.Body, provider.refreshes)
+	}
+}
+
+func TestOIDCConcurrentRefresh(t *testing.T) {
+	const requests = 8
+
+	provider := newTestProvider(t)
+	clock, serve := newTestOIDCRouter(t, provider)
+	session := sessionCookies(signIn(provider, serve))
+
+	clock.now = clock.now.Add(2 * time.Hour)
+
+	recorders := make([]*httptest.ResponseRecorder, requests)
+
+	var wg sync.WaitGroup
+	for index := range recorders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorders[index] = serve("/orders", session)
+		}()
+	}
+	wg.Wait()
+
+	for _, recorder := range recorders {
+		if recorder.Body.String() != "user-1 ibn@example.com access-2" {
+			t.Errorf("expected every request to be refreshed, got %d %q", recorder.Code, recorder.Body)
+		}
+	}
+
+	if provider.refreshes != 1 {
+		t.Errorf("expected a single refresh but got %d", provider.refreshes)
+	}
+}
+
+func TestOIDCCookieTooLarge(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.claims = map[string]any{"picture": strings.Repeat("x", 5000)}
+
+	_, serve := newTestOIDCRouter(t, provider)
+
+	if callback := signIn(provider, serve); callback.Code != http.StatusInternalServerError || len(sessionCookies(callback)) != 0 {
+		t.Errorf("expected a session too large for a cookie to fail, got %d %v", callback.Code, callback.Result().Cookies())
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := map[string]string{
+		"/orders?page=2":     "/orders?page=2",
+		"":                   "/",
+		"https://evil.com":   "/",
+		"//evil.com":         "/",
+		"/\\evil.com":        "/",
+		"javascript:alert()": "/",
+	}
+
+	for input, expected := range tests {
+		if output := safeReturnTo(input); output != expected {
+			t.Errorf("%q: expected %q but was %q", input, expected, output)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI is a loaded OpenAPI 3 document, from which routes are registered
+// with Mount.
+type OpenAPI struct {
+	operations []*apiOperation
+}
+
+type apiOperation struct {
+	id     string
+	method string
+	path   string
+
+	params       []apiParam
+	body         *JSONSchema
+	bodyRequired bool
+
+	stubStatus int
+	stubBody   []byte
+}
+
+type apiParam struct {
+	name     string
+	in       string
+	required bool
+	schema   *JSONSchema
+}
+
+var apiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// LoadOpenAPI loads an OpenAPI 3 document in JSON. Schemas may use local
+// references such as "#/components/schemas/User", which must all resolve.
+func LoadOpenAPI(data []byte) (*OpenAPI, error) {
+	document, err := decodeJSONNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := document.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("openapi: expected an object, got %T", document)
+	}
+
+	root := &schemaRoot{document: document, refs: make(map[string]*JSONSchema)}
+	api := &OpenAPI{}
+
+	paths, _ := spec["paths"].(map[string]any)
+
+	patterns := make([]string, 0, len(paths))
+	for pattern := range paths {
+		patterns = append(patterns, pattern)
+	}
+
+	// registration order decides matching, so keep it independent of the
+	// map order and put literal segments before params
+	sort.Strings(patterns)
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return strings.Count(patterns[i], "{") < strings.Count(patterns[j], "{")
+	})
+
+	for _, pattern := range patterns {
+		item, _ := paths[pattern].(map[string]any)
+		shared, _ := item["parameters"].([]any)
+
+		for _, method := range apiMethods {
+			object, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			operation, err := loadOperation(root, pattern, method, object, shared)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", strings.ToUpper(method), pattern, err)
+			}
+
+			api.operations = append(api.operations, operation)
+		}
+	}
+
+	if err := root.resolveAll(); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+
+	return api, nil
+}
+
+func loadOperation(root *schemaRoot, pattern string, method string, object map[string]any, shared []any) (*apiOperation, error) {
+	operation := &apiOperation{
+		method: strings.ToUpper(method),
+		path:   openAPIPath(pattern),
+	}
+
+	operation.id, _ = object["operationId"].(string)
+
+	params, _ := object["parameters"].([]any)
+	for _, param := range append(slices.Clone(shared), params...) {
+		definition, err := root.dereference(param)
+		if err != nil {
+			return nil, err
+		}
+
+		name, _ := definition["name"].(string)
+		in, _ := definition["in"].(string)
+		required, _ := definition["required"].(bool)
+
+		if in != "path" && in != "query" {
+			continue
+		}
+
+		apiParam := apiParam{name: name, in: in, required: required}
+
+		if schema, ok := definition["schema"]; ok {
+			compiled, err := compileSchema(schema, root)
+			if err != nil {
+				return nil, err
+			}
+
+			apiParam.schema = compiled
+		}
+
+		operation.params = append(operation.params, apiParam)
+	}
+
+	if requestBody, ok := object["requestBody"]; ok {
+		definition, err := root.dereference(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		operation.bodyRequired, _ = definition["required"].(bool)
+
+		if media, ok := jsonMedia(definition); ok {
+			if schema, ok := media["schema"]; ok {
+				if operation.body, err = compileSchema(schema, root); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	responses, _ := object["responses"].(map[string]any)
+	if err := operation.loadStub(root, responses); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// loadStub picks the lowest 2xx response and its example, if any.
+func (operation *apiOperation) loadStub(root *schemaRoot, responses map[string]any) error {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+
+	if len(codes) == 0 {
+		operation.stubStatus = http.StatusNotImplemented
+		return nil
+	}
+
+	sort.Strings(codes)
+
+	operation.stubStatus = http.StatusOK
+	if status, err := strconv.Atoi(codes[0]); err == nil {
+		operation.stubStatus = status
+	}
+
+	response, err := root.dereference(responses[codes[0]])
+	if err != nil {
+		return err
+	}
+
+	media, ok := jsonMedia(response)
+	if !ok {
+		return nil
+	}
+
+	example, found := media["example"]
+
+	if examples, ok := media["examples"].(map[string]any); ok && !found {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		if len(names) > 0 {
+			definition, err := root.dereference(examples[names[0]])
+			if err != nil {
+				return err
+			}
+
+			example, found = definition["value"]
+		}
+	}
+
+	if schema, ok := media["schema"]; ok && !found {
+		if definition, err := root.dereference(schema); err == nil {
+			example, found = definition["example"]
+		}
+	}
+
+	if found {
+		encoded, err := json.Marshal(example)
+		if err != nil {
+			return err
+		}
+
+		operation.stubBody = append(encoded, '\n')
+	}
+
+	return nil
+}
+
+func jsonMedia(definition map[string]any) (map[string]any, bool) {
+	content, _ := definition["content"].(map[string]any)
+
+	for mediaType, media := range content {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			object, ok := media.(map[string]any)
+			return object, ok
+		}
+	}
+
+	return nil, false
+}
+
+// dereference follows a local $ref, if node is one, and returns the object
+// it points to.
+func (root *schemaRoot) dereference(node any) (map[string]any, error) {
+	return root.follow(node, nil)
+}
+
+// follow is dereference, visited holding the references followed so far,
+// so that a reference leading back to itself is an error.
+func (root *schemaRoot) follow(node any, visited map[string]bool) (map[string]any, error) {
+	object, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", node)
+	}
+
+	ref, ok := object["$ref"].(string)
+	if !ok {
+		return object, nil
+	}
+
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only local references are supported, got %q", ref)
+	}
+
+	if visited[ref] {
+		return nil, fmt.Errorf("circular reference %q", ref)
+	}
+
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+
+	visited[ref] = true
+
+	var current any = root.document
+
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+
+		current = node[token]
+	}
+
+	return root.follow(current, visited)
+}
+
+// openAPIPath turns "/users/{id}" into the pattern "/users/:id".
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+
+	for index, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[index] = ":" + segment[1:len(segment)-1]
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Mount registers a route for every operation of the document. Operations
+// whose operationId has a handler in handlers are served by it; the others
+// by a stub answering with the example of their first 2xx response, marked
+// with an "X-Stub: true" header, so clients can be developed against the
+// contract before the server is done. Either way, requests are first
+// validated against the path and query parameters and the JSON body schema
+// of the operation, and answered with ValidationFailed when they break it.
+func (api *OpenAPI) Mount(router *Router, handlers map[string]Handler) {
+	for _, operation := range api.operations {
+		handler, ok := handlers[operation.id]
+		if !ok {
+			handler = operation.stub
+		}
+
+		router.Handle(operation.path, operation.validated(handler), operation.method)
+	}
+}
+
+func (operation *apiOperation) stub(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	response.Header().Set("X-Stub", "true")
+
+	if operation.stubBody == nil {
+		response.WriteHeader(operation.stubStatus)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(operation.stubStatus)
+	response.Write(operation.stubBody)
+}
+
+func (operation *apiOperation) validated(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		validator := GetValidator()
+		defer PutValidator(validator)
+
+		query := request.URL.Query()
+
+		for _, param := range operation.params {
+			value, present := "", false
+
+			if param.in == "path" {
+				value, present = paramValue(ctx, param.name)
+			} else if values, ok := query[param.name]; ok {
+				value, present = values[0], true
+			}
+
+			if !present {
+				if param.required {
+					validator.AddFieldError(param.name, CodeRequired, "must be provided")
+				}
+
+				continue
+			}
+
+			if param.schema != nil {
+				param.schema.validate(param.schema.coerce(value), param.name, validator)
+			}
+		}
+
+		if operation.body != nil {
+			data, err := io.ReadAll(io.LimitReader(request.Body, MaxBodyBytes+1))
+			if err != nil {
+				response.WriteHeader(http.StatusBadRequest)
+				response.Write([]byte("the request body could not be read\n"))
+				return
+			}
+
+			if int64(len(data)) > MaxBodyBytes {
+				writeError(ctx, response, ErrBodyTooLarge)
+				return
+			}
+
+			request.Body = io.NopCloser(bytes.NewReader(data))
+
+			switch {
+			case len(bytes.TrimSpace(data)) == 0:
+				if operation.bodyRequired {
+					validator.AddNonFieldError("body must not be empty")
+				}
+			default:
+				if err := operation.body.ValidateJSON(data, validator); err != nil {
+					validator.AddNonFieldError("body contains badly-formed JSON")
+				}
+			}
+		}
+
+		if !validator.Ok() {
+			ValidationFailed(ctx, response, validator)
+			return
+		}
+
+		next(ctx, response, request)
+	}
+}
+
+// coerce converts a path or query parameter to the JSON type its schema
+// expects, leaving it a string when it does not parse.
+func (schema *JSONSchema) coerce(value string) any {
+	for _, typ := range schema.types {
+		switch typ {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return json.Number(value)
+			}
+		case "boolean":
+			if boolean, err := strconv.ParseBool(value); err == nil {
+				return boolean
+			}
+		}
+	}
+
+	return value
+}
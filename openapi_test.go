@@ -0,0 +1,154 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/users": {
+			"post": {
+				"operationId": "createUser",
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+				},
+				"responses": {"201": {"description": "created"}}
+			},
+			"get": {
+				"operationId": "listUsers",
+				"parameters": [{"name": "limit", "in": "query", "schema": {"type": "integer", "maximum": 100}}],
+				"responses": {
+					"200": {
+						"description": "users",
+						"content": {"application/json": {"examples": {"one": {"value": [{"name": "ibn"}]}}}}
+					}
+				}
+			}
+		},
+		"/users/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+			"get": {
+				"operationId": "getUser",
+				"responses": {
+					"200": {
+						"description": "user",
+						"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+					},
+					"404": {"description": "missing"}
+				}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {"name": {"type": "string", "minLength": 1}},
+				"example": {"name": "sina"}
+			}
+		}
+	}
+}`
+
+func TestOpenAPI(t *testing.T) {
+	api, err := LoadOpenAPI([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("LoadOpenAPI: %s", err)
+	}
+
+	router := NewRouter()
+	api.Mount(router, map[string]Handler{
+		"createUser": func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		stub   bool
+		output string
+	}{
+		{"POST", "/users", `{"name":"ibn"}`, http.StatusCreated, false, ""},
+		{"POST", "/users", `{"name":""}`, http.StatusUnprocessableEntity, false, `"/name"`},
+		{"POST", "/users", ``, http.StatusUnprocessableEntity, false, "body must not be empty"},
+		{"GET", "/users", "", http.StatusOK, true, `[{"name":"ibn"}]`},
+		{"GET", "/users?limit=500", "", http.StatusUnprocessableEntity, false, `"limit"`},
+		{"GET", "/users/7", "", http.StatusOK, true, `{"name":"sina"}`},
+		{"GET", "/users/x", "", http.StatusUnprocessableEntity, false, `"id"`},
+		{"POST", "/users", `{"name":"` + strings.Repeat("a", int(MaxBodyBytes)) + `"}`, http.StatusRequestEntityTooLarge, false, ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s %s: expected status %d but was %d", test.method, test.path, test.status, recorder.Code)
+		}
+
+		if stub := recorder.Header().Get("X-Stub") == "true"; stub != test.stub {
+			t.Errorf("%s %s: expected stub %t but was %t", test.method, test.path, test.stub, stub)
+		}
+
+		if !strings.Contains(recorder.Body.String(), test.output) {
+			t.Errorf("%s %s: expected body to contain %s but was %q", test.method, test.path, test.output, recorder.Body.String())
+		}
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	if path := openAPIPath("/users/{id}/posts/{post}"); path != "/users/:id/posts/:post" {
+		t.Errorf("unexpected path %q", path)
+	}
+}
+
+func TestOpenAPIDanglingReference(t *testing.T) {
+	spec := `{
+		"paths": {
+			"/users": {
+				"post": {
+					"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Missing"}}}},
+					"responses": {"201": {"description": "created"}}
+				}
+			}
+		}
+	}`
+
+	if _, err := LoadOpenAPI([]byte(spec)); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("expected an unresolvable reference error but got %v", err)
+	}
+}
+
+func TestOpenAPICircularReference(t *testing.T) {
+	spec := `{
+		"paths": {
+			"/users": {
+				"get": {
+					"responses": {"200": {"$ref": "#/components/responses/Users"}}
+				}
+			}
+		},
+		"components": {
+			"responses": {
+				"Users": {"$ref": "#/components/responses/Others"},
+				"Others": {"$ref": "#/components/responses/Users"}
+			}
+		}
+	}`
+
+	if _, err := LoadOpenAPI([]byte(spec)); err == nil || !strings.Contains(err.Error(), "circular reference") {
+		t.Errorf("expected a circular reference error but got %v", err)
+	}
+}
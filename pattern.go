@@ -0,0 +1,323 @@
+package ibnsina
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	literalSegment segmentKind = iota
+	paramSegment
+	wildcardSegment
+)
+
+type segment struct {
+	kind    segmentKind
+	literal string
+	name    string
+	rx      *regexp.Regexp
+	integer bool
+}
+
+// intParam is the value of a param declared ":name|int", parsed when the
+// path is matched.
+type intParam struct {
+	name  string
+	value int64
+}
+
+// Pattern is a parsed route pattern. Patterns are split on "/" into segments,
+// each of which is one of:
+//
+//   - a literal, such as "users", matching a path segment equal to it once
+//     both are percent-decoded, so "/café" matches "/caf%C3%A9";
+//   - a param, ":name", matching any non-empty segment, or ":name|regexp",
+//     matching segments the regular expression matches, empty ones included.
+//     Params capture the segment as it appears in the escaped path, and the
+//     regular expression is matched against that escaped form. ":name|int"
+//     matches optionally signed base 10 integers fitting in an int64,
+//     parsed once when matched, for ParamInt;
+//   - the wildcard "...", only allowed as the last segment, matching the rest
+//     of the path, empty or not, captured under the name "...".
+//
+// Paths are matched in their escaped form (URL.EscapedPath), so an encoded
+// slash "%2F" never separates segments. Leading and trailing slashes are
+// significant: "/users/" and "/users" are different paths.
+type Pattern struct {
+	raw      string
+	segments []segment
+	wildcard bool
+}
+
+// ParsePattern parses and validates a route pattern. It rejects invalid
+// regular expressions, unnamed or duplicate params and wildcards that are not
+// the last segment.
+func ParsePattern(pattern string) (*Pattern, error) {
+	parts := strings.Split(pattern, "/")
+
+	parsed := &Pattern{
+		raw:      pattern,
+		segments: make([]segment, len(parts)),
+	}
+
+	names := map[string]bool{}
+
+	for index, part := range parts {
+		switch {
+		case part == "...":
+			if index != len(parts)-1 || index == 0 {
+				return nil, fmt.Errorf("pattern %q: the wildcard must be the last segment", pattern)
+			}
+
+			parsed.segments[index] = segment{kind: wildcardSegment, name: "..."}
+			parsed.wildcard = true
+		case strings.HasPrefix(part, ":"):
+			name, rx, constrained := strings.Cut(part[1:], "|")
+			if name == "" {
+				return nil, fmt.Errorf("pattern %q: param without a name in segment %d", pattern, index)
+			}
+
+			if names[name] {
+				return nil, fmt.Errorf("pattern %q: duplicate param %q", pattern, name)
+			}
+
+			names[name] = true
+
+			seg := segment{kind: paramSegment, name: name}
+
+			if constrained && rx == "int" {
+				seg.integer = true
+			} else if constrained {
+				compiled, err := regexp.Compile(rx)
+				if err != nil {
+					return nil, fmt.Errorf("pattern %q: param %q: %w", pattern, name, err)
+				}
+
+				seg.rx = compiled
+			}
+
+			parsed.segments[index] = seg
+		default:
+			literal := part
+			if unescaped, err := url.PathUnescape(part); err == nil {
+				literal = unescaped
+			}
+
+			parsed.segments[index] = segment{kind: literalSegment, literal: literal}
+		}
+	}
+
+	return parsed, nil
+}
+
+// MustParsePattern is like ParsePattern but panics on invalid patterns.
+func MustParsePattern(pattern string) *Pattern {
+	parsed, err := ParsePattern(pattern)
+	if err != nil {
+		panic("ibnsina: " + err.Error())
+	}
+
+	return parsed
+}
+
+func (pattern *Pattern) String() string {
+	return pattern.raw
+}
+
+// Match matches an escaped path against the pattern and returns the captured
+// params, which is nil when the pattern has none.
+func (pattern *Pattern) Match(path string) (map[string]string, bool) {
+	return pattern.match(path, strings.Count(path, "/")+1, nil)
+}
+
+// MatchPath parses pattern and matches path against it. Invalid patterns
+// never match. It is meant for tests and fuzzing; the router parses patterns
+// once, at registration.
+func MatchPath(pattern string, path string) (map[string]string, bool) {
+	parsed, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, false
+	}
+
+	return parsed.Match(path)
+}
+
+// match walks path segment by segment without splitting it, so that the cost
+// of a mismatch does not grow with the length of the path. count is the
+// number of segments in path, computed once per request by the router. The
+// values of int params are appended to ints, when not nil, even when path
+// does not match in the end.
+func (pattern *Pattern) match(path string, count int, ints *[]intParam) (map[string]string, bool) {
+	if pattern.wildcard {
+		if count < len(pattern.segments) {
+			return nil, false
+		}
+	} else if count != len(pattern.segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+
+	rest := path
+
+	for index := range pattern.segments {
+		seg := &pattern.segments[index]
+
+		if seg.kind == wildcardSegment {
+			if params == nil {
+				params = make(map[string]string, 1)
+			}
+
+			params[seg.name] = rest
+			return params, true
+		}
+
+		current, next, _ := strings.Cut(rest, "/")
+		rest = next
+
+		switch seg.kind {
+		case literalSegment:
+			if current != seg.literal && !unescapedEqual(current, seg.literal) {
+				return nil, false
+			}
+		case paramSegment:
+			if seg.integer {
+				value, err := strconv.ParseInt(current, 10, 64)
+				if err != nil {
+					return nil, false
+				}
+
+				if ints != nil {
+					*ints = append(*ints, intParam{name: seg.name, value: value})
+				}
+			} else if seg.rx != nil {
+				if !seg.rx.MatchString(current) {
+					return nil, false
+				}
+			} else if current == "" {
+				return nil, false
+			}
+
+			if params == nil {
+				params = make(map[string]string, len(pattern.segments)-index)
+			}
+
+			params[seg.name] = current
+		}
+	}
+
+	return params, true
+}
+
+// EncodedSlashes is a policy for encoded slashes ("%2F") in request paths.
+type EncodedSlashes int
+
+const (
+	// PassEncodedSlashes keeps "%2F" inside its segment, so "/files/a%2Fb"
+	// has two segments and, unless RawParams is set, a param matching the
+	// second one holds "a/b".
+	PassEncodedSlashes EncodedSlashes = iota
+
+	// RejectEncodedSlashes answers requests with "%2F" in their path with
+	// 400 Bad Request.
+	RejectEncodedSlashes
+
+	// DecodeEncodedSlashes turns "%2F" into "/" before matching, so
+	// "/files/a%2Fb" is matched as "/files/a/b".
+	DecodeEncodedSlashes
+)
+
+// requestPath applies the router's path policies to an escaped request path.
+// It reports false when the path must be rejected.
+func (router *Router) requestPath(path string) (string, bool) {
+	if strings.Contains(path, "%2") && (strings.Contains(path, "%2F") || strings.Contains(path, "%2f")) {
+		switch router.EncodedSlashes {
+		case RejectEncodedSlashes:
+			return path, false
+		case DecodeEncodedSlashes:
+			path = strings.NewReplacer("%2F", "/", "%2f", "/").Replace(path)
+		}
+	}
+
+	if router.CleanPath {
+		path = removeDotSegments(path)
+	}
+
+	return path, true
+}
+
+// removeDotSegments removes "." and ".." segments from an escaped path as
+// described in RFC 3986, section 5.2.4, treating "%2E" as a dot. Unlike
+// path.Clean it keeps empty segments and trailing slashes, which are
+// significant when matching. ".." never climbs above the root.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") && !strings.Contains(path, "%2") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	cleaned := make([]string, 0, len(segments))
+
+	for index, segment := range segments {
+		last := index == len(segments)-1
+
+		switch dotSegment(segment) {
+		case ".":
+			if last {
+				cleaned = append(cleaned, "")
+			}
+		case "..":
+			if len(cleaned) > 1 {
+				cleaned = cleaned[:len(cleaned)-1]
+			}
+
+			if last {
+				cleaned = append(cleaned, "")
+			}
+		default:
+			cleaned = append(cleaned, segment)
+		}
+	}
+
+	return strings.Join(cleaned, "/")
+}
+
+func dotSegment(segment string) string {
+	if len(segment) > 6 {
+		return segment
+	}
+
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "%2E", "."), "%2e", ".")
+}
+
+// unescapeParams percent-decodes params in place. Segments are split before
+// decoding, so an encoded slash ends up as a literal "/" inside a param
+// value; the wildcard is decoded as a whole, making "%2F" and "/"
+// indistinguishable in it. Values that are not validly encoded are left as
+// they are.
+func unescapeParams(params map[string]string) {
+	for name, value := range params {
+		if !strings.Contains(value, "%") {
+			continue
+		}
+
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			params[name] = unescaped
+		}
+	}
+}
+
+func unescapedEqual(escaped string, literal string) bool {
+	if !strings.Contains(escaped, "%") {
+		return false
+	}
+
+	unescaped, err := url.PathUnescape(escaped)
+
+	return err == nil && unescaped == literal
+}
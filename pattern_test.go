@@ -0,0 +1,233 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	invalid := []string{
+		"/files/.../more",
+		"...",
+		"/users/:",
+		"/users/:|^[0-9]+$",
+		"/users/:id/:id",
+		"/users/:id|([0-9]",
+	}
+
+	for _, pattern := range invalid {
+		if _, err := ParsePattern(pattern); err == nil {
+			t.Errorf("expected an error for pattern %q", pattern)
+		}
+	}
+
+	valid := []string{"", "/", "/users", "/users/:id|^[0-9]+$/...", "/prefix...", "/baz//:age"}
+
+	for _, pattern := range valid {
+		parsed, err := ParsePattern(pattern)
+		if err != nil {
+			t.Errorf("unexpected error for pattern %q: %s", pattern, err)
+			continue
+		}
+
+		if parsed.String() != pattern {
+			t.Errorf("expected String() %q but was %q", pattern, parsed.String())
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		ok      bool
+		params  map[string]string
+	}{
+		{"/café", "/caf%C3%A9", true, nil},
+		{"/caf%C3%A9", "/café", true, nil},
+		{"/a%2Fb", "/a/b", false, nil},
+		{"/users/:id", "/users/%ZZ", true, map[string]string{"id": "%ZZ"}},
+		{"/users/literal", "/users/%ZZ", false, nil},
+		{"/users/:id", "/users/", false, nil},
+		{"/users/:id|^$", "/users/", true, map[string]string{"id": ""}},
+		{"/files/...", "/files/a%2Fb/c", true, map[string]string{"...": "a%2Fb/c"}},
+		{"/users/:id/:id", "/users/1/2", false, nil},
+		{"/:a/:b", "//", false, nil},
+		{"/users/:id|int", "/users/-42", true, map[string]string{"id": "-42"}},
+		{"/users/:id|int", "/users/4x2", false, nil},
+		{"/users/:id|int", "/users/", false, nil},
+		{"/users/:id|int", "/users/99999999999999999999", false, nil},
+	}
+
+	for _, test := range tests {
+		params, ok := MatchPath(test.pattern, test.path)
+		if ok != test.ok {
+			t.Errorf("MatchPath(%q, %q): expected %t but was %t", test.pattern, test.path, test.ok, ok)
+			continue
+		}
+
+		for name, value := range test.params {
+			if params[name] != value {
+				t.Errorf("MatchPath(%q, %q): expected param %q to be %q but was %q", test.pattern, test.path, name, value, params[name])
+			}
+		}
+	}
+}
+
+func TestMatchPathLong(t *testing.T) {
+	path := strings.Repeat("/segment", 100000)
+
+	if _, ok := MatchPath("/users/:id", path); ok {
+		t.Error("expected a long path not to match")
+	}
+
+	params, ok := MatchPath("/segment/...", path)
+	if !ok || len(params["..."]) != len(path)-len("/segment/") {
+		t.Error("expected the wildcard to capture the rest of a long path")
+	}
+}
+
+func FuzzMatchPath(f *testing.F) {
+	f.Add("/users/:id|^[0-9]+$/...", "/users/42/a/b")
+	f.Add("/caf%C3%A9/:name", "/café/%2F%2F")
+	f.Add("/baz//:age", "/baz//21")
+	f.Add("/...", "")
+
+	f.Fuzz(func(t *testing.T, pattern string, path string) {
+		parsed, err := ParsePattern(pattern)
+		if err != nil {
+			return
+		}
+
+		params, ok := parsed.Match(path)
+		if !ok {
+			return
+		}
+
+		for index := range parsed.segments {
+			seg := &parsed.segments[index]
+
+			if seg.kind == literalSegment {
+				continue
+			}
+
+			value, found := params[seg.name]
+			if !found {
+				t.Fatalf("pattern %q matched %q without capturing %q", pattern, path, seg.name)
+			}
+
+			if seg.kind == paramSegment && strings.Contains(value, "/") {
+				t.Fatalf("pattern %q matched %q with param %q spanning segments: %q", pattern, path, seg.name, value)
+			}
+		}
+	})
+}
+
+func TestRemoveDotSegments(t *testing.T) {
+	tests := map[string]string{
+		"/static/../admin":    "/admin",
+		"/a/./b":              "/a/b",
+		"/a/b/.":              "/a/b/",
+		"/a/b/..":             "/a/",
+		"/..":                 "/",
+		"/../../etc/passwd":   "/etc/passwd",
+		"/a/%2e%2E/b":         "/b",
+		"/a/.%2e/b":           "/b",
+		"/baz//21":            "/baz//21",
+		"/a/b/":               "/a/b/",
+		"/a/...":              "/a/...",
+		"/files/.hidden/../x": "/files/x",
+	}
+
+	for path, expected := range tests {
+		if cleaned := removeDotSegments(path); cleaned != expected {
+			t.Errorf("removeDotSegments(%q): expected %q but was %q", path, expected, cleaned)
+		}
+	}
+}
+
+func TestPathPolicies(t *testing.T) {
+	tests := []struct {
+		encodedSlashes EncodedSlashes
+		cleanPath      bool
+		path           string
+		expectedStatus int
+		expectedFile   string
+	}{
+		{PassEncodedSlashes, false, "/files/a%2Fb", http.StatusOK, "a/b"},
+		{RejectEncodedSlashes, false, "/files/a%2Fb", http.StatusBadRequest, ""},
+		{RejectEncodedSlashes, false, "/files/a%2fb", http.StatusBadRequest, ""},
+		{DecodeEncodedSlashes, false, "/files/a%2Fb", http.StatusNotFound, ""},
+		{DecodeEncodedSlashes, false, "/files/x%2F..%2Fb", http.StatusNotFound, ""},
+		{DecodeEncodedSlashes, true, "/files/x%2F..%2Fb", http.StatusOK, "b"},
+		{PassEncodedSlashes, false, "/static/../files/b", http.StatusNotFound, ""},
+		{PassEncodedSlashes, true, "/static/../files/b", http.StatusOK, "b"},
+		{PassEncodedSlashes, true, "/files/%2e%2e/files/b", http.StatusOK, "b"},
+	}
+
+	for _, test := range tests {
+		router := NewRouter()
+		router.EncodedSlashes = test.encodedSlashes
+		router.CleanPath = test.cleanPath
+
+		var file string
+
+		router.Handle("/files/:file", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			file = Param(ctx, "file")
+		}, http.MethodGet)
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if response.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d but was %d", test.path, test.expectedStatus, response.Code)
+			continue
+		}
+
+		if file != test.expectedFile {
+			t.Errorf("%s: expected param %q but was %q", test.path, test.expectedFile, file)
+		}
+	}
+}
+
+func TestParamInt(t *testing.T) {
+	for _, cached := range []bool{false, true} {
+		router := NewRouter()
+		if cached {
+			router.EnableMatchCache(16)
+		}
+
+		var id int64
+		var ok bool
+
+		router.Handle("/users/:id|int", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			id, ok = ParamInt(ctx, "id")
+		}, "GET")
+
+		router.Handle("/users/:name", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			id, ok = ParamInt(ctx, "id")
+		}, "GET")
+
+		for range 2 {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+			if id != 42 || !ok {
+				t.Errorf("cached %t: expected id 42 but got %d %t", cached, id, ok)
+			}
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/alice", nil))
+
+			if ok {
+				t.Errorf("cached %t: expected no id for a name, got %d", cached, id)
+			}
+		}
+	}
+
+	ctx := WithParams(context.Background(), map[string]string{"id": "7"})
+	if id, ok := ParamInt(ctx, "id"); id != 7 || !ok {
+		t.Errorf("expected params set with WithParams to be parsed, got %d %t", id, ok)
+	}
+}
@@ -0,0 +1,45 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an RFC 9457 problem details object.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	TraceID  string `json:"trace_id,omitempty"`
+}
+
+// WriteProblem responds with problem as application/problem+json. The title
+// defaults to the text of the status and the trace id to the one of ctx. A
+// failure to write the body fails the response.
+func WriteProblem(ctx context.Context, response http.ResponseWriter, problem Problem) error {
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	if values := GetValues(ctx); values != nil && problem.TraceID == "" {
+		problem.TraceID = values.TraceID
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	response.Header().Set("Content-Type", "application/problem+json")
+	response.WriteHeader(problem.Status)
+
+	if _, err := response.Write(append(body, '\n')); err != nil {
+		failResponse(ctx, err)
+		return err
+	}
+
+	return nil
+}
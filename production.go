@@ -0,0 +1,122 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// NewProductionRouter returns a router set up for production, configured
+// by config:
+//
+//   - the access log, to logger, and Recover, logging to logger too
+//   - Disconnects and Instrument, recording to a Metrics registry served at
+//     /metrics, which Run also reports its connections to
+//   - SecureHeaders, with an HSTS max-age of hsts.maxage, 180 days by
+//     default
+//   - Timeout, giving handlers timeout.request, 30 seconds by default, none
+//     when zero or less; the streams of Router.Stream are not subject to it
+//   - the CORS, compression and rate limit of StackFromConfig
+//   - /healthz, answering 200 OK while the process serves, and /readyz,
+//     answering 503 once Run is interrupted, shutdown.delay, 5 seconds by
+//     default, before it starts shutting down
+//   - with stats.routes, RouteStats, reported by ServeStats at /stats
+//   - with sample.percent or sample.header, whose value must be
+//     sample.secret, SampleRequests, the samples
+//     being served at /samples
+//   - CaptureErrors, keeping the latest errors.size errors, 100 by default,
+//     served at /errors
+//
+// The probes are neither logged, sampled nor rate limited. /metrics, /stats,
+// /samples and /errors are served by the Admin router, which Run serves on
+// admin.addr, 127.0.0.1:9090 by default, away from the public listener.
+func NewProductionRouter(config *Config, logger Logger) *Router {
+	metrics := NewMetrics()
+	probes := []string{"/healthz", "/readyz"}
+	sampler := samplerFromConfig(config)
+	errorLog := NewErrorLog(config.IntOrDefault("errors.size", 0))
+
+	middlewares := []Middleware{
+		exceptRoutes(probes, AccessLog(logger)),
+	}
+
+	if sampler != nil {
+		middlewares = append(middlewares, exceptRoutes(probes, SampleRequests(sampler)))
+	}
+
+	middlewares = append(middlewares,
+		CaptureErrors(errorLog),
+		Disconnects(metrics),
+		Instrument(metrics, InstrumentOptions{}),
+		Recover,
+		SecureHeaders(SecureHeadersOptions{HSTS: config.DurationOrDefault("hsts.maxage", 180*24*time.Hour)}),
+		Timeout(config.DurationOrDefault("timeout.request", 30*time.Second)),
+	)
+
+	if cors := corsFromConfig(config); cors != nil {
+		middlewares = append(middlewares, cors)
+	}
+
+	if compress := compressFromConfig(config); compress != nil {
+		middlewares = append(middlewares, compress)
+	}
+
+	if rateLimit := rateLimitFromConfig(config); rateLimit != nil {
+		middlewares = append(middlewares, exceptRoutes(probes, rateLimit))
+	}
+
+	router := NewRouter(middlewares...)
+	router.Logger = logger
+	router.Metrics = metrics
+	router.ShutdownDelay = config.DurationOrDefault("shutdown.delay", 5*time.Second)
+
+	router.Handle("/healthz", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("ok\n"))
+	}, "GET")
+
+	router.Handle("/readyz", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if router.ShuttingDown() {
+			response.WriteHeader(http.StatusServiceUnavailable)
+			response.Write([]byte("shutting down\n"))
+			return
+		}
+
+		response.Write([]byte("ok\n"))
+	}, "GET")
+
+	admin := NewRouter(Recover)
+	admin.Logger = logger
+	admin.Handle("/metrics", metrics.ServeMetrics, "GET")
+	admin.Handle("/errors", errorLog.ServeErrors, "GET")
+
+	if config.BoolOrDefault("stats.routes", false) {
+		router.RouteStats = true
+		admin.Handle("/stats", router.ServeStats, "GET")
+	}
+
+	if sampler != nil {
+		admin.Handle("/samples", sampler.ServeSamples, "GET")
+	}
+
+	router.Admin = admin
+	router.AdminAddr = config.StringOrDefault("admin.addr", "127.0.0.1:9090")
+
+	return router
+}
+
+// exceptRoutes applies middleware to the routes not matching patterns.
+func exceptRoutes(patterns []string, middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := middleware(next)
+
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if slices.Contains(patterns, RoutePattern(ctx)) {
+				next(ctx, response, request)
+				return
+			}
+
+			wrapped(ctx, response, request)
+		}
+	}
+}
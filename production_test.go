@@ -0,0 +1,139 @@
+package ibnsina
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewProductionRouter(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewProductionRouter(&Config{m: map[string]string{
+		"ratelimit.limit": "1",
+		"timeout.request": "50ms",
+	}}, logger)
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 50*time.Millisecond {
+			t.Errorf("expected the request timeout to apply but the deadline was %v", deadline)
+		}
+
+		response.Write([]byte("orders"))
+	}, "GET")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", path, nil)
+		request.Header.Set("X-Forwarded-Proto", "https")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	recorder := serve("/orders")
+
+	header := recorder.Header()
+	if recorder.Code != http.StatusOK || header.Get("X-Content-Type-Options") != "nosniff" || header.Get("Strict-Transport-Security") != "max-age=15552000; includeSubDomains" {
+		t.Errorf("unexpected response %d %v", recorder.Code, header)
+	}
+
+	if recorder := serve("/orders"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the rate limit to apply but the status was %d", recorder.Code)
+	}
+
+	for range 3 {
+		if recorder := serve("/healthz"); recorder.Code != http.StatusOK {
+			t.Errorf("expected probes not to be rate limited but the status was %d", recorder.Code)
+		}
+	}
+
+	if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "GET /orders 200") {
+		t.Errorf("expected only the orders requests to be logged but got %q", logger.lines)
+	}
+
+	for _, path := range []string{"/metrics", "/errors"} {
+		if recorder := serve(path); recorder.Code == http.StatusOK {
+			t.Errorf("expected %s not to be served publicly but the status was %d", path, recorder.Code)
+		}
+	}
+
+	serveAdmin := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.Admin.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+		return recorder
+	}
+
+	if metrics := serveAdmin("/metrics").Body.String(); !strings.Contains(metrics, `http_requests_total{method="GET",route="/orders",status="200"} 1`) {
+		t.Errorf("unexpected metrics\n%s", metrics)
+	}
+
+	if recorder := serveAdmin("/errors"); recorder.Code != http.StatusOK || router.AdminAddr != "127.0.0.1:9090" {
+		t.Errorf("expected the errors to be served on the admin router but the status was %d", recorder.Code)
+	}
+}
+
+func TestProductionRouterWithoutTimeout(t *testing.T) {
+	router := NewProductionRouter(&Config{m: map[string]string{"timeout.request": "0"}}, &testLogger{})
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if deadline, ok := ctx.Deadline(); ok || ctx.Err() != nil {
+			t.Errorf("expected no request timeout but the deadline was %v: %v", deadline, ctx.Err())
+		}
+	}, "GET")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+}
+
+func TestProductionRouterShutdown(t *testing.T) {
+	router := NewProductionRouter(&Config{m: map[string]string{"shutdown.delay": "200ms"}}, &testLogger{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: router}
+	go srv.Serve(listener)
+
+	ready := func() int {
+		response, err := http.Get("http://" + listener.Addr().String() + "/readyz")
+		if err != nil {
+			return 0
+		}
+
+		response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	if status := ready(); status != http.StatusOK {
+		t.Fatalf("expected ready before shutdown but the status was %d", status)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- router.shutdown(srv)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if status := ready(); status != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready but still serving during the delay, the status was %d", status)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if status := ready(); status != 0 {
+		t.Errorf("expected the server to be shut down after the delay but the status was %d", status)
+	}
+}
@@ -0,0 +1,151 @@
+package ibnsina
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateStore counts requests in fixed time windows. NewRateStore counts them
+// in a Store: a RedisStore enforces the limits across replicas, a MemoryStore
+// only within the process.
+type RateStore interface {
+	// Increment adds a request to the window of key containing now and
+	// returns the number of requests counted in it so far.
+	Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int64, error)
+}
+
+// RateLimitOptions configures RateLimit.
+type RateLimitOptions struct {
+	// Store counts the requests, in a MemoryStore if nil.
+	Store RateStore
+
+	// Limit is the number of requests allowed per Window, for clients
+	// without a plan or whose plan is not in Plans. Both must be positive.
+	Limit  int
+	Window time.Duration
+
+	// Plans overrides Limit for principals whose plan claim, see
+	// Principal.Claims, names one of them. RatePlans loads them from a
+	// Config.
+	Plans map[string]int
+
+	// PlanClaim names the claim holding the plan, "plan" if empty.
+	PlanClaim string
+
+	// Key returns the client a request counts for, RateLimitKey if nil.
+	Key func(ctx context.Context, request *http.Request) string
+}
+
+// RateLimit is a middleware allowing each client Limit requests per Window,
+// answering the others with 429 Too Many Requests and Retry-After. Responses
+// carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers. Clients are told apart by their principal, so the middleware must
+// run after authentication. When the store fails, requests are let through
+// and the error is logged.
+func RateLimit(options RateLimitOptions) Middleware {
+	if options.Limit <= 0 || options.Window <= 0 {
+		panic("ibnsina: the rate limit needs a positive limit and window")
+	}
+
+	if options.Store == nil {
+		options.Store = NewRateStore(NewMemoryStore())
+	}
+
+	if options.Key == nil {
+		options.Key = RateLimitKey
+	}
+
+	if options.PlanClaim == "" {
+		options.PlanClaim = "plan"
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			limit := options.Limit
+
+			if principal := GetPrincipal(ctx); principal != nil {
+				if planLimit, ok := options.Plans[principal.Claims[options.PlanClaim]]; ok {
+					limit = planLimit
+				}
+			}
+
+			now := requestTime(ctx)
+
+			count, err := options.Store.Increment(ctx, "ratelimit:"+options.Key(ctx, request), options.Window, now)
+			if err != nil {
+				if values := GetValues(ctx); values != nil && values.Logger != nil {
+					values.Logger.Printf("%s: rate limit: %v", values.TraceID, err)
+				}
+
+				next(ctx, response, request)
+				return
+			}
+
+			reset := now.Truncate(options.Window).Add(options.Window).Sub(now)
+			resetSeconds := strconv.Itoa(int((reset + time.Second - 1) / time.Second))
+
+			header := response.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(limit))
+			header.Set("RateLimit-Remaining", strconv.FormatInt(max(int64(limit)-count, 0), 10))
+			header.Set("RateLimit-Reset", resetSeconds)
+
+			if count > int64(limit) {
+				header.Set("Retry-After", resetSeconds)
+				writeError(ctx, response, &StatusError{Status: http.StatusTooManyRequests, Message: "too many requests, retry later"})
+				return
+			}
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+// RateLimitKey tells clients apart by their principal, as set by the
+// authentication, then by their IP address. Unverified credentials, such as
+// an API key header, are never used, since clients could send a new one with
+// every request to get a fresh limit.
+func RateLimitKey(ctx context.Context, request *http.Request) string {
+	if principal := GetPrincipal(ctx); principal != nil {
+		return "principal:" + principal.ID
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// RatePlans reads the per-plan limits of RateLimitOptions from the keys of
+// config starting with prefix, followed by the plan:
+//
+//	ratelimit.free=60
+//	ratelimit.pro=600
+//
+// Keys whose value is not an integer are skipped.
+func RatePlans(config *Config, prefix string) map[string]int {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	plans := make(map[string]int)
+
+	for key, value := range config.m {
+		plan, ok := strings.CutPrefix(key, prefix)
+		if !ok || plan == "" {
+			continue
+		}
+
+		config.used.Store(key, true)
+
+		if limit, err := strconv.Atoi(value); err == nil {
+			plans[plan] = limit
+		}
+	}
+
+	return plans
+}
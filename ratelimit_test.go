@@ -0,0 +1,119 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingRateStore struct{}
+
+func (failingRateStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)}
+
+	principals := map[string]*Principal{
+		"free": {ID: "1", Claims: map[string]string{"plan": "free"}},
+		"pro":  {ID: "2", Claims: map[string]string{"plan": "pro"}},
+	}
+
+	authenticate := func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if principal, ok := principals[request.Header.Get("Authorization")]; ok {
+				SetPrincipal(ctx, principal)
+			}
+
+			next(ctx, response, request)
+		}
+	}
+
+	router := NewRouter(authenticate, RateLimit(RateLimitOptions{
+		Store:  NewRateStore(NewMemoryStore()),
+		Limit:  1,
+		Window: time.Minute,
+		Plans:  RatePlans(&Config{m: map[string]string{"ratelimit.free": "2", "ratelimit.pro": "3", "port": "80"}}, "ratelimit."),
+	}))
+	router.Clock = clock
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	serve := func(authorization string, apiKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", authorization)
+		request.Header.Set("X-API-Key", apiKey)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	allowed := map[string]int{}
+
+	for range 4 {
+		for _, client := range []string{"free", "pro", "anonymous"} {
+			if serve(client, "").Code == http.StatusOK {
+				allowed[client]++
+			}
+		}
+	}
+
+	if allowed["free"] != 2 || allowed["pro"] != 3 || allowed["anonymous"] != 1 {
+		t.Errorf("unexpected allowed requests %v", allowed)
+	}
+
+	if recorder := serve("", "key-1"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected unverified API keys not to get a limit of their own but got %d", recorder.Code)
+	}
+
+	limited := serve("free", "")
+	if limited.Code != http.StatusTooManyRequests || limited.Header().Get("Retry-After") != "30" || limited.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected response %d %v", limited.Code, limited.Header())
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+
+	if recorder := serve("free", ""); recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("expected the next window to allow requests but got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter(RateLimit(RateLimitOptions{Store: failingRateStore{}, Limit: 1, Window: time.Minute}))
+	router.Logger = logger
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected requests through when the store fails but got %d", recorder.Code)
+		}
+	}
+
+	if len(logger.lines) != 2 {
+		t.Errorf("expected the failures logged but got %q", logger.lines)
+	}
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	for _, options := range []RateLimitOptions{{Limit: 0, Window: time.Minute}, {Limit: 10, Window: 0}, {Limit: -1, Window: -time.Second}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %+v to be rejected", options)
+				}
+			}()
+
+			RateLimit(options)
+		}()
+	}
+}
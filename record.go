@@ -0,0 +1,218 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values removed from recorded interactions.
+const Redacted = "[REDACTED]"
+
+// Interaction is a request and the response it got, as written by Record,
+// one JSON object per line, and replayed by ibnsinatest.Replay.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordOptions configures Record.
+type RecordOptions struct {
+	// Writer receives the interactions, such as a file opened with
+	// os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600).
+	Writer io.Writer
+
+	// RedactHeaders and RedactFields name headers and JSON object fields,
+	// at any depth, whose values are replaced with Redacted in both requests
+	// and responses. Authorization, Cookie and Set-Cookie headers,
+	// password, token and secret fields, and the query params holding
+	// credentials, such as signature, are always redacted.
+	RedactHeaders []string
+	RedactFields  []string
+
+	// MaxBodyBytes bounds the recorded bodies, 64 KiB if zero; interactions
+	// with larger bodies are not recorded.
+	MaxBodyBytes int64
+}
+
+// Record is a middleware writing every request and its response, sanitized,
+// to options.Writer in a format ibnsinatest.Replay plays back against a
+// router. Run it in front of a staging deployment to capture the contract
+// its consumers rely on.
+func Record(options RecordOptions) Middleware {
+	if options.MaxBodyBytes == 0 {
+		options.MaxBodyBytes = 64 << 10
+	}
+
+	headers := map[string]bool{"Authorization": true, "Cookie": true, "Set-Cookie": true}
+	for _, name := range options.RedactHeaders {
+		headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	fields := map[string]bool{"password": true, "token": true, "secret": true}
+	for _, name := range options.RedactFields {
+		fields[name] = true
+	}
+
+	var mu sync.Mutex
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			var requestBody []byte
+
+			if request.Body != nil && request.Body != http.NoBody {
+				buffered, err := io.ReadAll(io.LimitReader(request.Body, options.MaxBodyBytes+1))
+				request.Body = readCloser{io.MultiReader(bytes.NewReader(buffered), request.Body), request.Body}
+
+				if err != nil || int64(len(buffered)) > options.MaxBodyBytes {
+					next(ctx, response, request)
+					return
+				}
+
+				requestBody = buffered
+			}
+
+			recorder := &recordingWriter{ResponseWriter: response, limit: options.MaxBodyBytes}
+			next(ctx, recorder, request)
+
+			if recorder.overflow {
+				return
+			}
+
+			interaction := Interaction{
+				Request: RecordedRequest{
+					Method: request.Method,
+					URI:    redactURI(request.URL),
+					Header: redactHeader(request.Header, headers),
+					Body:   redactBody(requestBody, fields),
+				},
+				Response: RecordedResponse{
+					Status: recorder.status,
+					Header: redactHeader(response.Header(), headers),
+					Body:   redactBody(recorder.body.Bytes(), fields),
+				},
+			}
+
+			if interaction.Response.Status == 0 {
+				interaction.Response.Status = http.StatusOK
+			}
+
+			line, err := json.Marshal(interaction)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			options.Writer.Write(append(line, '\n'))
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the response, up to limit bytes, setting
+// overflow beyond.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (writer *recordingWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *recordingWriter) Write(data []byte) (int, error) {
+	if remaining := writer.limit - int64(writer.body.Len()); int64(len(data)) > remaining {
+		// keep what fits, an excerpt for Sample
+		writer.body.Write(data[:max(remaining, 0)])
+		writer.overflow = true
+	} else {
+		writer.body.Write(data)
+	}
+
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *recordingWriter) Flush() {
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *recordingWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+func redactHeader(header http.Header, redacted map[string]bool) http.Header {
+	clone := header.Clone()
+
+	for name := range clone {
+		if redacted[name] {
+			clone[name] = []string{Redacted}
+		}
+	}
+
+	return clone
+}
+
+// redactBody replaces the values of the redacted fields of JSON bodies.
+// Other bodies are kept as they are.
+func redactBody(body []byte, fields map[string]bool) string {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return string(body)
+	}
+
+	redacted, err := json.Marshal(redactValue(decoded, fields))
+	if err != nil {
+		return string(body)
+	}
+
+	// keep the trailing newline of WriteJSON so replays compare equal
+	if strings.HasSuffix(string(body), "\n") {
+		redacted = append(redacted, '\n')
+	}
+
+	return string(redacted)
+}
+
+func redactValue(value any, fields map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if fields[key] {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(field, fields)
+			}
+		}
+	case []any:
+		for index := range v {
+			v[index] = redactValue(v[index], fields)
+		}
+	}
+
+	return value
+}
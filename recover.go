@@ -0,0 +1,45 @@
+package ibnsina
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover is a middleware answering requests whose handler panics with 500
+// Internal Server Error, unless the response was already started, and
+// logging the panic with its stack trace to Values.Logger.
+// http.ErrAbortHandler is panicked again, since net/http relies on it to
+// abort responses silently.
+func Recover(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			values := GetValues(ctx)
+			stack := debug.Stack()
+
+			if values != nil {
+				values.failed(fmt.Errorf("panic: %v", recovered), stack)
+			}
+
+			if values != nil && values.Logger != nil {
+				values.Logger.Printf("%s: panic: %v\n%s", values.TraceID, recovered, stack)
+			}
+
+			if values == nil || values.Status == 0 {
+				writeError(ctx, response, &StatusError{Status: http.StatusInternalServerError, Message: "the server encountered a problem and could not process the request"})
+			}
+		}()
+
+		next(ctx, response, request)
+	}
+}
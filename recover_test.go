@@ -0,0 +1,53 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter(Recover)
+	router.Logger = logger
+
+	router.Handle("/panic", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		panic("boom")
+	}, "GET")
+
+	router.Handle("/started", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/panic", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 but was %d", recorder.Code)
+	}
+
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "panic: boom") || !strings.Contains(logger.lines[0], "recover_test.go") {
+		t.Errorf("expected the panic to be logged with its stack but got %q", logger.lines)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/started", nil))
+
+	if recorder.Code != http.StatusAccepted {
+		t.Errorf("expected the started response to be kept but was %d", recorder.Code)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to be panicked again but got %v", recovered)
+		}
+	}()
+
+	Recover(func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		panic(http.ErrAbortHandler)
+	})(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,340 @@
+package ibnsina
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisOptions configures a RedisStore.
+type RedisOptions struct {
+	// Addr is the host:port of the Redis server.
+	Addr string
+
+	// Password authenticates connections, when not empty, with AUTH.
+	Password string
+
+	// DB selects the database of connections, when not zero, with SELECT.
+	DB int
+
+	// DialTimeout bounds connecting, 5 seconds if zero. Commands are
+	// bounded by the deadline of their context.
+	DialTimeout time.Duration
+
+	// MaxIdle is the number of connections kept open between commands, 8
+	// if zero.
+	MaxIdle int
+}
+
+// RedisStore is a Store backed by a Redis server, or any server speaking
+// its protocol, shared by every replica connecting to it. Connections are
+// opened as needed and pooled.
+type RedisStore struct {
+	options RedisOptions
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// incrScript increments a key and sets its expiry when it was created, in
+// one atomic step.
+const incrScript = `local count = redis.call('INCR', KEYS[1])
+if count == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count`
+
+// RedisError is an error reply of the server.
+type RedisError struct {
+	Message string
+}
+
+func (err *RedisError) Error() string {
+	return "redis: " + err.Message
+}
+
+func NewRedisStore(options RedisOptions) *RedisStore {
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 5 * time.Second
+	}
+
+	if options.MaxIdle == 0 {
+		options.MaxIdle = 8
+	}
+
+	return &RedisStore{options: options}
+}
+
+func (store *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := store.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply == nil {
+		return nil, ErrNotStored
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+
+	return value, nil
+}
+
+func (store *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+
+	_, err := store.do(ctx, args...)
+	return err
+}
+
+func (store *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	milliseconds := int64(0)
+	if ttl > 0 {
+		milliseconds = max(ttl.Milliseconds(), 1)
+	}
+
+	reply, err := store.do(ctx, "EVAL", incrScript, "1", key, strconv.FormatInt(milliseconds, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %v to INCR", reply)
+	}
+
+	return count, nil
+}
+
+func (store *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := store.do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the idle connections. Commands in progress are unaffected.
+func (store *RedisStore) Close() error {
+	store.mu.Lock()
+	idle := store.idle
+	store.idle = nil
+	store.mu.Unlock()
+
+	var errs []error
+	for _, conn := range idle {
+		errs = append(errs, conn.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// do sends a command and returns its reply: nil, a string, an int64, a
+// []byte or a []any. Error replies are returned as *RedisError, those
+// within arrays being elements.
+func (store *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := store.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args...)
+
+	var redisErr *RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the connection may be halfway through a reply
+		conn.Close()
+		return nil, err
+	}
+
+	store.put(conn)
+
+	return reply, err
+}
+
+func (store *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	store.mu.Lock()
+
+	if n := len(store.idle); n > 0 {
+		conn := store.idle[n-1]
+		store.idle = store.idle[:n-1]
+		store.mu.Unlock()
+
+		return conn, nil
+	}
+
+	store.mu.Unlock()
+
+	return store.dial(ctx)
+}
+
+func (store *RedisStore) put(conn *redisConn) {
+	store.mu.Lock()
+
+	if len(store.idle) < store.options.MaxIdle {
+		store.idle = append(store.idle, conn)
+		conn = nil
+	}
+
+	store.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (store *RedisStore) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: store.options.DialTimeout}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", store.options.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if store.options.Password != "" {
+		if _, err := conn.do(ctx, "AUTH", store.options.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if store.options.DB != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(store.options.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// redisConn is a connection speaking RESP, the protocol of Redis.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// cancelling ctx interrupts the command
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+		close(interrupted)
+	})
+
+	reply, err := conn.roundTrip(args)
+
+	if !stop() {
+		// the deadline set on cancel would break the next command, so the
+		// connection must not go back to the pool
+		<-interrupted
+		return nil, ctx.Err()
+	}
+
+	return reply, err
+}
+
+// roundTrip sends the command args and reads its reply.
+func (conn *redisConn) roundTrip(args []string) (any, error) {
+	command := make([]byte, 0, 64)
+	command = append(command, '*')
+	command = strconv.AppendInt(command, int64(len(args)), 10)
+	command = append(command, "\r\n"...)
+
+	for _, arg := range args {
+		command = append(command, '$')
+		command = strconv.AppendInt(command, int64(len(arg)), 10)
+		command = append(command, "\r\n"...)
+		command = append(command, arg...)
+		command = append(command, "\r\n"...)
+	}
+
+	if _, err := conn.Write(command); err != nil {
+		return nil, err
+	}
+
+	return readRedisReply(conn.reader)
+}
+
+func readRedisReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, &RedisError{Message: payload}
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size < -1 {
+			return nil, fmt.Errorf("redis: malformed reply %q", line)
+		}
+
+		if size == -1 {
+			return nil, nil
+		}
+
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+
+		return value[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count < -1 {
+			return nil, fmt.Errorf("redis: malformed reply %q", line)
+		}
+
+		if count == -1 {
+			return nil, nil
+		}
+
+		elements := make([]any, count)
+		for index := range elements {
+			element, err := readRedisReply(reader)
+
+			// errors within arrays are values, the rest of the array is
+			// still read
+			var redisErr *RedisError
+			if errors.As(err, &redisErr) {
+				element = redisErr
+			} else if err != nil {
+				return nil, err
+			}
+
+			elements[index] = element
+		}
+
+		return elements, nil
+	}
+
+	return nil, fmt.Errorf("redis: malformed reply %q", line)
+}
@@ -0,0 +1,169 @@
+package ibnsina
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the commands used by RedisStore from a MemoryStore.
+func fakeRedis(t *testing.T, password string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	store := NewMemoryStore()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveFakeRedis(conn, store, password)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func serveFakeRedis(conn net.Conn, store *MemoryStore, password string) {
+	defer conn.Close()
+
+	ctx := context.Background()
+	reader := bufio.NewReader(conn)
+	authenticated := password == ""
+
+	for {
+		reply, err := readRedisReply(reader)
+		if err != nil {
+			return
+		}
+
+		var args []string
+		for _, arg := range reply.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		switch {
+		case args[0] == "AUTH":
+			authenticated = args[1] == password
+			if !authenticated {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+
+			fmt.Fprint(conn, "+OK\r\n")
+		case !authenticated:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "GET":
+			value, err := store.Get(ctx, args[1])
+			if err != nil {
+				fmt.Fprint(conn, "$-1\r\n")
+				continue
+			}
+
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+		case args[0] == "SET":
+			var ttl time.Duration
+			if len(args) == 5 && args[3] == "PX" {
+				milliseconds, _ := strconv.Atoi(args[4])
+				ttl = time.Duration(milliseconds) * time.Millisecond
+			}
+
+			store.Set(ctx, args[1], []byte(args[2]), ttl)
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "EVAL" && args[1] == incrScript:
+			milliseconds, _ := strconv.Atoi(args[4])
+
+			count, err := store.Incr(ctx, args[3], time.Duration(milliseconds)*time.Millisecond)
+			if err != nil {
+				fmt.Fprint(conn, "-ERR value is not an integer or out of range\r\n")
+				continue
+			}
+
+			fmt.Fprintf(conn, ":%d\r\n", count)
+		case args[0] == "DEL":
+			store.Delete(ctx, args[1])
+			fmt.Fprint(conn, ":1\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewRedisStore(RedisOptions{Addr: fakeRedis(t, "secret"), Password: "secret"})
+	defer store.Close()
+
+	if err := store.Set(ctx, "greeting", []byte("hello\r\nworld"), time.Minute); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+
+	if value, err := store.Get(ctx, "greeting"); err != nil || string(value) != "hello\r\nworld" {
+		t.Errorf("expected the value back but got %q %v", value, err)
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		if count, err := store.Incr(ctx, "count", time.Minute); err != nil || count != want {
+			t.Errorf("expected count %d but got %d %v", want, count, err)
+		}
+	}
+
+	var redisErr *RedisError
+	if _, err := store.Incr(ctx, "greeting", time.Minute); !errors.As(err, &redisErr) {
+		t.Errorf("expected an error reply, got %v", err)
+	}
+
+	// the connection is still usable after an error reply
+	store.Delete(ctx, "greeting")
+
+	if _, err := store.Get(ctx, "greeting"); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected the key to be deleted, got %v", err)
+	}
+
+	if len(store.idle) != 1 {
+		t.Errorf("expected a single pooled connection but got %d", len(store.idle))
+	}
+}
+
+func TestRedisStoreCancel(t *testing.T) {
+	store := NewRedisStore(RedisOptions{Addr: fakeRedis(t, "")})
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.Get(ctx, "greeting"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the command to be cancelled, got %v", err)
+	}
+
+	if len(store.idle) != 0 {
+		t.Errorf("expected the interrupted connection to be discarded but %d are pooled", len(store.idle))
+	}
+
+	if _, err := store.Get(context.Background(), "greeting"); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected the next command to succeed, got %v", err)
+	}
+}
+
+func TestRedisStoreAuth(t *testing.T) {
+	store := NewRedisStore(RedisOptions{Addr: fakeRedis(t, "secret"), Password: "wrong"})
+	defer store.Close()
+
+	var redisErr *RedisError
+	if _, err := store.Get(context.Background(), "greeting"); !errors.As(err, &redisErr) || redisErr.Message != "WRONGPASS invalid password" {
+		t.Errorf("expected the authentication to fail, got %v", err)
+	}
+}
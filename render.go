@@ -0,0 +1,118 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+// Renderer renders the HTML templates of server-rendered pages.
+type Renderer struct {
+	// Minify minifies rendered pages with MinifyHTML.
+	Minify bool
+
+	// Assets, when set, rewrites the quoted asset paths of rendered pages to
+	// their fingerprinted paths. Templates may also call the asset function,
+	// as in {{asset "/static/app.css"}}.
+	Assets *AssetManifest
+
+	// templates are never executed, so that they can still be cloned for
+	// every locale
+	templates *template.Template
+
+	mu        sync.Mutex
+	localized map[string]*template.Template
+}
+
+// NewRenderer parses the templates of fsys matching patterns, as
+// template.ParseFS does. Templates may call the locale function for the
+// locale of the request, as resolved by Localization, as in
+// <html lang="{{locale}}">.
+func NewRenderer(fsys fs.FS, patterns ...string) (*Renderer, error) {
+	renderer := &Renderer{localized: make(map[string]*template.Template)}
+
+	templates, err := template.New("").Funcs(template.FuncMap{
+		"asset": func(path string) string {
+			if renderer.Assets == nil {
+				return path
+			}
+
+			return renderer.Assets.Path(path)
+		},
+		"locale": func() string {
+			return ""
+		},
+	}).ParseFS(fsys, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer.templates = templates
+
+	return renderer, nil
+}
+
+// Render executes the template name with data and responds with the page.
+// The template is executed before touching the response, so a failure can
+// still be reported with a proper status code.
+func (renderer *Renderer) Render(ctx context.Context, response http.ResponseWriter, status int, name string, data any) error {
+	templates, err := renderer.localizedTemplates(Locale(ctx))
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	if err := templates.ExecuteTemplate(&buffer, name, data); err != nil {
+		return err
+	}
+
+	page := buffer.Bytes()
+
+	if renderer.Assets != nil {
+		page = renderer.Assets.Rewrite(page)
+	}
+
+	if renderer.Minify {
+		page = MinifyHTML(page)
+	}
+
+	response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	response.WriteHeader(status)
+
+	if _, err := response.Write(page); err != nil {
+		failResponse(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+// localizedTemplates returns the templates whose locale function returns
+// locale, cloned on first use. Localization only resolves the supported
+// locales, so there are few of them.
+func (renderer *Renderer) localizedTemplates(locale string) (*template.Template, error) {
+	renderer.mu.Lock()
+	defer renderer.mu.Unlock()
+
+	if templates, ok := renderer.localized[locale]; ok {
+		return templates, nil
+	}
+
+	templates, err := renderer.templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	templates.Funcs(template.FuncMap{
+		"locale": func() string {
+			return locale
+		},
+	})
+
+	renderer.localized[locale] = templates
+
+	return templates, nil
+}
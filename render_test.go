@@ -0,0 +1,118 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderer(t *testing.T) {
+	static := fstest.MapFS{
+		"app.css":      {Data: []byte("body {\n  margin: 0;\n}\n")},
+		"app.js":       {Data: []byte("const help = `\n  // not a comment\n`;\n")},
+		"img/logo.svg": {Data: []byte("<svg/>")},
+	}
+
+	manifest, err := NewAssetManifest(static, AssetOptions{Prefix: "/static", Minify: true})
+	if err != nil {
+		t.Fatalf("NewAssetManifest: %s", err)
+	}
+
+	css := manifest.Path("/static/app.css")
+	if !strings.HasPrefix(css, "/static/app.") || !strings.HasSuffix(css, ".css") || css == "/static/app.css" {
+		t.Fatalf("unexpected fingerprinted path %q", css)
+	}
+
+	templates := fstest.MapFS{
+		"page.html": {Data: []byte(`<html>
+  <head>
+    <link rel="stylesheet" href="/static/app.css">
+  </head>
+  <body>
+    <img src="{{asset "/static/img/logo.svg"}}">
+    <p>{{.}}</p>
+  </body>
+</html>`)},
+	}
+
+	renderer, err := NewRenderer(templates, "*.html")
+	if err != nil {
+		t.Fatalf("NewRenderer: %s", err)
+	}
+
+	renderer.Minify = true
+	renderer.Assets = manifest
+
+	router := NewRouter()
+	router.Handle("/static/...", manifest.ServeAssets, "GET")
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		renderer.Render(ctx, response, http.StatusOK, "page.html", "hello")
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	expected := `<html> <head> <link rel="stylesheet" href="` + css + `"> </head> <body> <img src="` + manifest.Path("/static/img/logo.svg") + `"> <p>hello</p> </body> </html>`
+	if recorder.Body.String() != expected {
+		t.Errorf("expected page\n%s\nbut was\n%s", expected, recorder.Body)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", css, nil))
+
+	if recorder.Body.String() != "body{margin: 0}" || recorder.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("unexpected asset %q %v", recorder.Body, recorder.Header())
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", manifest.Path("/static/app.js"), nil))
+
+	if recorder.Body.String() != string(static["app.js"].Data) {
+		t.Errorf("expected scripts to be served as they are, got %q", recorder.Body)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/static/app.css", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("unexpected asset %d %v", recorder.Code, recorder.Header())
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/static/missing.css", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 but was %d", recorder.Code)
+	}
+}
+
+func TestRendererLocale(t *testing.T) {
+	templates := fstest.MapFS{
+		"page.html": {Data: []byte(`<html lang="{{locale}}"><p>{{.}}</p></html>`)},
+	}
+
+	renderer, err := NewRenderer(templates, "*.html")
+	if err != nil {
+		t.Fatalf("NewRenderer: %s", err)
+	}
+
+	router := NewRouter(Localization(LocaleOptions{Supported: []string{"en", "uz"}, Fallback: "en"}))
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		renderer.Render(ctx, response, http.StatusOK, "page.html", "hello")
+	}, "GET")
+
+	for acceptLanguage, locale := range map[string]string{"uz-UZ": "uz", "fr": "en", "uz": "uz"} {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Accept-Language", acceptLanguage)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if expected := `<html lang="` + locale + `"><p>hello</p></html>`; recorder.Body.String() != expected {
+			t.Errorf("%s: expected %s but was %s", acceptLanguage, expected, recorder.Body)
+		}
+	}
+}
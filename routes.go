@@ -0,0 +1,185 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Route describes the handler registered by a call to Handle. Its methods
+// are safe to call while the router serves requests and return the route,
+// so they chain:
+//
+//	router.Handle("/users", createUser, "POST").Accepts(CreateUser{})
+type Route struct {
+	router   *Router
+	endpoint *endpoint
+}
+
+// routeMeta is the metadata of an endpoint. It is never modified once
+// published: setters store an updated copy, so requests can read the one
+// they started with without locking.
+type routeMeta struct {
+	pattern     string
+	body        reflect.Type
+	headers     http.Header
+	deprecation *deprecation
+	permissions []string
+	consumes    []string
+	params      []paramCheck
+	scratch     *sync.Pool
+}
+
+func (route *Route) update(fn func(meta *routeMeta)) *Route {
+	route.router.mu.Lock()
+	defer route.router.mu.Unlock()
+
+	meta := *route.endpoint.meta
+	fn(&meta)
+	route.endpoint.meta = &meta
+
+	return route
+}
+
+// Accepts declares the type of the request body, given as a value of that
+// type, for BindBody to decode and validate.
+func (route *Route) Accepts(prototype any) *Route {
+	return route.update(func(meta *routeMeta) {
+		meta.body = reflect.TypeOf(prototype)
+	})
+}
+
+// paramCheck is a path param validator declared with Route.Validate.
+type paramCheck struct {
+	name  string
+	check func(value string) bool
+}
+
+// Validate declares that the path param name must pass check, such as
+// IsUUID, for the route to serve a request. Requests with an invalid param
+// are answered as those matching no route, by Router.NotFound behind the
+// middlewares of the router, so handlers can assume well-formed params:
+//
+//	router.Handle("/users/:id", getUser, "GET").Validate("id", ibnsina.IsUUID)
+func (route *Route) Validate(name string, check func(value string) bool) *Route {
+	return route.update(func(meta *routeMeta) {
+		meta.params = append(slices.Clone(meta.params), paramCheck{name: name, check: check})
+	})
+}
+
+// validParams reports whether params pass the checks declared with
+// Route.Validate.
+func (meta *routeMeta) validParams(params map[string]string) bool {
+	for _, param := range meta.params {
+		if !param.check(params[param.name]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RoutePattern returns the pattern of the route serving the request ctx
+// belongs to, such as "/users/:id", or "" when no route matched.
+func RoutePattern(ctx context.Context) string {
+	if values := GetValues(ctx); values != nil && values.meta != nil {
+		return values.meta.pattern
+	}
+
+	return ""
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method      string
+	Pattern     string
+	Version     string
+	Handler     string
+	Middlewares []string
+}
+
+// Routes lists the registered routes, one per method, in matching order,
+// which is the order their patterns were first registered in.
+func (router *Router) Routes() []RouteInfo {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	routes := make([]RouteInfo, 0, len(router.routes))
+
+	for _, route := range router.routes {
+		for _, method := range route.methods {
+			middlewares := route.endpoints[method].middlewares(router)
+
+			names := make([]string, len(middlewares))
+			for i, middleware := range middlewares {
+				names[i] = funcName(middleware)
+			}
+
+			routes = append(routes, RouteInfo{
+				Method:      method,
+				Pattern:     route.pattern.String(),
+				Version:     route.version,
+				Handler:     funcName(route.endpoints[method].handler),
+				Middlewares: names,
+			})
+		}
+	}
+
+	return routes
+}
+
+// Walk calls fn for every registered route, in the order of Routes. The
+// router is not locked while fn runs, so fn may register routes, which are
+// not walked.
+func (router *Router) Walk(fn func(RouteInfo)) {
+	for _, route := range router.Routes() {
+		fn(route)
+	}
+}
+
+// Snapshot renders the route table one route per line, for instance
+//
+//	GET /users/:id [ibnsina.Recover app.Auth]
+//
+// with the version after the pattern for versioned routes, so that tests can
+// compare it with a golden file and catch route changes introduced by
+// accident. The output only depends on the registrations.
+func (router *Router) Snapshot() string {
+	var builder strings.Builder
+
+	for _, route := range router.Routes() {
+		pattern := route.Pattern
+		if route.Version != "" {
+			pattern += " " + route.Version
+		}
+
+		builder.WriteString(route.Method + " " + pattern + " [" + strings.Join(route.Middlewares, " ") + "]\n")
+	}
+
+	return builder.String()
+}
+
+// funcName returns the package qualified name of fn, e.g. "ibnsina.Recover",
+// or "app.main.func1" for function literals.
+func funcName(fn any) string {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return "<nil>"
+	}
+
+	f := runtime.FuncForPC(value.Pointer())
+	if f == nil {
+		return "<unknown>"
+	}
+
+	name := f.Name()
+	if index := strings.LastIndex(name, "/"); index > -1 {
+		name = name[index+1:]
+	}
+
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,76 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testMiddleware(next Handler) Handler {
+	return next
+}
+
+func TestSnapshot(t *testing.T) {
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router := NewRouter(testMiddleware)
+	router.Handle("/users", handler, "GET", "POST")
+	router.Use(func(next Handler) Handler { return next })
+	router.Handle("/users/:id|^[0-9]+$", handler, "DELETE")
+
+	expected := "" +
+		"GET /users [ibnsina.testMiddleware ibnsina.TestSnapshot.func2]\n" +
+		"POST /users [ibnsina.testMiddleware ibnsina.TestSnapshot.func2]\n" +
+		"HEAD /users [ibnsina.testMiddleware ibnsina.TestSnapshot.func2]\n" +
+		"DELETE /users/:id|^[0-9]+$ [ibnsina.testMiddleware ibnsina.TestSnapshot.func2]\n"
+
+	if snapshot := router.Snapshot(); snapshot != expected {
+		t.Errorf("expected snapshot\n%s\nbut was\n%s", expected, snapshot)
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	var pattern string
+
+	router := NewRouter()
+	router.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		pattern = RoutePattern(ctx)
+	}, "GET")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+
+	if pattern != "/users/:id" {
+		t.Errorf("expected pattern /users/:id but was %q", pattern)
+	}
+}
+
+func TestRouteValidate(t *testing.T) {
+	logger := &testLogger{}
+
+	called := false
+
+	router := NewRouter(AccessLog(logger))
+	router.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		called = true
+	}, "GET").Validate("id", IsUUID)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/42", nil))
+
+	if recorder.Code != http.StatusNotFound || called {
+		t.Errorf("expected an invalid id answered with %d before the handler, got %d", http.StatusNotFound, recorder.Code)
+	}
+
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], " 404 ") {
+		t.Errorf("expected the router middlewares to see the 404, got %q", logger.lines)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/5f0c8a2e-3b1d-4c6a-9e7f-2a4b6c8d0e1f", nil))
+
+	if recorder.Code != http.StatusOK || !called {
+		t.Errorf("expected a valid id served by the handler, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package ibnsina
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RouteFormat selects the output of RenderRoutes.
+type RouteFormat int
+
+const (
+	// RoutesText renders an indented tree, one path segment per line:
+	//
+	//	/ [GET HEAD]
+	//	└── users [GET HEAD POST]
+	//	    └── :id [DELETE]
+	RoutesText RouteFormat = iota
+
+	// RoutesDOT renders a Graphviz digraph, for instance for
+	// "dot -Tsvg routes.dot > routes.svg".
+	RoutesDOT
+)
+
+type routeNode struct {
+	path     string
+	segment  string
+	methods  []string
+	children []*routeNode
+}
+
+func (node *routeNode) child(segment string) *routeNode {
+	for _, child := range node.children {
+		if child.segment == segment {
+			return child
+		}
+	}
+
+	path := strings.TrimSuffix(node.path, "/") + "/" + segment
+	child := &routeNode{path: path, segment: segment}
+	node.children = append(node.children, child)
+
+	return child
+}
+
+func (node *routeNode) label() string {
+	segment := node.segment
+	if node.path == "/" {
+		segment = "/"
+	}
+
+	if len(node.methods) == 0 {
+		return segment
+	}
+
+	return segment + " [" + strings.Join(node.methods, " ") + "]"
+}
+
+// RenderRoutes writes the routes of router as a tree of path segments, so
+// the API surface of large applications can be reviewed at a glance.
+// Siblings appear in matching order, and the methods of versioned routes
+// are followed by their version, as in "GET@v2".
+func RenderRoutes(w io.Writer, router *Router, format RouteFormat) error {
+	root := &routeNode{path: "/"}
+
+	router.Walk(func(route RouteInfo) {
+		node := root
+
+		if path := strings.TrimPrefix(route.Pattern, "/"); path != "" {
+			for _, segment := range strings.Split(path, "/") {
+				node = node.child(segment)
+			}
+		}
+
+		method := route.Method
+		if route.Version != "" {
+			method += "@" + route.Version
+		}
+
+		node.methods = append(node.methods, method)
+	})
+
+	var builder strings.Builder
+
+	switch format {
+	case RoutesText:
+		builder.WriteString(root.label() + "\n")
+		writeTextTree(&builder, root, "")
+	case RoutesDOT:
+		builder.WriteString("digraph routes {\n\trankdir=LR;\n\tnode [shape=box];\n")
+		writeDOTTree(&builder, root)
+		builder.WriteString("}\n")
+	default:
+		return fmt.Errorf("unknown route format %d", format)
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func writeTextTree(builder *strings.Builder, node *routeNode, indent string) {
+	for index, child := range node.children {
+		branch, next := "├── ", "│   "
+		if index == len(node.children)-1 {
+			branch, next = "└── ", "    "
+		}
+
+		builder.WriteString(indent + branch + child.label() + "\n")
+		writeTextTree(builder, child, indent+next)
+	}
+}
+
+func writeDOTTree(builder *strings.Builder, node *routeNode) {
+	fmt.Fprintf(builder, "\t%q [label=%q];\n", node.path, node.label())
+
+	for _, child := range node.children {
+		writeDOTTree(builder, child)
+		fmt.Fprintf(builder, "\t%q -> %q;\n", node.path, child.path)
+	}
+}
@@ -0,0 +1,64 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRenderRoutes(t *testing.T) {
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
+
+	router := NewRouter()
+	router.Handle("/", handler, "GET")
+	router.Handle("/users", handler, "POST")
+	router.Handle("/users/:id", handler, "DELETE")
+	router.Handle("/health", handler, "GET")
+
+	var text strings.Builder
+	if err := RenderRoutes(&text, router, RoutesText); err != nil {
+		t.Fatalf("RenderRoutes: %s", err)
+	}
+
+	expected := "" +
+		"/ [GET HEAD]\n" +
+		"├── users [POST]\n" +
+		"│   └── :id [DELETE]\n" +
+		"└── health [GET HEAD]\n"
+
+	if text.String() != expected {
+		t.Errorf("expected tree\n%s\nbut was\n%s", expected, text.String())
+	}
+
+	var dot strings.Builder
+	if err := RenderRoutes(&dot, router, RoutesDOT); err != nil {
+		t.Fatalf("RenderRoutes: %s", err)
+	}
+
+	for _, line := range []string{
+		`"/users/:id" [label=":id [DELETE]"];`,
+		`"/users" -> "/users/:id";`,
+		`"/" -> "/health";`,
+	} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("expected DOT output to contain %s but was\n%s", line, dot.String())
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	router := NewRouter()
+	router.Handle("/users", testHandler, "POST")
+
+	var routes []RouteInfo
+	router.Walk(func(route RouteInfo) {
+		routes = append(routes, route)
+	})
+
+	if len(routes) != 1 || routes[0].Handler != "ibnsina.testHandler" {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+}
+
+func testHandler(ctx context.Context, response http.ResponseWriter, request *http.Request) {}
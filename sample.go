@@ -0,0 +1,257 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SamplerOptions configures NewSampler.
+type SamplerOptions struct {
+	// Percent of the requests to sample, from 0 to 100.
+	Percent float64
+
+	// Header names a request header, such as "X-Debug-Sample", sampling the
+	// requests that carry it with the value HeaderSecret whatever Percent,
+	// so that public clients cannot force their capture. Empty disables it.
+	Header string
+
+	// HeaderSecret is the value of Header sampling requests, shared with
+	// operators. It is required with Header.
+	HeaderSecret string
+
+	// Size is how many samples are kept, the oldest being dropped first,
+	// 100 if zero or less.
+	Size int
+
+	// MaxBodyBytes bounds the excerpts of the bodies kept, 4 KiB if zero.
+	MaxBodyBytes int64
+
+	// RedactHeaders and RedactFields are redacted from the samples, as
+	// with RecordOptions.
+	RedactHeaders []string
+	RedactFields  []string
+}
+
+// Sample is a request captured by a Sampler.
+type Sample struct {
+	TraceID string    `json:"trace_id"`
+	Time    time.Time `json:"time"`
+	Route   string    `json:"route,omitempty"`
+
+	// Reason is why the request was sampled: "percent", "header" or
+	// "trace".
+	Reason string `json:"reason"`
+
+	// Request and Response are redacted, their bodies cut to
+	// MaxBodyBytes, the fields of JSON bodies being redacted even when cut.
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+
+	Duration time.Duration `json:"duration"`
+
+	// Stages are the stages of the request, when the router traces them.
+	Stages []Stage `json:"stages,omitempty"`
+}
+
+// Sampler captures a share of the requests in detail, headers, body
+// excerpts and stages, for debugging, keeping the latest ones in memory. Its
+// middleware is SampleRequests.
+type Sampler struct {
+	options SamplerOptions
+	headers map[string]bool
+	fields  map[string]bool
+	partial *regexp.Regexp
+
+	mu      sync.Mutex
+	samples []Sample
+	next    int
+	watched map[string]bool
+}
+
+func NewSampler(options SamplerOptions) *Sampler {
+	if options.Header != "" && options.HeaderSecret == "" {
+		panic("ibnsina: sampling requests by header needs a header secret")
+	}
+
+	if options.Size <= 0 {
+		options.Size = 100
+	}
+
+	if options.MaxBodyBytes == 0 {
+		options.MaxBodyBytes = 4 << 10
+	}
+
+	headers := map[string]bool{"Authorization": true, "Cookie": true, "Set-Cookie": true}
+	for _, name := range options.RedactHeaders {
+		headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	fields := map[string]bool{"password": true, "token": true, "secret": true}
+	for _, name := range options.RedactFields {
+		fields[name] = true
+	}
+
+	// the values of the redacted fields in JSON cut anywhere, the last one
+	// possibly unterminated
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+
+	partial := regexp.MustCompile(`("(?:` + strings.Join(names, "|") + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+	return &Sampler{options: options, headers: headers, fields: fields, partial: partial, watched: make(map[string]bool)}
+}
+
+// Watch samples the requests carrying traceID in their X-Trace-ID header,
+// as propagated by the services in front, until Unwatch.
+func (sampler *Sampler) Watch(traceID string) {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	sampler.watched[traceID] = true
+}
+
+func (sampler *Sampler) Unwatch(traceID string) {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	delete(sampler.watched, traceID)
+}
+
+// Samples returns the samples kept, latest first.
+func (sampler *Sampler) Samples() []Sample {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	samples := make([]Sample, 0, len(sampler.samples))
+
+	for index := range sampler.samples {
+		samples = append(samples, sampler.samples[(sampler.next-1-index+len(sampler.samples))%len(sampler.samples)])
+	}
+
+	return samples
+}
+
+// ServeSamples is a handler serving the samples kept, latest first, as
+// JSON.
+func (sampler *Sampler) ServeSamples(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	WriteJSON(ctx, response, http.StatusOK, sampler.Samples())
+}
+
+func (sampler *Sampler) reason(request *http.Request) string {
+	if sampler.options.Header != "" {
+		if value := request.Header.Get(sampler.options.Header); value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(sampler.options.HeaderSecret)) == 1 {
+			return "header"
+		}
+	}
+
+	if traceID := request.Header.Get(TraceIDHeader); traceID != "" {
+		sampler.mu.Lock()
+		watched := sampler.watched[traceID]
+		sampler.mu.Unlock()
+
+		if watched {
+			return "trace"
+		}
+	}
+
+	if sampler.options.Percent > 0 && rand.Float64()*100 < sampler.options.Percent {
+		return "percent"
+	}
+
+	return ""
+}
+
+func (sampler *Sampler) add(sample Sample) {
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+
+	if len(sampler.samples) < sampler.options.Size {
+		sampler.samples = append(sampler.samples, sample)
+	} else {
+		sampler.samples[sampler.next] = sample
+	}
+
+	sampler.next = (sampler.next + 1) % sampler.options.Size
+}
+
+// excerpt redacts body, marked as cut when truncated.
+func (sampler *Sampler) excerpt(body []byte, truncated bool) string {
+	if truncated {
+		return sampler.partial.ReplaceAllString(string(body), `${1}"`+Redacted+`"`) + "…"
+	}
+
+	return redactBody(body, sampler.fields)
+}
+
+// SampleRequests is a middleware capturing the requests sampler samples,
+// along with their responses. Put it early in the chain so that the stages
+// of the middlewares it wraps are complete. The samples hold request bodies,
+// so serve them to operators only.
+func SampleRequests(sampler *Sampler) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			reason := sampler.reason(request)
+			if reason == "" {
+				next(ctx, response, request)
+				return
+			}
+
+			start := time.Now()
+			limit := sampler.options.MaxBodyBytes
+
+			var requestBody []byte
+			var truncated bool
+
+			if request.Body != nil && request.Body != http.NoBody {
+				requestBody, _ = io.ReadAll(io.LimitReader(request.Body, limit+1))
+				request.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), request.Body), request.Body}
+
+				if int64(len(requestBody)) > limit {
+					requestBody, truncated = requestBody[:limit], true
+				}
+			}
+
+			recorder := &recordingWriter{ResponseWriter: response, limit: limit}
+			next(ctx, recorder, request)
+
+			sample := Sample{
+				Time:   start,
+				Route:  RoutePattern(ctx),
+				Reason: reason,
+				Request: RecordedRequest{
+					Method: request.Method,
+					URI:    redactURI(request.URL),
+					Header: redactHeader(request.Header, sampler.headers),
+					Body:   sampler.excerpt(requestBody, truncated),
+				},
+				Response: RecordedResponse{
+					Status: recorder.status,
+					Header: redactHeader(response.Header(), sampler.headers),
+					Body:   sampler.excerpt(recorder.body.Bytes(), recorder.overflow),
+				},
+				Duration: time.Since(start),
+				Stages:   Stages(ctx),
+			}
+
+			if sample.Response.Status == 0 {
+				sample.Response.Status = http.StatusOK
+			}
+
+			if values := GetValues(ctx); values != nil {
+				sample.TraceID = values.TraceID
+			}
+
+			sampler.add(sample)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ibnsina
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSampleRequests(t *testing.T) {
+	sampler := NewSampler(SamplerOptions{Header: "X-Debug-Sample", HeaderSecret: "s3cret", Size: 2, MaxBodyBytes: 32})
+
+	router := NewRouter(SampleRequests(sampler))
+	router.TraceStages = true
+
+	router.Handle("/login", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		if len(body) == 0 {
+			t.Error("expected the handler to read the body")
+		}
+
+		response.Header().Set("Set-Cookie", "session=42")
+		response.Write([]byte(`{"user":"ada","token":"abc"}`))
+	}, "POST")
+
+	router.Handle("/report", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(strings.Repeat("x", 100)))
+	}, "GET")
+
+	serve := func(method, path, body string, header http.Header) {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		for name, values := range header {
+			request.Header.Set(name, values[0])
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	serve("POST", "/login", `{"user":"ada","password":"hunter2"}`, nil)
+	serve("POST", "/login", `{"user":"ada","password":"hunter2"}`, http.Header{"X-Debug-Sample": {"1"}})
+
+	if samples := sampler.Samples(); len(samples) != 0 {
+		t.Fatalf("expected requests without the header secret not to be sampled, got %+v", samples)
+	}
+
+	serve("POST", "/login", `{"user":"ada","password":"hunter2"}`, http.Header{"X-Debug-Sample": {"s3cret"}, "Authorization": {"Bearer abc"}})
+
+	samples := sampler.Samples()
+	if len(samples) != 1 {
+		t.Fatalf("expected a sample but got %+v", samples)
+	}
+
+	login := samples[0]
+	if login.Reason != "header" || login.Route != "/login" || login.TraceID == "" || login.Response.Status != http.StatusOK || len(login.Stages) == 0 {
+		t.Errorf("unexpected sample %+v", login)
+	}
+
+	if login.Request.Header.Get("Authorization") != Redacted || login.Response.Header.Get("Set-Cookie") != Redacted {
+		t.Errorf("expected the credentials to be redacted, got %v and %v", login.Request.Header, login.Response.Header)
+	}
+
+	if login.Request.Body != `{"user":"ada","password":"[REDACTED]"…` || login.Response.Body != `{"token":"[REDACTED]","user":"ada"}` {
+		t.Errorf("expected the bodies to be redacted, got %s and %s", login.Request.Body, login.Response.Body)
+	}
+
+	sampler.Watch("upstream-trace")
+
+	serve("GET", "/report", "", http.Header{TraceIDHeader: {"upstream-trace"}})
+	serve("GET", "/report", "", http.Header{"X-Debug-Sample": {"s3cret"}})
+
+	samples = sampler.Samples()
+	if len(samples) != 2 || samples[0].Reason != "header" || samples[1].Reason != "trace" {
+		t.Fatalf("expected the latest 2 samples, latest first, but got %+v", samples)
+	}
+
+	if body := samples[0].Response.Body; body != strings.Repeat("x", 32)+"…" {
+		t.Errorf("expected an excerpt of the body but got %q", body)
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	sampler := NewSampler(SamplerOptions{Percent: 100, Size: -1})
+
+	router := NewRouter(SampleRequests(sampler))
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if samples := sampler.Samples(); len(samples) != 1 {
+		t.Errorf("expected a negative size to keep the default but got %d samples", len(samples))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a sampling header without secret to panic")
+		}
+	}()
+
+	NewSampler(SamplerOptions{Header: "X-Debug-Sample"})
+}
@@ -0,0 +1,151 @@
+package ibnsina
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var sanitizers = map[string]func(value string, param string) string{
+	"trim": func(value string, param string) string {
+		return strings.TrimSpace(value)
+	},
+	"lower": func(value string, param string) string {
+		return strings.ToLower(value)
+	},
+	"upper": func(value string, param string) string {
+		return strings.ToUpper(value)
+	},
+	"email": func(value string, param string) string {
+		return NormalizeEmail(value)
+	},
+	"strip_control": func(value string, param string) string {
+		return StripControlChars(value)
+	},
+	"truncate": func(value string, param string) string {
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("ibnsina: invalid truncate parameter %q", param))
+		}
+
+		return TruncateRunes(value, limit)
+	},
+}
+
+// Sanitize rewrites the string fields of the struct pointed to by v according
+// to their `sanitize` tags, e.g. `sanitize:"trim,lower"`, in tag order. It
+// walks nested structs, pointers and slices like Validate does. Available
+// sanitizers are trim, lower, upper, email, strip_control and truncate=N.
+func Sanitize(v any) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return
+	}
+
+	sanitizeValue(value.Elem(), "")
+}
+
+// TrimAll trims the surrounding whitespace of every string field reachable
+// from the struct pointed to by v, whatever its tags.
+func TrimAll(v any) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return
+	}
+
+	sanitizeValue(value.Elem(), "trim")
+}
+
+func NormalizeEmail(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// StripControlChars removes control characters except tabs and newlines.
+func StripControlChars(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return r
+		}
+
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+
+		return r
+	}, value)
+}
+
+func TruncateRunes(value string, maxLimit int) string {
+	if maxLimit < 0 {
+		return ""
+	}
+
+	count := 0
+	for index := range value {
+		if count == maxLimit {
+			return value[:index]
+		}
+
+		count++
+	}
+
+	return value
+}
+
+func sanitizeValue(value reflect.Value, tag string) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			sanitizeValue(value.Elem(), tag)
+		}
+	case reflect.String:
+		if tag != "" && value.CanSet() {
+			value.SetString(sanitizeString(value.String(), tag))
+		}
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			sanitizeValue(value.Index(index), tag)
+		}
+	case reflect.Struct:
+		typ := value.Type()
+
+		for index := 0; index < typ.NumField(); index++ {
+			field := typ.Field(index)
+			if !field.IsExported() {
+				continue
+			}
+
+			fieldTag := field.Tag.Get("sanitize")
+			if tag == "trim" {
+				fieldTag = "trim"
+			}
+
+			if fieldTag == "-" {
+				continue
+			}
+
+			sanitizeValue(value.Field(index), fieldTag)
+		}
+	}
+}
+
+func sanitizeString(value string, tag string) string {
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+
+		sanitizer, exists := sanitizers[name]
+		if !exists {
+			panic(fmt.Sprintf("ibnsina: unknown sanitizer %q", name))
+		}
+
+		value = sanitizer(value, param)
+	}
+
+	return value
+}
@@ -0,0 +1,76 @@
+package ibnsina
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	type profile struct {
+		Email    string   `json:"email" sanitize:"email" validate:"required,email"`
+		Username string   `json:"username" sanitize:"trim,lower,truncate=8"`
+		Bio      *string  `json:"bio" sanitize:"strip_control,trim"`
+		Tags     []string `json:"tags" sanitize:"trim,upper"`
+		Raw      string   `json:"raw"`
+	}
+
+	bio := "  hello\x00 world\n\x1b "
+
+	input := profile{
+		Email:    "  Ibn.Sina@Example.COM ",
+		Username: "  AbuAliIbnSina ",
+		Bio:      &bio,
+		Tags:     []string{" go ", "http"},
+		Raw:      "  untouched  ",
+	}
+
+	validator := Validate(&input)
+	if !validator.Ok() {
+		t.Errorf("unexpected errors %v", validator.FieldErrors)
+	}
+
+	if input.Email != "ibn.sina@example.com" {
+		t.Errorf("email: got %q", input.Email)
+	}
+
+	if input.Username != "abualiib" {
+		t.Errorf("username: got %q", input.Username)
+	}
+
+	if *input.Bio != "hello world" {
+		t.Errorf("bio: got %q", *input.Bio)
+	}
+
+	if input.Tags[0] != "GO" || input.Tags[1] != "HTTP" {
+		t.Errorf("tags: got %q", input.Tags)
+	}
+
+	if input.Raw != "  untouched  " {
+		t.Errorf("raw: got %q", input.Raw)
+	}
+
+	TrimAll(&input)
+
+	if input.Raw != "untouched" {
+		t.Errorf("TrimAll: got %q", input.Raw)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	var tests = []struct {
+		Value    string
+		Limit    int
+		Expected string
+	}{
+		{"héllo", 2, "hé"},
+		{"héllo", 5, "héllo"},
+		{"héllo", 10, "héllo"},
+		{"héllo", 0, ""},
+		{"héllo", -1, ""},
+	}
+
+	for _, test := range tests {
+		if actual := TruncateRunes(test.Value, test.Limit); actual != test.Expected {
+			t.Errorf("TruncateRunes(%q, %d): expected %q but was %q", test.Value, test.Limit, test.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// maxScratch is the capacity beyond which scratch buffers are dropped rather
+// than reused, so that a rare large response does not pin memory.
+const maxScratch = 64 << 10
+
+// defaultScratch serves the requests matching no route.
+var defaultScratch = newScratchPool()
+
+func newScratchPool() *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			return new(bytes.Buffer)
+		},
+	}
+}
+
+// Scratch returns an empty buffer for the request ctx belongs to, taken from
+// a pool of its route, so buffers settle at the sizes the route needs. It is
+// released when the handler returns and must not be used afterwards. Each
+// call returns another buffer. WriteJSON encodes into one.
+func Scratch(ctx context.Context) *bytes.Buffer {
+	values := GetValues(ctx)
+	if values == nil {
+		return new(bytes.Buffer)
+	}
+
+	buffer := values.scratchPool().Get().(*bytes.Buffer)
+	buffer.Reset()
+
+	values.scratch = append(values.scratch, buffer)
+
+	return buffer
+}
+
+func (values *Values) scratchPool() *sync.Pool {
+	if values.meta != nil && values.meta.scratch != nil {
+		return values.meta.scratch
+	}
+
+	return defaultScratch
+}
+
+// release returns the scratch buffers of the request, then the record, to
+// their pools.
+func (values *Values) release() {
+	if len(values.scratch) > 0 {
+		pool := values.scratchPool()
+
+		for index, buffer := range values.scratch {
+			if buffer.Cap() <= maxScratch {
+				pool.Put(buffer)
+			}
+
+			values.scratch[index] = nil
+		}
+
+		values.scratch = values.scratch[:0]
+	}
+
+	valuesPool.Put(values)
+}
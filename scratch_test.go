@@ -0,0 +1,37 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScratch(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		first := Scratch(ctx)
+		first.WriteString("first")
+
+		second := Scratch(ctx)
+		if second == first || second.Len() != 0 {
+			t.Errorf("expected a new empty buffer but got %q", second)
+		}
+
+		WriteJSON(ctx, response, http.StatusOK, map[string]string{"buffer": first.String()})
+	}, "GET")
+
+	for range 3 {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		if recorder.Body.String() != `{"buffer":"first"}`+"\n" {
+			t.Errorf("unexpected body %q", recorder.Body)
+		}
+	}
+
+	if buffer := Scratch(context.Background()); buffer == nil || buffer.Len() != 0 {
+		t.Error("expected a buffer outside of requests")
+	}
+}
@@ -0,0 +1,296 @@
+package ibnsina
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// SessionOptions configures Sessions.
+type SessionOptions struct {
+	// Key encrypts and authenticates the session cookie. It must be kept
+	// secret and should be at least 32 random bytes.
+	Key []byte
+
+	// Cookie names the session cookie, "session" if empty.
+	Cookie string
+
+	// MaxAge is the lifetime of the session cookie, until the browser
+	// closes if zero. Sessions expire MaxAge, or a day if zero, after their
+	// last change, even if the cookie is kept.
+	MaxAge time.Duration
+
+	// Secure restricts the cookie to HTTPS.
+	Secure bool
+
+	// Store, when set, keeps sessions on the server, the cookie holding
+	// only their id, so that they are not limited in size and can be
+	// revoked.
+	Store Store
+}
+
+// sessionTTL is how long stored sessions are kept without MaxAge.
+const sessionTTL = 24 * time.Hour
+
+// session is the state of a client, loaded from its cookie or, with
+// SessionOptions.Store, from the store under its id.
+type session struct {
+	id     string
+	values map[string]string
+	dirty  bool
+
+	// revoked is the id the session had before RenewSession.
+	revoked string
+}
+
+// sealedSession is the content of the session cookie: the values, or the id
+// with SessionOptions.Store, and the time, in Unix seconds, the cookie
+// expires at, so that a copy of it is not accepted forever.
+type sealedSession struct {
+	Values  map[string]string `json:"values,omitempty"`
+	ID      string            `json:"id,omitempty"`
+	Expires int64             `json:"expires"`
+}
+
+// Sessions is a middleware giving every client a small set of string values,
+// read and written with SessionValue and SetSessionValue, kept in an
+// encrypted cookie so no server-side storage is needed, or in
+// SessionOptions.Store. Cookies are limited to about 4 KB, so sessions kept
+// in them should hold ids rather than records.
+//
+// Changes are saved when the response header is written: values set after
+// the handler started writing the body are lost. Cookie sessions cannot be
+// revoked before they expire, a copy of the cookie staying valid even after
+// ClearSession; use a Store for sessions that must be.
+func Sessions(options SessionOptions) Middleware {
+	if len(options.Key) == 0 {
+		panic("ibnsina: a session key is required")
+	}
+
+	if options.Cookie == "" {
+		options.Cookie = "session"
+	}
+
+	sealer, err := newCookieSealer(options.Key)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			values := GetValues(ctx)
+			if values == nil {
+				next(ctx, response, request)
+				return
+			}
+
+			values.session = &session{values: make(map[string]string)}
+
+			if cookie, err := request.Cookie(options.Cookie); err == nil {
+				var sealed sealedSession
+				if sealer.open(options.Cookie, cookie.Value, &sealed) && values.Now.Unix() < sealed.Expires {
+					if options.Store == nil {
+						if sealed.Values != nil {
+							values.session.values = sealed.Values
+						}
+					} else if sealed.ID != "" {
+						values.session.id = sealed.ID
+
+						if err := loadSession(ctx, options.Store, values.session); err != nil && values.Logger != nil {
+							values.Logger.Printf("%s: session: %v", values.TraceID, err)
+						}
+					}
+				}
+			}
+
+			current := values.session
+
+			writer := &sessionWriter{ResponseWriter: response, save: func() {
+				if !current.dirty {
+					return
+				}
+
+				cookie := &http.Cookie{
+					Name:     options.Cookie,
+					Path:     "/",
+					MaxAge:   int(options.MaxAge.Seconds()),
+					HttpOnly: true,
+					Secure:   options.Secure,
+					SameSite: http.SameSiteLaxMode,
+				}
+
+				deleteSession := func(id string) {
+					if options.Store != nil && id != "" {
+						if err := options.Store.Delete(ctx, "session:"+id); err != nil && values.Logger != nil {
+							values.Logger.Printf("%s: session: %v", values.TraceID, err)
+						}
+					}
+				}
+
+				deleteSession(current.revoked)
+
+				if len(current.values) == 0 {
+					cookie.MaxAge = -1
+					deleteSession(current.id)
+				} else {
+					sealed := sealedSession{Values: current.values, Expires: values.Now.Add(sessionLifetime(options)).Unix()}
+
+					if options.Store != nil {
+						if err := storeSession(ctx, options, current); err != nil {
+							if values.Logger != nil {
+								values.Logger.Printf("%s: session: %v", values.TraceID, err)
+							}
+
+							return
+						}
+
+						sealed.Values, sealed.ID = nil, current.id
+					}
+
+					value, err := sealer.seal(options.Cookie, sealed)
+					if err != nil {
+						return
+					}
+
+					cookie.Value = value
+				}
+
+				http.SetCookie(response, cookie)
+			}}
+
+			next(ctx, writer, request)
+			writer.saveOnce()
+		}
+	}
+}
+
+// loadSession reads the values of session from store. Sessions that expired
+// or failed to load start over, without id.
+func loadSession(ctx context.Context, store Store, session *session) error {
+	data, err := store.Get(ctx, "session:"+session.id)
+	if err == nil {
+		err = json.Unmarshal(data, &session.values)
+	}
+
+	if err != nil {
+		session.id = ""
+		clear(session.values)
+	}
+
+	if errors.Is(err, ErrNotStored) {
+		return nil
+	}
+
+	return err
+}
+
+// storeSession writes the values of session to the store of options, under
+// a new id for new sessions.
+func storeSession(ctx context.Context, options SessionOptions, session *session) error {
+	data, err := json.Marshal(session.values)
+	if err != nil {
+		return err
+	}
+
+	if session.id == "" {
+		session.id = randomToken()
+	}
+
+	return options.Store.Set(ctx, "session:"+session.id, data, sessionLifetime(options))
+}
+
+// sessionLifetime is how long sessions last after their last change.
+func sessionLifetime(options SessionOptions) time.Duration {
+	if options.MaxAge > 0 {
+		return options.MaxAge
+	}
+
+	return sessionTTL
+}
+
+// SessionValue returns the value of key in the session of the client, "" if
+// it is not set or there is no session.
+func SessionValue(ctx context.Context, key string) string {
+	if values := GetValues(ctx); values != nil && values.session != nil {
+		return values.session.values[key]
+	}
+
+	return ""
+}
+
+// SetSessionValue sets key to value in the session of the client. It does
+// nothing without the Sessions middleware.
+func SetSessionValue(ctx context.Context, key string, value string) {
+	if values := GetValues(ctx); values != nil && values.session != nil {
+		values.session.values[key] = value
+		values.session.dirty = true
+	}
+}
+
+// DeleteSessionValue removes key from the session of the client.
+func DeleteSessionValue(ctx context.Context, key string) {
+	if values := GetValues(ctx); values != nil && values.session != nil {
+		if _, ok := values.session.values[key]; ok {
+			delete(values.session.values, key)
+			values.session.dirty = true
+		}
+	}
+}
+
+// RenewSession gives the session of the client a new id, keeping its values,
+// and revokes the old one. Call it whenever the privileges of the client
+// change, as on sign in, so that a session id planted by an attacker
+// beforehand does not gain them.
+func RenewSession(ctx context.Context) {
+	if values := GetValues(ctx); values != nil && values.session != nil {
+		if values.session.id != "" {
+			values.session.revoked, values.session.id = values.session.id, ""
+		}
+
+		values.session.dirty = true
+	}
+}
+
+// ClearSession removes all values from the session of the client, as on
+// sign out, which deletes its cookie.
+func ClearSession(ctx context.Context) {
+	if values := GetValues(ctx); values != nil && values.session != nil {
+		clear(values.session.values)
+		values.session.dirty = true
+	}
+}
+
+// sessionWriter saves the session before the response header is written.
+type sessionWriter struct {
+	http.ResponseWriter
+	save  func()
+	saved bool
+}
+
+func (writer *sessionWriter) saveOnce() {
+	if !writer.saved {
+		writer.saved = true
+		writer.save()
+	}
+}
+
+func (writer *sessionWriter) WriteHeader(status int) {
+	writer.saveOnce()
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *sessionWriter) Write(data []byte) (int, error) {
+	writer.saveOnce()
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *sessionWriter) Flush() {
+	writer.saveOnce()
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *sessionWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
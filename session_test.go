@@ -0,0 +1,213 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionsAndFlash(t *testing.T) {
+	router := NewRouter(Sessions(SessionOptions{Key: []byte("0123456789abcdef0123456789abcdef")}))
+
+	router.Handle("/orders", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		SetSessionValue(ctx, "user", "42")
+		Flash(ctx, "success", "the order was placed")
+		Flash(ctx, "info", "it ships tomorrow")
+		http.Redirect(response, request, "/", http.StatusSeeOther)
+	}, "POST")
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		for _, flash := range Flashes(ctx) {
+			response.Write([]byte(flash.Kind + ": " + flash.Message + "\n"))
+		}
+
+		response.Write([]byte("user " + SessionValue(ctx, "user")))
+	}, "GET")
+
+	router.Handle("/logout", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		ClearSession(ctx)
+	}, "POST")
+
+	serve := func(method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	posted := serve("POST", "/orders", nil)
+	cookies := posted.Result().Cookies()
+
+	if len(cookies) != 1 || cookies[0].Name != "session" || !cookies[0].HttpOnly {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	shown := serve("GET", "/", cookies)
+	if shown.Body.String() != "success: the order was placed\ninfo: it ships tomorrow\nuser 42" {
+		t.Errorf("unexpected page %q", shown.Body)
+	}
+
+	cookies = shown.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected the session saved without the flashes but got %v", cookies)
+	}
+
+	if page := serve("GET", "/", cookies); page.Body.String() != "user 42" || len(page.Result().Cookies()) != 0 {
+		t.Errorf("expected the flashes shown once, but got %q %v", page.Body, page.Result().Cookies())
+	}
+
+	if cleared := serve("POST", "/logout", cookies).Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge != -1 {
+		t.Errorf("expected the session cookie deleted but got %v", cleared)
+	}
+
+	forged := []*http.Cookie{{Name: "session", Value: "eyJ1c2VyIjoiMSJ9"}}
+	if page := serve("GET", "/", forged); page.Body.String() != "user " {
+		t.Errorf("expected a forged session ignored but got %q", page.Body)
+	}
+}
+
+func TestSessionsStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	router := NewRouter(Sessions(SessionOptions{Key: []byte("0123456789abcdef0123456789abcdef"), Store: store}))
+
+	router.Handle("/login", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		SetSessionValue(ctx, "user", "42")
+		SetSessionValue(ctx, "cart", strings.Repeat("item,", 2000))
+	}, "POST")
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("user " + SessionValue(ctx, "user")))
+	}, "GET")
+
+	router.Handle("/logout", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		ClearSession(ctx)
+	}, "POST")
+
+	serve := func(method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	cookies := serve("POST", "/login", nil).Result().Cookies()
+	if len(cookies) != 1 || len(cookies[0].Value) > 200 || len(store.entries) != 1 {
+		t.Fatalf("expected the cookie to hold the session id alone, got %v and %d stored", cookies, len(store.entries))
+	}
+
+	if page := serve("GET", "/", cookies); page.Body.String() != "user 42" {
+		t.Errorf("expected the session loaded from the store but got %q", page.Body)
+	}
+
+	serve("POST", "/logout", cookies)
+
+	if len(store.entries) != 0 {
+		t.Errorf("expected the stored session deleted, %d left", len(store.entries))
+	}
+
+	// a revoked session starts over, even with its cookie
+	if page := serve("GET", "/", cookies); page.Body.String() != "user " {
+		t.Errorf("expected the revoked session to be empty but got %q", page.Body)
+	}
+}
+
+func TestSessionsExpire(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	router := NewRouter(Sessions(SessionOptions{Key: []byte("0123456789abcdef0123456789abcdef"), MaxAge: time.Hour}))
+	router.Clock = clock
+
+	router.Handle("/login", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		SetSessionValue(ctx, "user", "42")
+	}, "POST")
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("user " + SessionValue(ctx, "user")))
+	}, "GET")
+
+	serve := func(method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	cookies := serve("POST", "/login", nil).Result().Cookies()
+
+	clock.now = clock.now.Add(59 * time.Minute)
+
+	if page := serve("GET", "/", cookies); page.Body.String() != "user 42" {
+		t.Errorf("expected the session within its lifetime but got %q", page.Body)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+
+	if page := serve("GET", "/", cookies); page.Body.String() != "user " {
+		t.Errorf("expected a copy of the expired cookie to be rejected but got %q", page.Body)
+	}
+}
+
+func TestRenewSession(t *testing.T) {
+	store := NewMemoryStore()
+
+	router := NewRouter(Sessions(SessionOptions{Key: []byte("0123456789abcdef0123456789abcdef"), Store: store}))
+
+	router.Handle("/cart", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		SetSessionValue(ctx, "cart", "tea")
+	}, "POST")
+
+	router.Handle("/login", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		RenewSession(ctx)
+		SetSessionValue(ctx, "user", "42")
+	}, "POST")
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(SessionValue(ctx, "cart") + " " + SessionValue(ctx, "user")))
+	}, "GET")
+
+	serve := func(method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	planted := serve("POST", "/cart", nil).Result().Cookies()
+	renewed := serve("POST", "/login", planted).Result().Cookies()
+
+	if len(renewed) != 1 || renewed[0].Value == planted[0].Value || len(store.entries) != 1 {
+		t.Fatalf("expected a new session id on login, got %v and %d stored", renewed, len(store.entries))
+	}
+
+	if page := serve("GET", "/", renewed); page.Body.String() != "tea 42" {
+		t.Errorf("expected the values kept under the new id but got %q", page.Body)
+	}
+
+	if page := serve("GET", "/", planted); page.Body.String() != " " {
+		t.Errorf("expected the old id revoked but got %q", page.Body)
+	}
+}
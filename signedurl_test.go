@@ -0,0 +1,61 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLSigner(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	old, err := NewURLSigner([]byte("old"))
+	if err != nil {
+		t.Fatalf("NewURLSigner: %s", err)
+	}
+
+	signer, err := NewURLSignerFromConfig(&Config{m: map[string]string{"url_keys": "new, old"}}, "url_keys")
+	if err != nil {
+		t.Fatalf("NewURLSignerFromConfig: %s", err)
+	}
+
+	router := NewRouter()
+	router.Clock = &testClock{now: now}
+	router.Group(signer.Verified).Handle("/downloads/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+
+	sign := func(signer *URLSigner, path string, expires time.Time) string {
+		signed, err := signer.Sign(path, expires)
+		if err != nil {
+			t.Fatalf("Sign: %s", err)
+		}
+
+		return signed
+	}
+
+	valid := sign(signer, "/downloads/42?file=report.pdf", now.Add(time.Hour))
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{valid, http.StatusOK},
+		{sign(old, "/downloads/42", now.Add(time.Hour)), http.StatusOK},
+		{sign(signer, "/downloads/42", now.Add(-time.Second)), http.StatusGone},
+		{strings.Replace(valid, "/42", "/43", 1), http.StatusForbidden},
+		{strings.Replace(valid, "report.pdf", "secret.pdf", 1), http.StatusForbidden},
+		{valid + "&extra=1", http.StatusForbidden},
+		{"/downloads/42", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d but was %d", test.path, test.status, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowOptions configures SlowRequests.
+type SlowOptions struct {
+	// Logger receives the reports, Values.Logger if nil.
+	Logger Logger
+
+	// Threshold is the latency above which requests are reported, one
+	// second if zero.
+	Threshold time.Duration
+
+	// Thresholds overrides Threshold for the routes with the given patterns,
+	// as returned by RoutePattern. SlowThresholds loads them from a Config.
+	Thresholds map[string]time.Duration
+
+	// Dump adds a dump of all goroutines to the report, taken as the
+	// threshold passes while the request is still being served, which shows
+	// where it is stuck.
+	Dump bool
+}
+
+// SlowRequests is a middleware reporting requests served in more time than
+// their threshold:
+//
+//	4bf92f35 slow request GET /reports/42 (/reports/:id) took 3.2s, threshold 2s
+func SlowRequests(options SlowOptions) Middleware {
+	if options.Threshold == 0 {
+		options.Threshold = time.Second
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			pattern := RoutePattern(ctx)
+
+			threshold, ok := options.Thresholds[pattern]
+			if !ok {
+				threshold = options.Threshold
+			}
+
+			var dump []byte
+			var stop func()
+
+			if options.Dump {
+				done := make(chan struct{})
+
+				timer := time.AfterFunc(threshold, func() {
+					defer close(done)
+
+					buffer := make([]byte, 1<<20)
+					dump = buffer[:runtime.Stack(buffer, true)]
+				})
+
+				stop = func() {
+					if !timer.Stop() {
+						<-done
+					}
+				}
+			}
+
+			start := time.Now()
+
+			next(ctx, response, request)
+
+			elapsed := time.Since(start)
+
+			if stop != nil {
+				stop()
+			}
+
+			if elapsed <= threshold {
+				return
+			}
+
+			logger, traceID := options.Logger, ""
+			if values := GetValues(ctx); values != nil {
+				traceID = values.TraceID
+
+				if logger == nil {
+					logger = values.Logger
+				}
+			}
+
+			if logger == nil {
+				return
+			}
+
+			logger.Printf("%s slow request %s %s (%s) took %s, threshold %s", traceID, request.Method, redactURI(request.URL), pattern, elapsed, threshold)
+
+			if len(dump) > 0 {
+				logger.Printf("%s goroutines at %s:\n%s", traceID, threshold, dump)
+			}
+		}
+	}
+}
+
+// SlowThresholds reads per-route thresholds for SlowOptions from the keys
+// of config starting with prefix, followed by the route pattern:
+//
+//	slow./reports/:id=2s
+//	slow./uploads/...=30s
+//
+// Keys whose value is not a duration are skipped.
+func SlowThresholds(config *Config, prefix string) map[string]time.Duration {
+	config.mu.RLock()
+	defer config.mu.RUnlock()
+
+	thresholds := make(map[string]time.Duration)
+
+	for key, value := range config.m {
+		pattern, ok := strings.CutPrefix(key, prefix)
+		if !ok || pattern == "" {
+			continue
+		}
+
+		config.used.Store(key, true)
+
+		if threshold, err := time.ParseDuration(value); err == nil {
+			thresholds[pattern] = threshold
+		}
+	}
+
+	return thresholds
+}
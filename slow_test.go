@@ -0,0 +1,76 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequests(t *testing.T) {
+	config := &Config{m: map[string]string{
+		"slow./reports/:id": "1ms",
+		"slow./broken":      "soon",
+		"port":              "8080",
+	}}
+
+	thresholds := SlowThresholds(config, "slow.")
+	if len(thresholds) != 1 || thresholds["/reports/:id"] != time.Millisecond {
+		t.Fatalf("unexpected thresholds %v", thresholds)
+	}
+
+	logger := &testLogger{}
+
+	router := NewRouter(SlowRequests(SlowOptions{
+		Logger:     logger,
+		Threshold:  time.Hour,
+		Thresholds: thresholds,
+		Dump:       true,
+	}))
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	router.Handle("/reports/:id", handler, "GET")
+	router.Handle("/users/:id", handler, "GET")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+
+	if len(logger.lines) != 0 {
+		t.Fatalf("expected no report but got %q", logger.lines)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/1", nil))
+
+	if len(logger.lines) != 2 {
+		t.Fatalf("expected a report and a dump but got %q", logger.lines)
+	}
+
+	if !strings.Contains(logger.lines[0], "slow request GET /reports/1 (/reports/:id)") {
+		t.Errorf("unexpected report %q", logger.lines[0])
+	}
+
+	if !strings.Contains(logger.lines[1], "goroutine ") {
+		t.Errorf("unexpected dump %q", logger.lines[1])
+	}
+}
+
+func TestSlowRequestsRouterLogger(t *testing.T) {
+	logger := &testLogger{}
+
+	router := NewRouter(SlowRequests(SlowOptions{Threshold: time.Millisecond}))
+	router.Logger = logger
+
+	router.Handle("/reports", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}, "GET")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports", nil))
+
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "slow request GET /reports") {
+		t.Errorf("expected the report logged by the router logger but got %q", logger.lines)
+	}
+}
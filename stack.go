@@ -0,0 +1,138 @@
+package ibnsina
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// StackFromConfig assembles the standard middlewares from the keys of
+// config, outermost first:
+//
+//	log.access=true        AccessLog to log.Default(), on by default
+//	recover=true           Recover, on by default
+//	cors.origins=https://app.example.com,https://admin.example.com
+//	cors.methods=GET,POST  CORS, when origins are set
+//	cors.headers=Content-Type,Authorization
+//	cors.credentials=false
+//	cors.maxage=10m
+//	compress=true          Compress, off by default
+//	compress.minsize=1024
+//	ratelimit.limit=600    RateLimit, when the limit is set
+//	ratelimit.window=1m
+//	ratelimit.plan.pro=6000
+//	redis.addr=redis:6379  counts in RedisStore, in memory if not set
+//	redis.password=secret
+//	redis.db=0
+//
+// The rate limit runs before authentication, so clients are told apart by
+// their address; use RateLimit after authentication to count per principal.
+func StackFromConfig(config *Config) []Middleware {
+	var middlewares []Middleware
+
+	if config.BoolOrDefault("log.access", true) {
+		middlewares = append(middlewares, AccessLog(log.Default()))
+	}
+
+	if config.BoolOrDefault("recover", true) {
+		middlewares = append(middlewares, Recover)
+	}
+
+	for _, middleware := range []Middleware{corsFromConfig(config), compressFromConfig(config), rateLimitFromConfig(config)} {
+		if middleware != nil {
+			middlewares = append(middlewares, middleware)
+		}
+	}
+
+	return middlewares
+}
+
+func corsFromConfig(config *Config) Middleware {
+	origins := configList(config, "cors.origins")
+	if len(origins) == 0 {
+		return nil
+	}
+
+	return CORS(CORSOptions{
+		Origins:     origins,
+		Methods:     configList(config, "cors.methods"),
+		Headers:     configList(config, "cors.headers"),
+		Credentials: config.BoolOrDefault("cors.credentials", false),
+		MaxAge:      config.DurationOrDefault("cors.maxage", 0),
+	})
+}
+
+func compressFromConfig(config *Config) Middleware {
+	if !config.BoolOrDefault("compress", false) {
+		return nil
+	}
+
+	return Compress(CompressOptions{MinSize: config.IntOrDefault("compress.minsize", 0)})
+}
+
+func rateLimitFromConfig(config *Config) Middleware {
+	limit := config.IntOrDefault("ratelimit.limit", 0)
+	if limit <= 0 {
+		return nil
+	}
+
+	var store RateStore
+	if redis := redisFromConfig(config); redis != nil {
+		store = NewRateStore(redis)
+	}
+
+	return RateLimit(RateLimitOptions{
+		Store:  store,
+		Limit:  limit,
+		Window: config.DurationOrDefault("ratelimit.window", time.Minute),
+		Plans:  RatePlans(config, "ratelimit.plan."),
+	})
+}
+
+// configList reads the comma-separated list of key, nil if unset.
+func configList(config *Config, key string) []string {
+	var list []string
+
+	for _, item := range strings.Split(config.StringOrDefault(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// redisFromConfig returns the RedisStore of the redis.* keys of config, nil
+// when redis.addr is not set.
+func redisFromConfig(config *Config) *RedisStore {
+	addr := config.StringOrDefault("redis.addr", "")
+	if addr == "" {
+		return nil
+	}
+
+	return NewRedisStore(RedisOptions{
+		Addr:     addr,
+		Password: config.StringOrDefault("redis.password", ""),
+		DB:       config.IntOrDefault("redis.db", 0),
+	})
+}
+
+// samplerFromConfig returns the sampler configured by the keys sample.percent,
+// sample.header, sample.secret and sample.size, nil if neither of the first
+// two is set. sample.percent is read with Config.Percent, so "0.5%" and
+// "0.005" both sample one request in 200.
+func samplerFromConfig(config *Config) *Sampler {
+	percent := config.PercentOrDefault("sample.percent", 0) * 100
+	header := config.StringOrDefault("sample.header", "")
+
+	if percent <= 0 && header == "" {
+		return nil
+	}
+
+	return NewSampler(SamplerOptions{
+		Percent:      percent,
+		Header:       header,
+		HeaderSecret: config.StringOrDefault("sample.secret", ""),
+		Size:         config.IntOrDefault("sample.size", 0),
+	})
+}
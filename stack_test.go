@@ -0,0 +1,80 @@
+package ibnsina
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStackFromConfig(t *testing.T) {
+	config := &Config{m: map[string]string{
+		"log.access":      "false",
+		"cors.origins":    "https://a.example.com, https://b.example.com",
+		"compress":        "true",
+		"ratelimit.limit": "2",
+	}}
+
+	middlewares := StackFromConfig(config)
+
+	var names []string
+	for _, middleware := range middlewares {
+		names = append(names, funcName(middleware))
+	}
+
+	if len(middlewares) != 4 || names[0] != "ibnsina.Recover" {
+		t.Fatalf("unexpected stack %v", names)
+	}
+
+	router := NewRouter(middlewares...)
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		panic("boom")
+	}, "GET")
+
+	router.Logger = &testLogger{}
+
+	serve := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Origin", "https://b.example.com")
+		request.Header.Set("Accept-Encoding", "gzip")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	recorder := serve()
+
+	if recorder.Code != http.StatusInternalServerError || recorder.Header().Get("Access-Control-Allow-Origin") != "https://b.example.com" || !strings.Contains(recorder.Header().Get("Vary"), "Origin") {
+		t.Errorf("unexpected response %d %v", recorder.Code, recorder.Header())
+	}
+
+	serve()
+
+	if recorder := serve(); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 past the limit but was %d", recorder.Code)
+	}
+
+	if defaults := StackFromConfig(&Config{m: map[string]string{}}); len(defaults) != 2 {
+		t.Errorf("expected the access log and Recover by default but got %d middlewares", len(defaults))
+	}
+}
+
+func TestSamplerFromConfig(t *testing.T) {
+	for value, percent := range map[string]float64{"1": 100, "0.5": 50, "0.005": 0.5, "1%": 1, "0.5%": 0.5} {
+		sampler := samplerFromConfig(&Config{m: map[string]string{"sample.percent": value}})
+		if sampler == nil || math.Abs(sampler.options.Percent-percent) > 1e-9 {
+			t.Errorf("expected sample.percent=%s to sample %v%% of the requests but got %+v", value, percent, sampler)
+		}
+	}
+
+	for _, value := range []string{"0", "50"} {
+		if sampler := samplerFromConfig(&Config{m: map[string]string{"sample.percent": value}}); sampler != nil {
+			t.Errorf("expected no sampler for sample.percent=%s but got %+v", value, sampler)
+		}
+	}
+}
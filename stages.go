@@ -0,0 +1,137 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is the time a request spent in a middleware or handler, excluding
+// the stages it called.
+type Stage struct {
+	// Name is the package qualified name of the function, as in
+	// RouteInfo.Middlewares.
+	Name     string
+	Duration time.Duration
+}
+
+// stage is a Stage being recorded. depth is the number of stages around
+// it.
+type stage struct {
+	name     string
+	depth    int
+	start    time.Time
+	duration time.Duration
+	done     bool
+}
+
+// Stages returns the stages of the request ctx belongs to, outermost first,
+// when the router traces them. The durations of the stages still running
+// are those so far.
+func Stages(ctx context.Context) []Stage {
+	if values := GetValues(ctx); values != nil {
+		return values.stageDurations()
+	}
+
+	return nil
+}
+
+func (values *Values) stageDurations() []Stage {
+	if len(values.stages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	inclusive := func(stage stage) time.Duration {
+		if stage.done {
+			return stage.duration
+		}
+
+		return now.Sub(stage.start)
+	}
+
+	stages := make([]Stage, len(values.stages))
+
+	for index, outer := range values.stages {
+		duration := inclusive(outer)
+
+		for _, inner := range values.stages[index+1:] {
+			if inner.depth <= outer.depth {
+				break
+			}
+
+			if inner.depth == outer.depth+1 {
+				duration -= inclusive(inner)
+			}
+		}
+
+		stages[index] = Stage{Name: outer.name, Duration: duration}
+	}
+
+	return stages
+}
+
+// compose chains middlewares and handler as chain does, recording their
+// stages when the router traces them.
+func (router *Router) compose(middlewares []Middleware, handler Handler) Handler {
+	if !router.TraceStages {
+		return chain(middlewares, handler)
+	}
+
+	return traceChain(middlewares, funcName(handler), handler)
+}
+
+// traceChain chains middlewares and handler, recording their stages, that of
+// handler under name.
+func traceChain(middlewares []Middleware, name string, handler Handler) Handler {
+	handler = traceStage(name, handler)
+
+	for index := len(middlewares) - 1; index > -1; index-- {
+		handler = traceStage(funcName(middlewares[index]), middlewares[index](handler))
+	}
+
+	return handler
+}
+
+func traceStage(name string, handler Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		values := GetValues(ctx)
+		if values == nil {
+			handler(ctx, response, request)
+			return
+		}
+
+		index := len(values.stages)
+		values.stages = append(values.stages, stage{name: name, depth: values.depth, start: time.Now()})
+		values.depth++
+
+		handler(ctx, response, request)
+
+		values.depth--
+		values.stages[index].duration = time.Since(values.stages[index].start)
+		values.stages[index].done = true
+	}
+}
+
+// serverTimingHeader formats the stages recorded so far as a Server-Timing
+// header, in milliseconds.
+func (values *Values) serverTimingHeader() string {
+	var builder strings.Builder
+
+	for _, stage := range values.stageDurations() {
+		if builder.Len() > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("stage;desc=" + strconv.Quote(stage.Name) + ";dur=" + formatMilliseconds(stage.Duration))
+	}
+
+	return builder.String()
+}
+
+func formatMilliseconds(duration time.Duration) string {
+	return strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64)
+}
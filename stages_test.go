@@ -0,0 +1,76 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func slowMiddleware(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		next(ctx, response, request)
+	}
+}
+
+func slowHandler(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	time.Sleep(10 * time.Millisecond)
+	response.Write([]byte("ok"))
+}
+
+func TestStages(t *testing.T) {
+	var stages []Stage
+
+	record := func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			next(ctx, response, request)
+			stages = Stages(ctx)
+		}
+	}
+
+	router := NewRouter(record, slowMiddleware)
+	router.TraceStages = true
+	router.ServerTiming = func(request *http.Request) bool {
+		return request.Header.Get("X-Debug") == "secret"
+	}
+
+	router.Handle("/", slowHandler, "GET")
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Debug", "secret")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if len(stages) != 3 || !strings.HasSuffix(stages[1].Name, "slowMiddleware") || !strings.HasSuffix(stages[2].Name, "slowHandler") {
+		t.Fatalf("unexpected stages %v", stages)
+	}
+
+	if stages[0].Duration >= 10*time.Millisecond || stages[1].Duration < 20*time.Millisecond || stages[2].Duration < 10*time.Millisecond {
+		t.Errorf("expected the stages to exclude each other but were %v", stages)
+	}
+
+	timing := recorder.Header().Get("Server-Timing")
+	if !strings.Contains(timing, `stage;desc="ibnsina.slowMiddleware";dur=`) || !strings.Contains(timing, `stage;desc="ibnsina.slowHandler";dur=`) {
+		t.Errorf("unexpected Server-Timing header %q", timing)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	if timing := recorder.Header().Get("Server-Timing"); timing != "" || len(stages) != 3 {
+		t.Errorf("expected the stages traced but kept from untrusted callers, got %q and %v", timing, stages)
+	}
+
+	router.TraceStages = false
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	if stages != nil || recorder.Header().Get("Server-Timing") != "" {
+		t.Errorf("expected no stages without tracing but got %v", stages)
+	}
+}
@@ -0,0 +1,209 @@
+package ibnsina
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// RouteStats are the statistics of a route, as tracked with
+// Router.RouteStats.
+type RouteStats struct {
+	Pattern string
+	Version string
+
+	// Methods are the methods served by the handler, HEAD included when
+	// implied by GET. Handlers registered for several methods share their
+	// statistics.
+	Methods []string
+
+	// Hits counts the requests served, Errors those answered with a 5xx
+	// status.
+	Hits   uint64
+	Errors uint64
+
+	// P50, P90 and P99 are quantiles of the time handlers took, accurate to
+	// about 10%.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// routeStats tracks the requests of an endpoint, without locking.
+type routeStats struct {
+	hits    atomic.Uint64
+	errors  atomic.Uint64
+	latency latencyHistogram
+}
+
+func (stats *routeStats) observe(status int, elapsed time.Duration) {
+	stats.hits.Add(1)
+
+	if status >= 500 {
+		stats.errors.Add(1)
+	}
+
+	stats.latency.observe(elapsed)
+}
+
+// latencyHistogram counts durations in buckets growing by a factor of
+// 2^(1/latencySteps) from a microsecond, as HDR histograms do, so that
+// quantiles are within latencySteps of the truth whatever their scale.
+type latencyHistogram struct {
+	counts [latencyBuckets]atomic.Uint64
+}
+
+const (
+	latencySteps = 8
+
+	// latencyBuckets goes up to 2^27 microseconds, more than two minutes.
+	latencyBuckets = 1 + 27*latencySteps
+)
+
+func (histogram *latencyHistogram) observe(elapsed time.Duration) {
+	index := 0
+	if elapsed > time.Microsecond {
+		index = min(1+int(math.Log2(float64(elapsed)/float64(time.Microsecond))*latencySteps), latencyBuckets-1)
+	}
+
+	histogram.counts[index].Add(1)
+}
+
+// quantiles returns the upper bounds of the buckets holding the quantiles qs,
+// given in increasing order.
+func (histogram *latencyHistogram) quantiles(qs ...float64) []time.Duration {
+	var counts [latencyBuckets]uint64
+	var total uint64
+
+	for index := range counts {
+		counts[index] = histogram.counts[index].Load()
+		total += counts[index]
+	}
+
+	results := make([]time.Duration, len(qs))
+	if total == 0 {
+		return results
+	}
+
+	var seen uint64
+	next := 0
+
+	for index, count := range counts {
+		seen += count
+
+		for next < len(qs) && float64(seen) >= qs[next]*float64(total) {
+			results[next] = time.Duration(float64(time.Microsecond) * math.Exp2(float64(index)/latencySteps))
+			next++
+		}
+	}
+
+	return results
+}
+
+// observe records a request served by endpoint in its statistics, created
+// on first use.
+func (endpoint *endpoint) observe(status int, elapsed time.Duration) {
+	stats := endpoint.stats.Load()
+	if stats == nil {
+		endpoint.stats.CompareAndSwap(nil, new(routeStats))
+		stats = endpoint.stats.Load()
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	stats.observe(status, elapsed)
+}
+
+// Stats returns the statistics of the routes that served requests since
+// RouteStats was set, most hit first. Statistics are kept by handler, so
+// replacing the handler of a route starts them over.
+func (router *Router) Stats() []RouteStats {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	var all []RouteStats
+	var seen []*endpoint
+
+	for _, route := range router.routes {
+		for _, method := range route.allowed() {
+			endpoint := route.endpoint(method)
+
+			if index := slices.Index(seen, endpoint); index >= 0 {
+				all[index].Methods = append(all[index].Methods, method)
+				continue
+			}
+
+			seen = append(seen, endpoint)
+
+			stats := RouteStats{Pattern: route.pattern.String(), Version: route.version, Methods: []string{method}}
+
+			if tracked := endpoint.stats.Load(); tracked != nil {
+				stats.Hits = tracked.hits.Load()
+				stats.Errors = tracked.errors.Load()
+
+				quantiles := tracked.latency.quantiles(0.5, 0.9, 0.99)
+				stats.P50, stats.P90, stats.P99 = quantiles[0], quantiles[1], quantiles[2]
+			}
+
+			all = append(all, stats)
+		}
+	}
+
+	all = slices.DeleteFunc(all, func(stats RouteStats) bool {
+		return stats.Hits == 0
+	})
+
+	slices.SortStableFunc(all, func(a, b RouteStats) int {
+		return cmp.Compare(b.Hits, a.Hits)
+	})
+
+	return all
+}
+
+// ServeStats is a handler reporting the statistics of the top routes, 20 or
+// the number given by the top query param, as a text table:
+//
+//	HITS  ERRORS  P50    P90    P99     ROUTE
+//	1204  0       2.8ms  5.7ms  11.3ms  GET,HEAD /orders/:id
+func (router *Router) ServeStats(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+	top := 20
+	if n, err := strconv.Atoi(request.URL.Query().Get("top")); err == nil && n > 0 {
+		top = n
+	}
+
+	stats := router.Stats()
+	stats = stats[:min(top, len(stats))]
+
+	response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	writer := tabwriter.NewWriter(response, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "HITS\tERRORS\tP50\tP90\tP99\tROUTE")
+
+	for _, route := range stats {
+		fmt.Fprintf(writer, "%d\t%d\t%s\t%s\t%s\t%s %s\n", route.Hits, route.Errors, roundDuration(route.P50), roundDuration(route.P90), roundDuration(route.P99), strings.Join(route.Methods, ","), route.Pattern)
+	}
+
+	writer.Flush()
+}
+
+// roundDuration keeps three significant digits or so.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,72 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteStats(t *testing.T) {
+	router := NewRouter()
+	router.RouteStats = true
+
+	router.Handle("/orders/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if Param(ctx, "id") == "broken" {
+			response.WriteHeader(http.StatusInternalServerError)
+		}
+	}, "GET")
+
+	router.Handle("/health", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+	router.Handle("/unused", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {}, "GET")
+	router.Handle("/stats", router.ServeStats, "GET")
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	for range 3 {
+		serve("GET", "/orders/1")
+	}
+	serve("HEAD", "/orders/2")
+	serve("GET", "/orders/broken")
+	serve("GET", "/health")
+
+	stats := router.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected the routes that served requests but got %+v", stats)
+	}
+
+	orders := stats[0]
+	if orders.Pattern != "/orders/:id" || orders.Hits != 5 || orders.Errors != 1 || strings.Join(orders.Methods, ",") != "GET,HEAD" {
+		t.Errorf("expected the most hit route first, got %+v", orders)
+	}
+
+	if orders.P50 <= 0 || orders.P50 > orders.P90 || orders.P90 > orders.P99 {
+		t.Errorf("expected ordered latency quantiles but got %+v", orders)
+	}
+
+	report := serve("GET", "/stats?top=1").Body.String()
+	if lines := strings.Split(strings.TrimSpace(report), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "5 ") || !strings.HasSuffix(lines[1], "GET,HEAD /orders/:id") {
+		t.Errorf("expected a report of the top route but got\n%s", report)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var histogram latencyHistogram
+
+	for i := range 1000 {
+		histogram.observe(time.Duration(i+1) * time.Millisecond)
+	}
+
+	quantiles := histogram.quantiles(0.5, 0.9, 0.99)
+	for index, want := range []time.Duration{500 * time.Millisecond, 900 * time.Millisecond, 990 * time.Millisecond} {
+		if got := quantiles[index]; got < want || got > want+want/10 {
+			t.Errorf("expected quantile %d to be about %s but got %s", index, want, got)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package ibnsina
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNotStored is returned by Store.Get for keys that are not set or have
+// expired.
+var ErrNotStored = errors.New("ibnsina: the key is not stored")
+
+// Store keeps the state of stateful middlewares, such as rate limit
+// counters and server-side sessions. Stores backed by a shared database,
+// such as RedisStore, let replicas share that state; MemoryStore keeps it
+// within the process.
+type Store interface {
+	// Get returns the value of key, or ErrNotStored.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set sets key to value, expiring after ttl, never if zero.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Incr increments the integer value of key, created at zero expiring
+	// after ttl when not set, and returns the incremented value. The
+	// expiry of existing keys is left as is.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Delete removes key, if set.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store for a single process.
+type MemoryStore struct {
+	// Clock tells when keys expire, SystemClock if nil.
+	Clock Clock
+
+	// MaxEntries bounds the number of keys, unbounded if zero. Setting a key
+	// beyond it evicts the least recently used one.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   list.List // of *storeEntry, most recently used first
+	evicted int64
+	swept   time.Time
+}
+
+type storeEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (entry *storeEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && !now.Before(entry.expires)
+}
+
+// storeSweep is how often MemoryStore drops the expired keys.
+const storeSweep = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*list.Element)}
+}
+
+func (store *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry := store.lookup(key, clockOrSystem(store.Clock).Now())
+	if entry == nil {
+		return nil, ErrNotStored
+	}
+
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (store *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.sweep()
+
+	store.put(key, append([]byte(nil), value...), expiry(now, ttl))
+
+	return nil
+}
+
+func (store *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.sweep()
+
+	entry := store.lookup(key, now)
+	if entry == nil {
+		entry = store.put(key, []byte("0"), expiry(now, ttl))
+	}
+
+	count, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, errors.New("ibnsina: the value of " + key + " is not an integer")
+	}
+
+	count++
+	entry.value = strconv.AppendInt(entry.value[:0], count, 10)
+
+	return count, nil
+}
+
+func (store *MemoryStore) Delete(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if element, ok := store.entries[key]; ok {
+		store.remove(element)
+	}
+
+	return nil
+}
+
+// Len returns the number of keys, expired ones included until swept.
+func (store *MemoryStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return len(store.entries)
+}
+
+// Evicted returns the number of keys evicted for MaxEntries so far.
+func (store *MemoryStore) Evicted() int64 {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.evicted
+}
+
+// lookup returns the entry of key, marked as used, or nil when it is not
+// set or expired. It must be called with store.mu held.
+func (store *MemoryStore) lookup(key string, now time.Time) *storeEntry {
+	element, ok := store.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := element.Value.(*storeEntry)
+	if entry.expired(now) {
+		store.remove(element)
+		return nil
+	}
+
+	store.order.MoveToFront(element)
+
+	return entry
+}
+
+// put sets the entry of key, evicting the least recently used entries
+// beyond MaxEntries. It must be called with store.mu held.
+func (store *MemoryStore) put(key string, value []byte, expires time.Time) *storeEntry {
+	if store.entries == nil {
+		store.entries = make(map[string]*list.Element)
+	}
+
+	if element, ok := store.entries[key]; ok {
+		store.remove(element)
+	}
+
+	entry := &storeEntry{key: key, value: value, expires: expires}
+	store.entries[key] = store.order.PushFront(entry)
+
+	for store.MaxEntries > 0 && len(store.entries) > store.MaxEntries {
+		store.remove(store.order.Back())
+		store.evicted++
+	}
+
+	return entry
+}
+
+func (store *MemoryStore) remove(element *list.Element) {
+	store.order.Remove(element)
+	delete(store.entries, element.Value.(*storeEntry).key)
+}
+
+// sweep drops the expired keys, once per storeSweep, so that keys set once
+// do not accumulate, and returns the current time. It must be called with
+// store.mu held.
+func (store *MemoryStore) sweep() time.Time {
+	now := clockOrSystem(store.Clock).Now()
+
+	if now.Sub(store.swept) > storeSweep {
+		for _, element := range store.entries {
+			if element.Value.(*storeEntry).expired(now) {
+				store.remove(element)
+			}
+		}
+
+		store.swept = now
+	}
+
+	return now
+}
+
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(ttl)
+}
+
+// NewRateStore returns a RateStore counting requests in store, so that
+// RateLimit enforces its limits across the replicas sharing it.
+func NewRateStore(store Store) RateStore {
+	return storeRateStore{store: store}
+}
+
+type storeRateStore struct {
+	store Store
+}
+
+func (rates storeRateStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int64, error) {
+	start := now.Truncate(window)
+
+	// every window has its own key, expiring with it
+	return rates.store.Incr(ctx, key+":"+strconv.FormatInt(start.UnixMilli(), 10), max(start.Add(window).Sub(now), time.Millisecond))
+}
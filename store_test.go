@@ -0,0 +1,74 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	store := NewMemoryStore()
+	store.Clock = clock
+
+	store.Set(ctx, "greeting", []byte("hello"), time.Minute)
+	store.Set(ctx, "forever", []byte("kept"), 0)
+
+	if value, err := store.Get(ctx, "greeting"); err != nil || string(value) != "hello" {
+		t.Errorf("expected hello but got %q %v", value, err)
+	}
+
+	for range 2 {
+		store.Incr(ctx, "count", time.Minute)
+	}
+
+	clock.now = clock.now.Add(30 * time.Second)
+
+	// incrementing leaves the expiry as is
+	if count, err := store.Incr(ctx, "count", time.Hour); err != nil || count != 3 {
+		t.Errorf("expected count 3 but got %d %v", count, err)
+	}
+
+	if _, err := store.Incr(ctx, "greeting", time.Minute); err == nil {
+		t.Error("expected incrementing a string to fail")
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+
+	if _, err := store.Get(ctx, "greeting"); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected the greeting to expire, got %v", err)
+	}
+
+	if count, _ := store.Incr(ctx, "count", time.Minute); count != 1 {
+		t.Errorf("expected the count to start over once expired, got %d", count)
+	}
+
+	store.Delete(ctx, "forever")
+
+	if _, err := store.Get(ctx, "forever"); !errors.Is(err, ErrNotStored) {
+		t.Errorf("expected the key to be deleted, got %v", err)
+	}
+}
+
+func TestNewRateStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.Clock = fixedClock(now)
+
+	rates := NewRateStore(store)
+
+	for want := int64(1); want <= 3; want++ {
+		if count, err := rates.Increment(ctx, "ratelimit:ip:10.0.0.1", time.Minute, now); err != nil || count != want {
+			t.Fatalf("expected count %d but got %d %v", want, count, err)
+		}
+	}
+
+	if count, _ := rates.Increment(ctx, "ratelimit:ip:10.0.0.1", time.Minute, now.Add(time.Minute)); count != 1 {
+		t.Errorf("expected the next window to start over, got %d", count)
+	}
+}
@@ -0,0 +1,179 @@
+package ibnsina
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stream is a long-lived connection registered with Router.Stream, such as a
+// server-sent event stream or a WebSocket.
+type Stream struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     func() bool
+	goaway   chan struct{}
+	once     sync.Once
+	registry *streamRegistry
+}
+
+// Stream registers the long-lived connection of the request ctx belongs to
+// with the router, until Close is called, so that Run can end it gracefully:
+//
+//	stream := router.Stream(ctx)
+//	defer stream.Close()
+//
+//	for {
+//		select {
+//		case event := <-events:
+//			fmt.Fprintf(response, "data: %s\n\n", event)
+//			flusher.Flush()
+//		case <-stream.GoAway():
+//			fmt.Fprint(response, "event: close\ndata: \n\n")
+//			return
+//		case <-stream.Context().Done():
+//			return
+//		}
+//	}
+//
+// Once Run starts shutting down, GoAway is closed for the handler to send a
+// close or goaway event and return. Connections still open after
+// StreamGrace are then force-closed by cancelling Context.
+//
+// Context is not subject to the deadline of Timeout, which would end streams
+// with the other requests, only to the cancellation of the request.
+func (router *Router) Stream(ctx context.Context) *Stream {
+	stop := func() bool { return false }
+
+	var cancel context.CancelFunc
+
+	if values := GetValues(ctx); values != nil && values.untimed != nil {
+		// keep the values of ctx but the cancellation of the untimed context
+		untimed := values.untimed
+		ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+		stop = context.AfterFunc(untimed, cancel)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	stream := &Stream{ctx: ctx, cancel: cancel, stop: stop, goaway: make(chan struct{}), registry: &router.streams}
+	stream.registry.add(stream)
+
+	return stream
+}
+
+// Context returns a copy of the request context cancelled when the
+// connection must be closed.
+func (stream *Stream) Context() context.Context {
+	return stream.ctx
+}
+
+// GoAway returns a channel closed when the server starts shutting down.
+func (stream *Stream) GoAway() <-chan struct{} {
+	return stream.goaway
+}
+
+// Close unregisters the connection. It may be called more than once.
+func (stream *Stream) Close() {
+	stream.registry.remove(stream)
+	stream.stop()
+	stream.cancel()
+}
+
+func (stream *Stream) notify() {
+	stream.once.Do(func() {
+		close(stream.goaway)
+	})
+}
+
+// streamRegistry tracks the open streams of a router. Its zero value is
+// ready to use.
+type streamRegistry struct {
+	mu       sync.Mutex
+	streams  map[*Stream]struct{}
+	draining bool
+	closed   bool
+
+	// idle is closed once the last stream is removed while draining.
+	idle chan struct{}
+}
+
+func (registry *streamRegistry) add(stream *Stream) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if registry.closed {
+		stream.notify()
+		stream.cancel()
+		return
+	}
+
+	if registry.draining {
+		stream.notify()
+	}
+
+	if registry.streams == nil {
+		registry.streams = make(map[*Stream]struct{})
+	}
+
+	registry.streams[stream] = struct{}{}
+}
+
+func (registry *streamRegistry) remove(stream *Stream) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	delete(registry.streams, stream)
+
+	if registry.idle != nil && len(registry.streams) == 0 {
+		close(registry.idle)
+		registry.idle = nil
+	}
+}
+
+// drain tells the open streams to go away and waits for them to close, at
+// most grace, before force-closing those left. Streams registered in the
+// meantime are told to go away as soon as they are.
+func (registry *streamRegistry) drain(grace time.Duration) {
+	registry.mu.Lock()
+
+	registry.draining = true
+
+	idle := make(chan struct{})
+	if len(registry.streams) == 0 {
+		close(idle)
+	} else {
+		registry.idle = idle
+	}
+
+	for stream := range registry.streams {
+		stream.notify()
+	}
+
+	registry.mu.Unlock()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.closed = true
+
+	for stream := range registry.streams {
+		stream.cancel()
+	}
+}
+
+func (router *Router) streamGrace() time.Duration {
+	if router.StreamGrace > 0 {
+		return router.StreamGrace
+	}
+
+	return 5 * time.Second
+}
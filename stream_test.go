@@ -0,0 +1,137 @@
+package ibnsina
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamDrain(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/events", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		stream := router.Stream(ctx)
+		defer stream.Close()
+
+		response.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(response, "event: open\n")
+		response.(http.Flusher).Flush()
+
+		select {
+		case <-stream.GoAway():
+			fmt.Fprint(response, "event: close\n")
+		case <-stream.Context().Done():
+		}
+	}, "GET")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	events, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	defer events.Body.Close()
+
+	reader := bufio.NewReader(events.Body)
+	if line, _ := reader.ReadString('\n'); line != "event: open\n" {
+		t.Fatalf("unexpected event %q", line)
+	}
+
+	start := time.Now()
+	router.streams.drain(time.Minute)
+
+	if line, _ := reader.ReadString('\n'); line != "event: close\n" {
+		t.Errorf("expected a close event but got %q", line)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected drain to return once the stream closed but it took %s", elapsed)
+	}
+
+	late := router.Stream(context.Background())
+	defer late.Close()
+
+	select {
+	case <-late.GoAway():
+	default:
+		t.Errorf("expected a stream opened after draining to be told to go away")
+	}
+}
+
+func TestStreamForceClose(t *testing.T) {
+	router := NewRouter()
+
+	opened := make(chan struct{})
+	closed := make(chan error, 1)
+
+	router.Handle("/events", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		stream := router.Stream(ctx)
+		defer stream.Close()
+
+		close(opened)
+
+		// ignores GoAway
+		<-stream.Context().Done()
+		closed <- stream.Context().Err()
+	}, "GET")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	go http.Get(server.URL + "/events")
+	<-opened
+
+	router.streams.drain(10 * time.Millisecond)
+
+	select {
+	case err := <-closed:
+		if err != context.Canceled {
+			t.Errorf("expected the stream to be cancelled but was %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to be force-closed after the grace period")
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	router := NewRouter(Timeout(10 * time.Millisecond))
+
+	closed := make(chan error, 1)
+
+	router.Handle("/events", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		stream := router.Stream(ctx)
+		defer stream.Close()
+
+		if GetValues(stream.Context()) == nil {
+			t.Error("expected the stream context to keep the values of the request")
+		}
+
+		select {
+		case <-stream.Context().Done():
+			closed <- stream.Context().Err()
+		case <-time.After(50 * time.Millisecond):
+			closed <- nil
+		}
+	}, "GET")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil).WithContext(ctx))
+
+	if err := <-closed; err != nil {
+		t.Errorf("expected the stream to outlive the request timeout but it ended with %v", err)
+	}
+
+	go router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil).WithContext(ctx))
+	cancel()
+
+	if err := <-closed; err != context.Canceled {
+		t.Errorf("expected the stream to end with the request but got %v", err)
+	}
+}
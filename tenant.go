@@ -0,0 +1,136 @@
+package ibnsina
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnknownTenant is returned by TenantResolver implementations for ids
+	// that name no tenant, which Tenants answers with 404.
+	ErrUnknownTenant = &StatusError{Status: http.StatusNotFound, Message: "the requested tenant could not be found"}
+
+	// ErrTenantRequired is the error RequireTenant answers requests without a
+	// tenant with.
+	ErrTenantRequired = &StatusError{Status: http.StatusBadRequest, Message: "the request must be made on behalf of a tenant"}
+)
+
+// TenantResolver validates the tenant ids found in requests.
+type TenantResolver interface {
+	// ResolveTenant returns the canonical id of the tenant id names, or
+	// ErrUnknownTenant.
+	ResolveTenant(ctx context.Context, id string) (string, error)
+}
+
+// TenantOptions configures Tenants. Sources left empty are not looked at;
+// the first one present in the request wins, in the order of the fields.
+type TenantOptions struct {
+	// Resolver validates the tenant ids. It is required.
+	Resolver TenantResolver
+
+	// Param is the route param holding the tenant, for routes prefixed by
+	// it, as in "/:tenant/orders".
+	Param string
+
+	// Header is the request header holding the tenant, e.g. "X-Tenant".
+	// Responses vary on it, so that caches keep them apart.
+	Header string
+
+	// Domain takes the tenant from the subdomain of requests to it, so
+	// "acme.example.com" is tenant "acme" for the domain "example.com".
+	Domain string
+}
+
+// Tenants is a middleware resolving the tenant of requests, which handlers
+// read with Tenant. Requests naming an unknown tenant are answered with
+// ErrUnknownTenant, those naming none are served without tenant; use
+// RequireTenant on the groups that need one. The tenant is added to the
+// access log.
+func Tenants(options TenantOptions) Middleware {
+	if options.Resolver == nil {
+		panic("ibnsina: a tenant resolver is required")
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if options.Header != "" {
+				response.Header().Add("Vary", options.Header)
+			}
+
+			id := requestTenant(ctx, request, options)
+			if id == "" {
+				next(ctx, response, request)
+				return
+			}
+
+			tenant, err := options.Resolver.ResolveTenant(ctx, id)
+			if err != nil {
+				writeError(ctx, response, err)
+				return
+			}
+
+			if values := GetValues(ctx); values != nil {
+				values.tenant = tenant
+			}
+
+			Annotate(ctx, "tenant", tenant)
+
+			next(ctx, response, request)
+		}
+	}
+}
+
+func requestTenant(ctx context.Context, request *http.Request, options TenantOptions) string {
+	if options.Param != "" {
+		if id := Param(ctx, options.Param); id != "" {
+			return id
+		}
+	}
+
+	if options.Header != "" {
+		if id := request.Header.Get(options.Header); id != "" {
+			return id
+		}
+	}
+
+	if options.Domain != "" {
+		host := request.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+
+		if subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(options.Domain)); ok && !strings.Contains(subdomain, ".") {
+			return subdomain
+		}
+	}
+
+	return ""
+}
+
+// Tenant returns the tenant of the request ctx belongs to, as resolved by
+// Tenants, or "" for requests without one.
+func Tenant(ctx context.Context) string {
+	if values := GetValues(ctx); values != nil {
+		return values.tenant
+	}
+
+	return ""
+}
+
+// RequireTenant is a middleware answering requests without a tenant with
+// ErrTenantRequired, for groups that only make sense for a tenant:
+//
+//	router.Use(ibnsina.Tenants(options))
+//	orders := router.Group(ibnsina.RequireTenant)
+func RequireTenant(next Handler) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		if Tenant(ctx) == "" {
+			writeError(ctx, response, ErrTenantRequired)
+			return
+		}
+
+		next(ctx, response, request)
+	}
+}
@@ -0,0 +1,84 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testTenants map[string]string
+
+func (tenants testTenants) ResolveTenant(ctx context.Context, id string) (string, error) {
+	tenant, ok := tenants[strings.ToLower(id)]
+	if !ok {
+		return "", ErrUnknownTenant
+	}
+
+	return tenant, nil
+}
+
+func TestTenants(t *testing.T) {
+	router := NewRouter(Tenants(TenantOptions{
+		Resolver: testTenants{"acme": "acme", "globex": "globex"},
+		Param:    "tenant",
+		Header:   "X-Tenant",
+		Domain:   "example.com",
+	}))
+
+	handler := func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(Tenant(ctx)))
+	}
+
+	router.Handle("/health", handler, "GET")
+	router.Group(RequireTenant).Handle("/orders", handler, "GET")
+	router.Handle("/t/:tenant/orders", handler, "GET")
+
+	tests := []struct {
+		path   string
+		host   string
+		header string
+		status int
+		body   string
+	}{
+		{"/health", "example.com", "", http.StatusOK, ""},
+		{"/orders", "example.com", "", http.StatusBadRequest, ""},
+		{"/orders", "acme.example.com", "", http.StatusOK, "acme"},
+		{"/orders", "ACME.example.com:8080", "", http.StatusOK, "acme"},
+		{"/orders", "a.b.example.com", "", http.StatusBadRequest, ""},
+		{"/orders", "acme.example.com", "Globex", http.StatusOK, "globex"},
+		{"/orders", "initech.example.com", "", http.StatusNotFound, ""},
+		{"/t/globex/orders", "acme.example.com", "", http.StatusOK, "globex"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", test.path, nil)
+		request.Host = test.host
+
+		if test.header != "" {
+			request.Header.Set("X-Tenant", test.header)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s %s: expected status %d but was %d", test.host, test.path, test.status, recorder.Code)
+		}
+
+		if test.status == http.StatusOK && recorder.Body.String() != test.body {
+			t.Errorf("%s %s: expected tenant %q but was %q", test.host, test.path, test.body, recorder.Body.String())
+		}
+	}
+}
+
+func TestTenantsResolverRequired(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Tenants to panic without a resolver")
+		}
+	}()
+
+	Tenants(TenantOptions{Header: "X-Tenant"})
+}
@@ -0,0 +1,32 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout is a middleware giving handlers at most duration, after which the
+// request context is cancelled with context.DeadlineExceeded. Handlers
+// stop when they pass the context to what they call, such as database
+// queries, and watch it in their own loops. A duration of zero or less sets
+// no timeout. The contexts of the streams registered with Router.Stream are
+// not subject to the timeout.
+func Timeout(duration time.Duration) Middleware {
+	return func(next Handler) Handler {
+		if duration <= 0 {
+			return next
+		}
+
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			if values := GetValues(ctx); values != nil && values.untimed == nil {
+				values.untimed = ctx
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, duration)
+			defer cancel()
+
+			next(ctx, response, request.WithContext(ctx))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ibnsina
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timings accumulates the durations of named spans of work done for a
+// request, sent in a Server-Timing header when the response header is
+// written:
+//
+//	span := ibnsina.Timing(ctx).Start("db")
+//	rows, err := db.QueryContext(ctx, query)
+//	span.Stop()
+//
+// Spans of the same name add up, so "db" covers every query. Spans still
+// running, or stopped after the response header was written, are not
+// reported. It is safe for concurrent use.
+type Timings struct {
+	mu        sync.Mutex
+	names     []string
+	durations map[string]time.Duration
+}
+
+// TimingSpan is a span started with Timings.Start.
+type TimingSpan struct {
+	timings *Timings
+	name    string
+	start   time.Time
+	once    sync.Once
+}
+
+// Timing returns the Timings of the request ctx belongs to. Outside of a
+// request, it returns Timings that are not reported.
+func Timing(ctx context.Context) *Timings {
+	values := GetValues(ctx)
+	if values == nil {
+		return &Timings{}
+	}
+
+	// handlers may call Timing from several goroutines at once
+	if timings, ok := values.timings.Load().(*Timings); ok {
+		return timings
+	}
+
+	values.timings.CompareAndSwap(nil, &Timings{})
+
+	return values.timings.Load().(*Timings)
+}
+
+// Start starts a span called name, which must be a token such as "db" or
+// "cache".
+func (timings *Timings) Start(name string) *TimingSpan {
+	return &TimingSpan{timings: timings, name: name, start: time.Now()}
+}
+
+// Stop ends the span, adding its duration to its name. Only the first call
+// counts.
+func (span *TimingSpan) Stop() {
+	span.once.Do(func() {
+		span.timings.add(span.name, time.Since(span.start))
+	})
+}
+
+func (timings *Timings) add(name string, duration time.Duration) {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+
+	if timings.durations == nil {
+		timings.durations = make(map[string]time.Duration)
+	}
+
+	if _, ok := timings.durations[name]; !ok {
+		timings.names = append(timings.names, name)
+	}
+
+	timings.durations[name] += duration
+}
+
+// header formats the spans stopped so far as a Server-Timing header, in
+// milliseconds, in the order they were first stopped.
+func (timings *Timings) header() string {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+
+	var builder strings.Builder
+
+	for _, name := range timings.names {
+		if builder.Len() > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString(name + ";dur=" + formatMilliseconds(timings.durations[name]))
+	}
+
+	return builder.String()
+}
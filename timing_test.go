@@ -0,0 +1,75 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	router := NewRouter()
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		for range 2 {
+			span := Timing(ctx).Start("db")
+			time.Sleep(5 * time.Millisecond)
+			span.Stop()
+			span.Stop()
+		}
+
+		Timing(ctx).Start("cache").Stop()
+		Timing(ctx).Start("running")
+
+		response.Write([]byte("ok"))
+
+		Timing(ctx).Start("late").Stop()
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	timing := recorder.Header().Get("Server-Timing")
+
+	matches := regexp.MustCompile(`^db;dur=(\d+\.\d{3}), cache;dur=\d+\.\d{3}$`).FindStringSubmatch(timing)
+	if matches == nil {
+		t.Fatalf("unexpected Server-Timing header %q", timing)
+	}
+
+	if db, _ := strconv.ParseFloat(matches[1], 64); db < 10 {
+		t.Errorf("expected the db spans to add up to 10ms or more but was %s", matches[1])
+	}
+
+	// outside of a request the spans go nowhere
+	Timing(context.Background()).Start("db").Stop()
+}
+
+func TestTimingConcurrent(t *testing.T) {
+	const queries = 8
+
+	router := NewRouter()
+
+	router.Handle("/", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		var wg sync.WaitGroup
+		for index := range queries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				Timing(ctx).Start("query" + strconv.Itoa(index)).Stop()
+			}()
+		}
+		wg.Wait()
+	}, "GET")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	if spans := strings.Count(recorder.Header().Get("Server-Timing"), ";dur="); spans != queries {
+		t.Errorf("expected %d spans but got %q", queries, recorder.Header().Get("Server-Timing"))
+	}
+}
@@ -0,0 +1,118 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// ResponseTransform rewrites a buffered response, returning the status and
+// body to send. header may be modified in place.
+type ResponseTransform func(status int, header http.Header, body []byte) (int, []byte)
+
+// TransformResponse is a middleware buffering responses to pass them through
+// transform before they are sent, for redacting fields, wrapping bodies in
+// an envelope or injecting markup into HTML. Only the first maxBytes of a
+// body are buffered, MaxBodyBytes if zero or less: larger responses, and
+// those flushed by the handler or already content-encoded, are sent as
+// written.
+func TransformResponse(maxBytes int64, transform ResponseTransform) Middleware {
+	if maxBytes <= 0 {
+		maxBytes = MaxBodyBytes
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			buffer := &bufferingWriter{ResponseWriter: response, limit: maxBytes}
+			next(ctx, buffer, request)
+
+			if buffer.passthrough {
+				return
+			}
+
+			status := buffer.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			body := buffer.body.Bytes()
+			header := response.Header()
+
+			if header.Get("Content-Encoding") == "" {
+				status, body = transform(status, header, body)
+				header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+
+			response.WriteHeader(status)
+			response.Write(body)
+		}
+	}
+}
+
+// bufferingWriter holds back the response, up to limit bytes, after which
+// it sends what it holds and passes the rest through.
+type bufferingWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	limit       int64
+	passthrough bool
+}
+
+func (writer *bufferingWriter) WriteHeader(status int) {
+	if writer.passthrough {
+		writer.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if writer.status == 0 {
+		writer.status = status
+	}
+}
+
+func (writer *bufferingWriter) Write(data []byte) (int, error) {
+	if writer.passthrough {
+		return writer.ResponseWriter.Write(data)
+	}
+
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+
+	if int64(writer.body.Len()+len(data)) > writer.limit {
+		if err := writer.release(); err != nil {
+			return 0, err
+		}
+
+		return writer.ResponseWriter.Write(data)
+	}
+
+	return writer.body.Write(data)
+}
+
+// release sends the status and the body held so far, and stops buffering.
+func (writer *bufferingWriter) release() error {
+	writer.passthrough = true
+
+	if writer.status != 0 {
+		writer.ResponseWriter.WriteHeader(writer.status)
+	}
+
+	_, err := writer.ResponseWriter.Write(writer.body.Bytes())
+	writer.body = bytes.Buffer{}
+
+	return err
+}
+
+func (writer *bufferingWriter) Flush() {
+	if !writer.passthrough {
+		writer.release()
+	}
+
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *bufferingWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
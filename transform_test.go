@@ -0,0 +1,60 @@
+package ibnsina
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransformResponse(t *testing.T) {
+	inject := func(status int, header http.Header, body []byte) (int, []byte) {
+		if !strings.HasPrefix(header.Get("Content-Type"), "text/html") {
+			return status, body
+		}
+
+		return status, bytes.Replace(body, []byte("</body>"), []byte("<script src=\"/live.js\"></script></body>"), 1)
+	}
+
+	router := NewRouter(TransformResponse(64, inject))
+
+	router.Handle("/page", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "text/html")
+		response.WriteHeader(http.StatusCreated)
+		response.Write([]byte("<html><body>"))
+		response.Write([]byte("hi</body></html>"))
+	}, "GET")
+
+	router.Handle("/large", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "text/html")
+		response.Write([]byte("<body>" + strings.Repeat("x", 100) + "</body>"))
+	}, "GET")
+
+	router.Handle("/stream", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "text/html")
+		response.Write([]byte("<body>"))
+		response.(http.Flusher).Flush()
+		response.Write([]byte("</body>"))
+	}, "GET")
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/page", http.StatusCreated, `<html><body>hi<script src="/live.js"></script></body></html>`},
+		{"/large", http.StatusOK, "<body>" + strings.Repeat("x", 100) + "</body>"},
+		{"/stream", http.StatusOK, "<body></body>"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if recorder.Code != test.status || recorder.Body.String() != test.body {
+			t.Errorf("%s: expected %d %q but was %d %q", test.path, test.status, test.body, recorder.Code, recorder.Body)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// StatusError is an error answered with its status code and message. Typed
+// handlers return it for failures the client should know about; any other
+// error becomes a 500 Internal Server Error whose details are only logged.
+type StatusError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (err *StatusError) Error() string {
+	if err.Err != nil {
+		return err.Message + ": " + err.Err.Error()
+	}
+
+	return err.Message
+}
+
+func (err *StatusError) Unwrap() error {
+	return err.Err
+}
+
+// Typed adapts fn, a function from a request type I to a response type O,
+// into a Handler. The handler builds I from the request body, with Bind, and
+// from path params and the query string, for fields tagged `param:"name"` or
+// `query:"name"`, which take precedence over the body. It then validates I
+// with Validate, answering with a 422 Unprocessable Entity if needed, calls fn
+// and writes its result with the ResponseEncoder of the request, with the
+// status returned by a StatusCode() int method of O if any, 200 OK otherwise.
+//
+//	router.Handle("/users/:id", ibnsina.Typed(func(ctx context.Context, in GetUser) (User, error) {
+//		return users.Find(ctx, in.ID)
+//	}), "GET")
+func Typed[I any, O any](fn func(ctx context.Context, in I) (O, error)) Handler {
+	return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		var in I
+
+		validator := GetValidator()
+		defer PutValidator(validator)
+
+		if request.ContentLength != 0 {
+			if err := Bind(request, &in, validator); err != nil {
+				status := http.StatusBadRequest
+				switch {
+				case errors.Is(err, ErrUnsupportedContentType):
+					status = http.StatusUnsupportedMediaType
+				case errors.Is(err, ErrBodyTooLarge):
+					status = http.StatusRequestEntityTooLarge
+				}
+
+				writeError(ctx, response, &StatusError{Status: status, Message: "the request body could not be decoded", Err: err})
+				return
+			}
+		}
+
+		bindRequestFields(ctx, request, &in, validator)
+
+		if validator.Ok() {
+			validator.Merge(Validate(&in))
+		}
+
+		if !validator.Ok() {
+			Encoder(ctx).EncodeError(ctx, response, &StatusError{Status: http.StatusUnprocessableEntity, Message: "the request failed validation"}, validator)
+			return
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			writeError(ctx, response, err)
+			return
+		}
+
+		status := http.StatusOK
+		if coder, ok := any(out).(interface{ StatusCode() int }); ok {
+			status = coder.StatusCode()
+		}
+
+		Encoder(ctx).Encode(ctx, response, status, out)
+	}
+}
+
+// bindRequestFields sets the fields of dst tagged with param or query from
+// the path params in ctx and the query string of request.
+func bindRequestFields(ctx context.Context, request *http.Request, dst any, validator *Validator) {
+	value := reflect.ValueOf(dst).Elem()
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	query := request.URL.Query()
+	typ := value.Type()
+
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		var key string
+		var values []string
+
+		if name := field.Tag.Get("param"); name != "" {
+			param, ok := paramValue(ctx, name)
+			if !ok {
+				continue
+			}
+
+			key, values = name, []string{param}
+		} else if name := field.Tag.Get("query"); name != "" {
+			if _, ok := query[name]; !ok {
+				continue
+			}
+
+			key, values = name, query[name]
+		} else {
+			continue
+		}
+
+		if err := setFormValue(value.Field(index), values); err != nil {
+			typ := value.Field(index).Type()
+			if typ.Kind() == reflect.Slice {
+				typ = typ.Elem()
+			}
+
+			validator.AddFieldError(key, CodeInvalidType, "must be a "+jsonTypeName(typ))
+		}
+	}
+}
+
+func paramValue(ctx context.Context, name string) (string, bool) {
+	values := GetValues(ctx)
+	if values == nil {
+		return "", false
+	}
+
+	value, ok := values.params[name]
+	return value, ok
+}
+
+// writeError answers with err, logging errors that are not a *StatusError.
+// Nothing is logged or written once the client has gone away. Once the
+// response was started, err fails it as failResponse does.
+func writeError(ctx context.Context, response http.ResponseWriter, err error) {
+	if Disconnected(ctx) {
+		if values := GetValues(ctx); values != nil {
+			values.Status = StatusClientClosedRequest
+		}
+
+		return
+	}
+
+	if values := GetValues(ctx); values != nil && values.Status != 0 {
+		failResponse(ctx, err)
+		return
+	}
+
+	statusErr := &StatusError{Status: http.StatusInternalServerError, Message: "the server encountered a problem and could not process the request"}
+
+	if !errors.As(err, &statusErr) {
+		if values := GetValues(ctx); values != nil {
+			values.failed(err, nil)
+
+			if values.Logger != nil {
+				values.Logger.Printf("%s: %v", values.TraceID, err)
+			}
+		}
+	}
+
+	Encoder(ctx).EncodeError(ctx, response, statusErr, nil)
+}
@@ -0,0 +1,86 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testGetUser struct {
+	ID      int    `param:"id" validate:"min=1"`
+	Fields  string `query:"fields"`
+	Comment string `json:"comment" validate:"max=5"`
+}
+
+type testUser struct {
+	ID     int    `json:"id"`
+	Fields string `json:"fields"`
+}
+
+type testCreated struct {
+	ID int `json:"id"`
+}
+
+func (testCreated) StatusCode() int {
+	return http.StatusCreated
+}
+
+func TestTyped(t *testing.T) {
+	router := NewRouter()
+	router.Logger = nil
+
+	router.Handle("/users/:id", Typed(func(ctx context.Context, in testGetUser) (testUser, error) {
+		switch in.ID {
+		case 404:
+			return testUser{}, &StatusError{Status: http.StatusNotFound, Message: "user not found"}
+		case 500:
+			return testUser{}, errors.New("connection refused")
+		}
+
+		return testUser{ID: in.ID, Fields: in.Fields}, nil
+	}), http.MethodGet, http.MethodPost)
+
+	router.Handle("/users", Typed(func(ctx context.Context, in struct{}) (testCreated, error) {
+		return testCreated{ID: 7}, nil
+	}), http.MethodPost)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+
+		expectedStatus int
+		expectedBody   string
+	}{
+		{http.MethodGet, "/users/42?fields=name", "", http.StatusOK, `{"id":42,"fields":"name"}`},
+		{http.MethodPost, "/users/42", `{"comment": "hi", "id": 1}`, http.StatusOK, `{"id":42,"fields":""}`},
+		{http.MethodPost, "/users/42", `{"comment": "too long"}`, http.StatusUnprocessableEntity, `"comment"`},
+		{http.MethodGet, "/users/abc", "", http.StatusUnprocessableEntity, `"invalid_type"`},
+		{http.MethodGet, "/users/0", "", http.StatusUnprocessableEntity, `"id"`},
+		{http.MethodGet, "/users/404", "", http.StatusNotFound, `"user not found"`},
+		{http.MethodGet, "/users/500", "", http.StatusInternalServerError, `"the server encountered a problem`},
+		{http.MethodPost, "/users", "{}", http.StatusCreated, `{"id":7}`},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.body == "" {
+			request = httptest.NewRequest(test.method, test.path, nil)
+		}
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.expectedStatus {
+			t.Errorf("%s %s: expected status %d but was %d: %s", test.method, test.path, test.expectedStatus, response.Code, response.Body)
+			continue
+		}
+
+		if !strings.Contains(response.Body.String(), test.expectedBody) {
+			t.Errorf("%s %s: expected body containing %q but was %q", test.method, test.path, test.expectedBody, response.Body.String())
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentRange is a parsed Content-Range header, "bytes 0-1023/4096". Last
+// is inclusive. Size is -1 when the complete length is unknown, "*".
+type ContentRange struct {
+	First int64
+	Last  int64
+	Size  int64
+}
+
+// Length is the number of bytes in the range.
+func (contentRange ContentRange) Length() int64 {
+	return contentRange.Last - contentRange.First + 1
+}
+
+// ParseContentRange parses and validates a Content-Range header of a request
+// carrying part of an upload.
+func ParseContentRange(header string) (ContentRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return ContentRange{}, fmt.Errorf("content range %q: unit must be bytes", header)
+	}
+
+	span, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return ContentRange{}, fmt.Errorf("content range %q: missing complete length", header)
+	}
+
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return ContentRange{}, fmt.Errorf("content range %q: invalid range", header)
+	}
+
+	contentRange := ContentRange{Size: -1}
+
+	var err error
+
+	if contentRange.First, err = parseRangeNumber(first); err != nil {
+		return ContentRange{}, fmt.Errorf("content range %q: %w", header, err)
+	}
+
+	if contentRange.Last, err = parseRangeNumber(last); err != nil {
+		return ContentRange{}, fmt.Errorf("content range %q: %w", header, err)
+	}
+
+	if size != "*" {
+		if contentRange.Size, err = parseRangeNumber(size); err != nil {
+			return ContentRange{}, fmt.Errorf("content range %q: %w", header, err)
+		}
+	}
+
+	if contentRange.Last < contentRange.First {
+		return ContentRange{}, fmt.Errorf("content range %q: last byte before first", header)
+	}
+
+	if contentRange.Size != -1 && contentRange.Last >= contentRange.Size {
+		return ContentRange{}, fmt.Errorf("content range %q: range beyond complete length", header)
+	}
+
+	return contentRange, nil
+}
+
+func parseRangeNumber(value string) (int64, error) {
+	// ParseInt would accept a sign
+	if value == "" || strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' }) > -1 {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+var errChunkLength = errors.New("chunk length does not match its content range")
+
+// ErrChunkOffset is returned by ChunkStore.Append for chunks that do not
+// start where the upload ends, such as a chunk sent twice concurrently.
+var ErrChunkOffset = errors.New("chunk does not start at the end of the upload")
+
+// exactReader reads exactly remaining bytes from reader, failing with
+// errChunkLength when it holds fewer or more.
+type exactReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (reader *exactReader) Read(p []byte) (int, error) {
+	if reader.remaining == 0 {
+		var extra [1]byte
+		if n, _ := io.ReadFull(reader.reader, extra[:]); n > 0 {
+			return 0, errChunkLength
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > reader.remaining {
+		p = p[:reader.remaining]
+	}
+
+	n, err := reader.reader.Read(p)
+	reader.remaining -= int64(n)
+
+	if err == io.EOF && reader.remaining > 0 {
+		return n, errChunkLength
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+// ChunkStore assembles uploads sent in chunks.
+type ChunkStore interface {
+	// Received returns the number of bytes of upload stored so far.
+	Received(ctx context.Context, upload string) (int64, error)
+
+	// Append adds data at the end of upload, which must hold offset bytes
+	// exactly or ErrChunkOffset is returned; the check and the write are
+	// atomic. When reading data fails, it must store none of it and return
+	// the error.
+	Append(ctx context.Context, upload string, offset int64, data io.Reader) (int64, error)
+
+	// Complete is called once every byte of upload was received. It fails
+	// when upload does not hold size bytes.
+	Complete(ctx context.Context, upload string, size int64) error
+}
+
+// UploadStatus is the state of an upload after a chunk.
+type UploadStatus struct {
+	Received int64
+	Size     int64
+	Complete bool
+}
+
+// ReceiveChunk stores the body of request, part of upload as described by
+// its Content-Range header, in store. Chunks must come in order: a chunk
+// that does not start where the previous one ended fails with a 409
+// Conflict *StatusError, whose message gives the expected offset so the
+// client can resume, and a body whose length does not match the range
+// fails with 400 Bad Request.
+func ReceiveChunk(request *http.Request, store ChunkStore, upload string) (UploadStatus, error) {
+	contentRange, err := ParseContentRange(request.Header.Get("Content-Range"))
+	if err != nil {
+		return UploadStatus{}, &StatusError{Status: http.StatusBadRequest, Message: "the Content-Range header is invalid", Err: err}
+	}
+
+	ctx := request.Context()
+
+	received, err := store.Received(ctx, upload)
+	if err != nil {
+		return UploadStatus{}, err
+	}
+
+	if contentRange.First != received {
+		return UploadStatus{Received: received, Size: contentRange.Size}, chunkConflict(received)
+	}
+
+	written, err := store.Append(ctx, upload, received, &exactReader{reader: request.Body, remaining: contentRange.Length()})
+	if errors.Is(err, ErrChunkOffset) {
+		// another chunk was stored since Received
+		if received, err = store.Received(ctx, upload); err != nil {
+			return UploadStatus{}, err
+		}
+
+		return UploadStatus{Received: received, Size: contentRange.Size}, chunkConflict(received)
+	}
+
+	if errors.Is(err, errChunkLength) {
+		return UploadStatus{Received: received, Size: contentRange.Size}, &StatusError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("the chunk must be %d bytes long, as its Content-Range says", contentRange.Length()),
+		}
+	}
+
+	if err != nil {
+		return UploadStatus{}, err
+	}
+
+	status := UploadStatus{Received: received + written, Size: contentRange.Size}
+
+	if status.Size != -1 && status.Received == status.Size {
+		if err := store.Complete(ctx, upload, status.Size); err != nil {
+			return status, err
+		}
+
+		status.Complete = true
+	}
+
+	return status, nil
+}
+
+func chunkConflict(received int64) error {
+	return &StatusError{
+		Status:  http.StatusConflict,
+		Message: fmt.Sprintf("the chunk must start at byte %d", received),
+	}
+}
+
+// DirChunkStore stores uploads as files in Dir, named after the upload with
+// a ".part" suffix until complete. Upload names must be file names, not
+// paths. The chunks of an upload are written one at a time within a process,
+// so Dir must not be shared by several.
+type DirChunkStore struct {
+	Dir string
+}
+
+// chunkLocks serialize the writes to upload files, striped by path.
+var chunkLocks [64]sync.Mutex
+
+func chunkLock(path string) *sync.Mutex {
+	hash := fnv.New32a()
+	hash.Write([]byte(path))
+
+	return &chunkLocks[hash.Sum32()%uint32(len(chunkLocks))]
+}
+
+func (store DirChunkStore) path(upload string) (string, error) {
+	if upload == "" || upload != filepath.Base(upload) || upload == "." || upload == ".." {
+		return "", fmt.Errorf("invalid upload name %q", upload)
+	}
+
+	return filepath.Join(store.Dir, upload), nil
+}
+
+func (store DirChunkStore) Received(ctx context.Context, upload string) (int64, error) {
+	path, err := store.path(upload)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path + ".part")
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Append truncates what it wrote when data fails, so a chunk is either
+// stored whole or not at all and the client can send it again.
+func (store DirChunkStore) Append(ctx context.Context, upload string, offset int64, data io.Reader) (int64, error) {
+	path, err := store.path(upload)
+	if err != nil {
+		return 0, err
+	}
+
+	lock := chunkLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	file, err := os.OpenFile(path+".part", os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Size() != offset {
+		return 0, ErrChunkOffset
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(file, data)
+	if err != nil {
+		return 0, errors.Join(err, file.Truncate(offset))
+	}
+
+	return written, nil
+}
+
+func (store DirChunkStore) Complete(ctx context.Context, upload string, size int64) error {
+	path, err := store.path(upload)
+	if err != nil {
+		return err
+	}
+
+	lock := chunkLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	info, err := os.Stat(path + ".part")
+	if err != nil {
+		return err
+	}
+
+	if info.Size() != size {
+		return fmt.Errorf("upload %q holds %d bytes instead of %d", upload, info.Size(), size)
+	}
+
+	return os.Rename(path+".part", path)
+}
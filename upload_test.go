@@ -0,0 +1,127 @@
+package ibnsina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected ContentRange
+		valid    bool
+	}{
+		{"bytes 0-1023/4096", ContentRange{0, 1023, 4096}, true},
+		{"bytes 1024-2047/*", ContentRange{1024, 2047, -1}, true},
+		{"bytes 0-0/1", ContentRange{0, 0, 1}, true},
+		{"bytes 10-5/100", ContentRange{}, false},
+		{"bytes 0-100/100", ContentRange{}, false},
+		{"bytes -1-5/100", ContentRange{}, false},
+		{"bytes +1-5/100", ContentRange{}, false},
+		{"bytes 0-5", ContentRange{}, false},
+		{"items 0-5/10", ContentRange{}, false},
+		{"", ContentRange{}, false},
+	}
+
+	for _, test := range tests {
+		contentRange, err := ParseContentRange(test.header)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: expected valid %t but got error %v", test.header, test.valid, err)
+			continue
+		}
+
+		if contentRange != test.expected {
+			t.Errorf("%q: expected %+v but got %+v", test.header, test.expected, contentRange)
+		}
+	}
+}
+
+func TestReceiveChunk(t *testing.T) {
+	store := DirChunkStore{Dir: t.TempDir()}
+
+	send := func(contentRange string, body string) (UploadStatus, error) {
+		request := httptest.NewRequest(http.MethodPatch, "/uploads/video", strings.NewReader(body))
+		request.Header.Set("Content-Range", contentRange)
+
+		return ReceiveChunk(request, store, "video")
+	}
+
+	var statusErr *StatusError
+
+	if _, err := send("bytes 0-4/10", "hel"); !errors.As(err, &statusErr) || statusErr.Status != http.StatusBadRequest {
+		t.Fatalf("expected a short chunk to fail with 400, got %v", err)
+	}
+
+	if _, err := send("bytes 0-4/10", "hello, world"); !errors.As(err, &statusErr) || statusErr.Status != http.StatusBadRequest {
+		t.Fatalf("expected a long chunk to fail with 400, got %v", err)
+	}
+
+	status, err := send("bytes 0-4/10", "hello")
+	if err != nil || status.Received != 5 || status.Complete {
+		t.Fatalf("expected the first chunk to be stored, got %+v, %v", status, err)
+	}
+
+	if _, err := send("bytes 0-4/10", "hello"); !errors.As(err, &statusErr) || statusErr.Status != http.StatusConflict {
+		t.Fatalf("expected a repeated chunk to conflict, got %v", err)
+	}
+
+	status, err = send("bytes 5-9/10", "world")
+	if err != nil || !status.Complete {
+		t.Fatalf("expected the upload to complete, got %+v, %v", status, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.Dir, "video"))
+	if err != nil || string(data) != "helloworld" {
+		t.Errorf("expected the assembled upload, got %q, %v", data, err)
+	}
+
+	if err := store.Complete(context.Background(), "video", 10); err == nil {
+		t.Error("expected completing an upload twice to fail")
+	}
+
+	if _, err := store.Received(context.Background(), "../video"); err == nil {
+		t.Error("expected upload names with paths to be rejected")
+	}
+}
+
+func TestDirChunkStoreOffset(t *testing.T) {
+	ctx := context.Background()
+	store := DirChunkStore{Dir: t.TempDir()}
+
+	var wg sync.WaitGroup
+	var stored atomic.Int32
+
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := store.Append(ctx, "video", 0, strings.NewReader("hello")); err == nil {
+				stored.Add(1)
+			} else if !errors.Is(err, ErrChunkOffset) {
+				t.Errorf("expected ErrChunkOffset but got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stored.Load() != 1 {
+		t.Errorf("expected a single chunk at offset 0 to be stored, got %d", stored.Load())
+	}
+
+	if err := store.Complete(ctx, "video", 10); err == nil {
+		t.Error("expected an incomplete upload not to complete")
+	}
+
+	if received, _ := store.Received(ctx, "video"); received != 5 {
+		t.Errorf("expected 5 bytes but got %d", received)
+	}
+}
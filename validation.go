@@ -1,8 +1,14 @@
 package ibnsina
 
 import (
+	"encoding/json"
+	"net"
+	"net/url"
 	"regexp"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -10,48 +16,221 @@ var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 	// ComplexPasswordRX = regexp.MustCompile("")
 	// ModeratePasswordRX = regexp.MustCompile("")
-	// URLRX = regexp.MustCompile("^(http|https)://[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}(/[a-zA-Z0-9-._~:?#@!$&'()*+,;=]*)*$")
 	// UsernameRX = regexp.MustCompile("")
 )
 
+// machine-readable error codes, so clients can localize messages themselves
+const (
+	CodeInvalid       = "invalid"
+	CodeRequired      = "required"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeOutOfRange    = "out_of_range"
+	CodeInvalidFormat = "invalid_format"
+	CodeNotAllowed    = "not_allowed"
+	CodeDuplicate     = "duplicate"
+)
+
 type Validator struct {
 	FieldErrors    map[string]string
+	FieldCodes     map[string]string
 	NonFieldErrors []string
+	// FieldWarnings are reported alongside errors but do not affect Ok, e.g.
+	// to announce that a field is deprecated.
+	FieldWarnings map[string]string
+	// FailFast makes the validator ignore every error after the first one.
+	FailFast bool
+	messages map[string]fieldMessage
+	prefix   string
+}
+
+type fieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MarshalJSON renders field errors keyed by field with their code and message,
+// alongside the non-field errors:
+//
+//	{"fields": {"name": {"code": "too_short", "message": "..."}}, "errors": ["..."]}
+func (validator *Validator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(validator.report())
+}
+
+type validationReport struct {
+	Fields   map[string]fieldError `json:"fields"`
+	Errors   []string              `json:"errors"`
+	Warnings map[string]string     `json:"warnings,omitempty"`
+}
+
+func (validator *Validator) report() validationReport {
+	fields := make(map[string]fieldError, len(validator.FieldErrors))
+
+	for key, message := range validator.FieldErrors {
+		code := validator.FieldCodes[key]
+		if code == "" {
+			code = CodeInvalid
+		}
+
+		fields[key] = fieldError{Code: code, Message: message}
+	}
+
+	errors := validator.NonFieldErrors
+	if errors == nil {
+		errors = []string{}
+	}
+
+	return validationReport{Fields: fields, Errors: errors, Warnings: validator.FieldWarnings}
 }
 
 func NewValidator() *Validator {
 	return &Validator{
 		FieldErrors:    make(map[string]string),
+		FieldCodes:     make(map[string]string),
 		NonFieldErrors: make([]string, 0),
+		FieldWarnings:  make(map[string]string),
+		messages:       make(map[string]fieldMessage),
+	}
+}
+
+var validatorPool = sync.Pool{
+	New: func() any {
+		return NewValidator()
+	},
+}
+
+// GetValidator returns an empty validator from a pool. Hand it back with
+// PutValidator once its errors have been written to the response.
+func GetValidator() *Validator {
+	return validatorPool.Get().(*Validator)
+}
+
+// PutValidator resets validator and returns it to the pool. The validator, and
+// the maps and slices it exposes, must not be used afterwards.
+func PutValidator(validator *Validator) {
+	if validator == nil || validator.prefix != "" {
+		return
 	}
+
+	validator.Clear()
+	validator.FailFast = false
+
+	validatorPool.Put(validator)
 }
 
 func (validator *Validator) Ok() bool {
 	return len(validator.FieldErrors) == 0 && len(validator.NonFieldErrors) == 0
 }
 
-// func (validator *Validator) Clear() {
-// 	validator = NewValidator()
-// }
+// Clear resets the validator in place, keeping the allocated map and slice so
+// a validator can be reused across requests.
+func (validator *Validator) Clear() {
+	clear(validator.FieldErrors)
+	clear(validator.FieldCodes)
+	clear(validator.FieldWarnings)
+	clear(validator.messages)
+	validator.NonFieldErrors = validator.NonFieldErrors[:0]
+}
+
+// Merge copies the errors of other into validator. Field errors already present
+// in validator win, as with AddFieldError.
+func (validator *Validator) Merge(other *Validator) {
+	if other == nil {
+		return
+	}
+
+	for key, message := range other.FieldErrors {
+		if fm, exists := other.messages[key]; exists {
+			if _, exists := validator.FieldErrors[validator.prefix+key]; !exists {
+				validator.messages[validator.prefix+key] = fm
+			}
+		}
 
+		validator.AddFieldError(key, other.FieldCodes[key], message)
+	}
+
+	for index := 0; index < len(other.NonFieldErrors); index++ {
+		validator.AddNonFieldError(other.NonFieldErrors[index])
+	}
+
+	for key, message := range other.FieldWarnings {
+		validator.AddFieldWarning(key, message)
+	}
+}
+
+// Check adds a field error for key, with the code "invalid", unless cond
+// holds.
 func (validator *Validator) Check(cond bool, key string, message string) {
+	validator.CheckCode(cond, key, CodeInvalid, message)
+}
+
+// CheckCode is Check with a code of its own, such as CodeTooShort.
+func (validator *Validator) CheckCode(cond bool, key string, code string, message string) {
 	if !cond {
-		validator.AddFieldError(key, message)
+		validator.AddFieldError(key, code, message)
 	}
 }
 
-func (validator *Validator) AddFieldError(key string, message string) {
+func (validator *Validator) AddFieldError(key string, code string, message string) {
+	if validator.stopped() {
+		return
+	}
+
+	key = validator.prefix + key
+
 	if _, exists := validator.FieldErrors[key]; !exists {
 		validator.FieldErrors[key] = message
+		validator.FieldCodes[key] = code
+	}
+}
+
+func (validator *Validator) When(cond bool, fn func(*Validator)) {
+	if cond {
+		fn(validator)
+	}
+}
+
+// Group runs fn with a validator whose field keys are prefixed with prefix and
+// a dot, so nested request objects report errors such as "address.city".
+// Groups can be nested.
+func (validator *Validator) Group(prefix string, fn func(*Validator)) {
+	group := &Validator{
+		FieldErrors:    validator.FieldErrors,
+		FieldCodes:     validator.FieldCodes,
+		NonFieldErrors: validator.NonFieldErrors,
+		FieldWarnings:  validator.FieldWarnings,
+		FailFast:       validator.FailFast,
+		messages:       validator.messages,
+		prefix:         validator.prefix + prefix + ".",
+	}
+
+	fn(group)
+
+	validator.NonFieldErrors = group.NonFieldErrors
+}
+
+func (validator *Validator) AddFieldWarning(key string, message string) {
+	key = validator.prefix + key
+
+	if _, exists := validator.FieldWarnings[key]; !exists {
+		validator.FieldWarnings[key] = message
 	}
 }
 
 func (validator *Validator) AddNonFieldError(message string) {
+	if validator.stopped() {
+		return
+	}
+
 	if !slices.Contains(validator.NonFieldErrors, message) {
 		validator.NonFieldErrors = append(validator.NonFieldErrors, message)
 	}
 }
 
+func (validator *Validator) stopped() bool {
+	return validator.FailFast && !validator.Ok()
+}
+
 func In[T comparable](value T, values []T) bool {
 	return slices.Contains(values, value)
 }
@@ -93,3 +272,108 @@ func Uniques[T comparable](values []T) bool {
 
 	return len(uniques) == len(values)
 }
+
+func IsURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" {
+		return false
+	}
+
+	if u.Opaque != "" {
+		return true
+	}
+
+	return u.Host != "" && IsHost(u.Host)
+}
+
+func IsHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	if u.User != nil || u.Opaque != "" {
+		return false
+	}
+
+	return u.Host != "" && IsHost(u.Host)
+}
+
+// IsHost accepts a hostname or an IP address, optionally followed by a port,
+// as found in the host part of a URL.
+func IsHost(value string) bool {
+	host := value
+
+	if h, port, err := net.SplitHostPort(value); err == nil {
+		if !IsPort(port) {
+			return false
+		}
+
+		host = h
+	} else if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		host = value[1 : len(value)-1]
+	}
+
+	if net.ParseIP(host) != nil {
+		return true
+	}
+
+	return IsHostname(host)
+}
+
+func IsHostname(value string) bool {
+	value = strings.TrimSuffix(value, ".")
+
+	if len(value) == 0 || len(value) > 253 {
+		return false
+	}
+
+	labels := strings.Split(value, ".")
+
+	for index := 0; index < len(labels); index++ {
+		label := labels[index]
+
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	// a hostname made only of digits and dots would be read as an IPv4 address
+	last := labels[len(labels)-1]
+	if _, err := strconv.Atoi(last); err == nil {
+		return false
+	}
+
+	return true
+}
+
+func IsPort(value string) bool {
+	if value == "" || value[0] == '+' || value[0] == '-' {
+		return false
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+
+	return port > 0 && port <= 65535
+}
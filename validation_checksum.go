@@ -0,0 +1,176 @@
+package ibnsina
+
+import (
+	"strings"
+)
+
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BI": 27,
+	"BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28,
+	"EE": 20, "EG": 29, "ES": 24, "FI": 18, "FK": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23,
+	"GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27,
+	"JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "LY": 25,
+	"MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27, "MT": 31, "MU": 30, "NI": 28, "NL": 18,
+	"NO": 15, "OM": 23, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33,
+	"SA": 24, "SC": 31, "SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "SO": 23, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// IsLuhnValid reports whether value, ignoring spaces and dashes, is a digit
+// string passing the Luhn (mod 10) checksum used by payment card numbers.
+func IsLuhnValid(value string) bool {
+	digits := stripSeparators(value)
+
+	if len(digits) < 2 {
+		return false
+	}
+
+	sum := 0
+	double := false
+
+	for index := len(digits) - 1; index > -1; index-- {
+		c := digits[index]
+		if c < '0' || c > '9' {
+			return false
+		}
+
+		digit := int(c - '0')
+
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// IsIBAN checks the country specific length and the ISO 7064 mod 97-10
+// checksum of an international bank account number.
+func IsIBAN(value string) bool {
+	iban := strings.ToUpper(stripSeparators(value))
+
+	if len(iban) < 5 {
+		return false
+	}
+
+	length, known := ibanLengths[iban[:2]]
+	if !known || len(iban) != length {
+		return false
+	}
+
+	if iban[2] < '0' || iban[2] > '9' || iban[3] < '0' || iban[3] > '9' {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+
+	for index := 0; index < len(rearranged); index++ {
+		c := rearranged[index]
+
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+
+	return remainder == 1
+}
+
+// IsISBN accepts both ISBN-10 and ISBN-13 numbers, with or without hyphens.
+func IsISBN(value string) bool {
+	isbn := stripSeparators(value)
+
+	switch len(isbn) {
+	case 10:
+		return isISBN10(isbn)
+	case 13:
+		return isISBN13(isbn)
+	default:
+		return false
+	}
+}
+
+func isISBN10(isbn string) bool {
+	sum := 0
+
+	for index := 0; index < 10; index++ {
+		c := isbn[index]
+
+		var digit int
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int(c - '0')
+		case (c == 'X' || c == 'x') && index == 9:
+			digit = 10
+		default:
+			return false
+		}
+
+		sum += digit * (10 - index)
+	}
+
+	return sum%11 == 0
+}
+
+func isISBN13(isbn string) bool {
+	if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+		return false
+	}
+
+	sum := 0
+
+	for index := 0; index < 13; index++ {
+		c := isbn[index]
+		if c < '0' || c > '9' {
+			return false
+		}
+
+		weight := 1
+		if index%2 == 1 {
+			weight = 3
+		}
+
+		sum += int(c-'0') * weight
+	}
+
+	return sum%10 == 0
+}
+
+func stripSeparators(value string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(value)
+}
+
+// IsUUID accepts UUIDs in their canonical form, such as
+// "5f0c8a2e-3b1d-4c6a-9e7f-2a4b6c8d0e1f", in either case, whatever their
+// version.
+func IsUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+
+	for index, char := range []byte(value) {
+		switch index {
+		case 8, 13, 18, 23:
+			if char != '-' {
+				return false
+			}
+		default:
+			if !('0' <= char && char <= '9' || 'a' <= char && char <= 'f' || 'A' <= char && char <= 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+package ibnsina
+
+import "testing"
+
+func TestChecksumValidators(t *testing.T) {
+	var tests = []struct {
+		Name      string
+		Validator func(string) bool
+		Value     string
+		Expected  bool
+	}{
+		{"IsLuhnValid", IsLuhnValid, "4111 1111 1111 1111", true},
+		{"IsLuhnValid", IsLuhnValid, "4111-1111-1111-1112", false},
+		{"IsLuhnValid", IsLuhnValid, "79927398713", true},
+		{"IsLuhnValid", IsLuhnValid, "7992739871a", false},
+		{"IsLuhnValid", IsLuhnValid, "0", false},
+		{"IsIBAN", IsIBAN, "GB82 WEST 1234 5698 7654 32", true},
+		{"IsIBAN", IsIBAN, "de89370400440532013000", true},
+		{"IsIBAN", IsIBAN, "GB82 WEST 1234 5698 7654 33", false},
+		{"IsIBAN", IsIBAN, "DE8937040044053201300", false},
+		{"IsIBAN", IsIBAN, "ZZ89370400440532013000", false},
+		{"IsISBN", IsISBN, "0-306-40615-2", true},
+		{"IsISBN", IsISBN, "0-8044-2957-X", true},
+		{"IsISBN", IsISBN, "978-0-306-40615-7", true},
+		{"IsISBN", IsISBN, "978-0-306-40615-6", false},
+		{"IsISBN", IsISBN, "0-306-40615-3", false},
+		{"IsISBN", IsISBN, "X-306-40615-2", false},
+		{"IsUUID", IsUUID, "5f0c8a2e-3b1d-4c6a-9e7f-2a4b6c8d0e1f", true},
+		{"IsUUID", IsUUID, "5F0C8A2E-3B1D-4C6A-9E7F-2A4B6C8D0E1F", true},
+		{"IsUUID", IsUUID, "5f0c8a2e3b1d4c6a9e7f2a4b6c8d0e1f", false},
+		{"IsUUID", IsUUID, "5f0c8a2e-3b1d-4c6a-9e7f-2a4b6c8d0e1g", false},
+		{"IsUUID", IsUUID, "{5f0c8a2e-3b1d-4c6a-9e7f-2a4b6c8d0e1}", false},
+	}
+
+	for _, test := range tests {
+		if actual := test.Validator(test.Value); actual != test.Expected {
+			t.Errorf("%s(%q): expected %t but was %t", test.Name, test.Value, test.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package ibnsina
+
+import (
+	_ "embed"
+	"strings"
+)
+
+var (
+	//go:embed codes/iso3166-1.txt
+	iso3166Table string
+	//go:embed codes/iso4217.txt
+	iso4217Table string
+	//go:embed codes/iso639.txt
+	iso639Table string
+	//go:embed codes/iso15924.txt
+	iso15924Table string
+
+	countryCodes  = parseCodeTable(iso3166Table, strings.ToUpper)
+	currencyCodes = parseCodeTable(iso4217Table, strings.ToUpper)
+	languageCodes = parseCodeTable(iso639Table, strings.ToLower)
+	scriptCodes   = parseCodeTable(iso15924Table, strings.ToLower)
+)
+
+// IsISO3166Country accepts upper case ISO 3166-1 alpha-2 codes such as "UZ".
+func IsISO3166Country(value string) bool {
+	return len(value) == 2 && countryCodes[value]
+}
+
+// IsISO4217Currency accepts upper case ISO 4217 codes such as "UZS".
+func IsISO4217Currency(value string) bool {
+	return len(value) == 3 && currencyCodes[value]
+}
+
+// IsBCP47LanguageTag checks value against the RFC 5646 grammar, and checks
+// the language, script and region subtags against the ISO 639, ISO 15924 and
+// ISO 3166-1 tables. Subtags are case insensitive, as the RFC specifies.
+//
+// Languages are the two letter ISO 639-1 and three letter ISO 639-2 codes,
+// plus the qaa to qtz range ISO 639-2 reserves for private use. ISO 639-3
+// codes that are not also ISO 639-2 ones, such as "cmn", are rejected.
+func IsBCP47LanguageTag(value string) bool {
+	if value == "" || len(value) > 255 {
+		return false
+	}
+
+	subtags := strings.Split(strings.ToLower(value), "-")
+
+	if subtags[0] == "x" {
+		return isPrivateUse(subtags)
+	}
+
+	index := 0
+
+	language := subtags[index]
+	switch {
+	case len(language) >= 2 && len(language) <= 3 && isAlpha(language):
+		if !languageCodes[language] && !isPrivateUseLanguage(language) {
+			return false
+		}
+	case len(language) >= 5 && len(language) <= 8 && isAlpha(language):
+		// registered languages longer than three letters are not in ISO 639
+	default:
+		return false
+	}
+	index++
+
+	// up to three extended language subtags
+	for extlangs := 0; extlangs < 3 && index < len(subtags) && len(subtags[index]) == 3 && isAlpha(subtags[index]); extlangs++ {
+		index++
+	}
+
+	if index < len(subtags) && len(subtags[index]) == 4 && isAlpha(subtags[index]) {
+		if !scriptCodes[subtags[index]] {
+			return false
+		}
+		index++
+	}
+
+	if index < len(subtags) {
+		region := subtags[index]
+
+		if len(region) == 2 && isAlpha(region) {
+			if !countryCodes[strings.ToUpper(region)] {
+				return false
+			}
+			index++
+		} else if len(region) == 3 && isDigits(region) {
+			index++
+		}
+	}
+
+	variants := []string{}
+	for index < len(subtags) && isVariant(subtags[index]) {
+		if In(subtags[index], variants) {
+			return false
+		}
+
+		variants = append(variants, subtags[index])
+		index++
+	}
+
+	singletons := []string{}
+	for index < len(subtags) && len(subtags[index]) == 1 && subtags[index] != "x" {
+		singleton := subtags[index]
+		if !isAlphanumeric(singleton) || In(singleton, singletons) {
+			return false
+		}
+
+		singletons = append(singletons, singleton)
+		index++
+
+		start := index
+		for index < len(subtags) && len(subtags[index]) >= 2 && len(subtags[index]) <= 8 && isAlphanumeric(subtags[index]) {
+			index++
+		}
+
+		if index == start {
+			return false
+		}
+	}
+
+	if index < len(subtags) && subtags[index] == "x" {
+		return isPrivateUse(subtags[index:])
+	}
+
+	return index == len(subtags)
+}
+
+func isPrivateUse(subtags []string) bool {
+	if len(subtags) < 2 {
+		return false
+	}
+
+	for _, subtag := range subtags[1:] {
+		if len(subtag) < 1 || len(subtag) > 8 || !isAlphanumeric(subtag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPrivateUseLanguage reports whether language is in the qaa to qtz range.
+func isPrivateUseLanguage(language string) bool {
+	return len(language) == 3 && language[0] == 'q' && language[1] >= 'a' && language[1] <= 't'
+}
+
+func isVariant(subtag string) bool {
+	if !isAlphanumeric(subtag) {
+		return false
+	}
+
+	if len(subtag) >= 5 && len(subtag) <= 8 {
+		return true
+	}
+
+	return len(subtag) == 4 && subtag[0] >= '0' && subtag[0] <= '9'
+}
+
+func isAlpha(value string) bool {
+	for index := 0; index < len(value); index++ {
+		if value[index] < 'a' || value[index] > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigits(value string) bool {
+	for index := 0; index < len(value); index++ {
+		if value[index] < '0' || value[index] > '9' {
+			return false
+		}
+	}
+
+	return value != ""
+}
+
+func isAlphanumeric(value string) bool {
+	for index := 0; index < len(value); index++ {
+		c := value[index]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return value != ""
+}
+
+// parseCodeTable reads whitespace separated codes, skipping # comment lines.
+func parseCodeTable(table string, normalize func(string) string) map[string]bool {
+	codes := make(map[string]bool)
+
+	for _, line := range strings.Split(table, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, code := range strings.Fields(line) {
+			codes[normalize(code)] = true
+		}
+	}
+
+	return codes
+}
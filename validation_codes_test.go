@@ -0,0 +1,53 @@
+package ibnsina
+
+import (
+	"testing"
+)
+
+func TestCodeValidators(t *testing.T) {
+	var tests = []struct {
+		Name      string
+		Validator func(string) bool
+		Value     string
+		Expected  bool
+	}{
+		{"IsISO3166Country", IsISO3166Country, "UZ", true},
+		{"IsISO3166Country", IsISO3166Country, "GB", true},
+		{"IsISO3166Country", IsISO3166Country, "uz", false},
+		{"IsISO3166Country", IsISO3166Country, "UK", false},
+		{"IsISO3166Country", IsISO3166Country, "USA", false},
+		{"IsISO4217Currency", IsISO4217Currency, "UZS", true},
+		{"IsISO4217Currency", IsISO4217Currency, "EUR", true},
+		{"IsISO4217Currency", IsISO4217Currency, "eur", false},
+		{"IsISO4217Currency", IsISO4217Currency, "XYZ", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-US", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "uz-Latn-UZ", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "zh-Hant-TW", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "es-419", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "de-CH-1901", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-US-u-ca-gregory", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-x-private", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "x-whatever", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qq-US", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qaa", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qtz-Latn", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qmx-UZ", true},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qua", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "qza", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "cmn", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-ZZ", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-Abcd", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en_US", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-u", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "de-1901-1901", false},
+		{"IsBCP47LanguageTag", IsBCP47LanguageTag, "en-", false},
+	}
+
+	for _, test := range tests {
+		if actual := test.Validator(test.Value); actual != test.Expected {
+			t.Errorf("%s(%q): expected %t but was %t", test.Name, test.Value, test.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ibnsina
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+func MaxFileSize(header *multipart.FileHeader, maxBytes int64) bool {
+	return header != nil && header.Size <= maxBytes
+}
+
+// AllowedMIME checks the media type of an uploaded file against allowed. The
+// Content-Type sent by the client is trusted unless sniffed is set, in which
+// case the type is detected from the first 512 bytes of the file instead.
+func AllowedMIME(header *multipart.FileHeader, sniffed bool, allowed ...string) bool {
+	if header == nil {
+		return false
+	}
+
+	contentType := header.Header.Get("Content-Type")
+
+	if sniffed {
+		file, err := header.Open()
+		if err != nil {
+			return false
+		}
+		defer file.Close()
+
+		buf := make([]byte, 512)
+
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return false
+		}
+
+		contentType = http.DetectContentType(buf[:n])
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return In(mediaType, allowed)
+}
+
+// ImageDimensions decodes only the header of an uploaded GIF, JPEG or PNG
+// image and checks its width and height are within the given bounds.
+func ImageDimensions(header *multipart.FileHeader, minWidth, minHeight, maxWidth, maxHeight int) bool {
+	if header == nil {
+		return false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return false
+	}
+
+	return config.Width >= minWidth && config.Width <= maxWidth &&
+		config.Height >= minHeight && config.Height <= maxHeight
+}
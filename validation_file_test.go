@@ -0,0 +1,74 @@
+package ibnsina
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"testing"
+)
+
+func testFileHeader(t *testing.T, contentType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("CreatePart: %s", err)
+	}
+
+	part.Write(content)
+	writer.Close()
+
+	request, err := http.NewRequest("POST", "/upload", &body)
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	if err := request.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm: %s", err)
+	}
+
+	return request.MultipartForm.File["file"][0]
+}
+
+func TestFileValidators(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	// the client claims a JPEG but sends a PNG
+	picture := testFileHeader(t, "image/jpeg", img.Bytes())
+	script := testFileHeader(t, "image/png", []byte("#!/bin/sh\nrm -rf /\n"))
+
+	if !MaxFileSize(picture, int64(img.Len())) || MaxFileSize(picture, int64(img.Len()-1)) {
+		t.Errorf("MaxFileSize: unexpected result for %d bytes", img.Len())
+	}
+
+	if !AllowedMIME(picture, false, "image/jpeg") || AllowedMIME(picture, true, "image/jpeg") {
+		t.Errorf("AllowedMIME: sniffing should detect the real type")
+	}
+
+	if !AllowedMIME(script, false, "image/png") || AllowedMIME(script, true, "image/png") {
+		t.Errorf("AllowedMIME: sniffing should reject the script")
+	}
+
+	if !ImageDimensions(picture, 64, 32, 64, 32) || ImageDimensions(picture, 100, 0, 1000, 1000) {
+		t.Errorf("ImageDimensions: unexpected result")
+	}
+
+	if ImageDimensions(script, 0, 0, 1000, 1000) {
+		t.Errorf("ImageDimensions: non images should be rejected")
+	}
+}
@@ -0,0 +1,207 @@
+package ibnsina
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog holds message templates per locale, keyed by message key. Templates
+// reference parameters in braces: "must be at least {min} characters".
+type Catalog struct {
+	messages map[string]map[string]string
+	mu       sync.RWMutex
+}
+
+// DefaultCatalog holds the English messages used by the tag engine. Add other
+// locales to it, or build a separate Catalog, to translate them.
+var DefaultCatalog = NewCatalog()
+
+type fieldMessage struct {
+	key    string
+	params map[string]string
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		messages: make(map[string]map[string]string),
+	}
+}
+
+func (catalog *Catalog) Add(locale string, key string, template string) {
+	locale = normalizeLocale(locale)
+
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+
+	if catalog.messages[locale] == nil {
+		catalog.messages[locale] = make(map[string]string)
+	}
+
+	catalog.messages[locale][key] = template
+}
+
+// Message renders the template for key, falling back from a regional locale
+// such as "uz-Latn-UZ" to its base language "uz".
+func (catalog *Catalog) Message(locale string, key string, params map[string]string) (string, bool) {
+	locale = normalizeLocale(locale)
+
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	for {
+		if template, exists := catalog.messages[locale][key]; exists {
+			return renderMessage(template, params), true
+		}
+
+		index := strings.LastIndex(locale, "-")
+		if index < 0 {
+			return "", false
+		}
+
+		locale = locale[:index]
+	}
+}
+
+// Locales lists the locales the catalog has messages for, sorted.
+func (catalog *Catalog) Locales() []string {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	locales := make([]string, 0, len(catalog.messages))
+	for locale := range catalog.messages {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Negotiate picks the best locale of the catalog for an Accept-Language header
+// value, or fallback when none of the requested languages is available.
+func (catalog *Catalog) Negotiate(acceptLanguage string, fallback string) string {
+	if locale, ok := matchLocale(parseAcceptLanguage(acceptLanguage), catalog.Locales()); ok {
+		return locale
+	}
+
+	return fallback
+}
+
+// matchLocale returns the first of tags in supported, trying the base
+// language of regional tags too.
+func matchLocale(tags []string, supported []string) (string, bool) {
+	for _, tag := range tags {
+		if tag == "*" {
+			break
+		}
+
+		for {
+			if In(tag, supported) {
+				return tag, true
+			}
+
+			index := strings.LastIndex(tag, "-")
+			if index < 0 {
+				break
+			}
+
+			tag = tag[:index]
+		}
+	}
+
+	return "", false
+}
+
+// AddFieldMessage adds a field error whose message is rendered from catalog
+// templates, so it can be translated later with Localize. The message is
+// first rendered in English from DefaultCatalog, falling back to messageKey.
+func (validator *Validator) AddFieldMessage(key string, code string, messageKey string, params map[string]string) {
+	message, ok := DefaultCatalog.Message("en", messageKey, params)
+	if !ok {
+		message = messageKey
+	}
+
+	if _, exists := validator.FieldErrors[validator.prefix+key]; exists || validator.stopped() {
+		return
+	}
+
+	validator.AddFieldError(key, code, message)
+	validator.messages[validator.prefix+key] = fieldMessage{messageKey, params}
+}
+
+// Localize re-renders the messages added with AddFieldMessage in locale.
+// Messages without a translation, and plain messages, are left untouched.
+func (validator *Validator) Localize(catalog *Catalog, locale string) {
+	for key, fm := range validator.messages {
+		if message, ok := catalog.Message(locale, fm.key, fm.params); ok {
+			validator.FieldErrors[key] = message
+		}
+	}
+}
+
+func renderMessage(template string, params map[string]string) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header,
+// normalized and ordered by decreasing quality. Tags with q=0 are dropped.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	tags := []weighted{}
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		tag = normalizeLocale(tag)
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+
+		if name, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+
+			quality = q
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag, quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	result := make([]string, len(tags))
+	for index := range tags {
+		result[index] = tags[index].tag
+	}
+
+	return result
+}
@@ -0,0 +1,62 @@
+package ibnsina
+
+import (
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Add("en", "min.string", "must be at least {min} characters")
+	catalog.Add("uz", "min.string", "kamida {min} ta belgidan iborat bo'lishi kerak")
+	catalog.Add("ru", "required", "обязательное поле")
+
+	type signup struct {
+		Name  string `json:"name" validate:"min=3"`
+		Email string `json:"email" validate:"required"`
+	}
+
+	validator := Validate(signup{Name: "ab"})
+	validator.AddFieldError("terms", CodeRequired, "must be accepted")
+
+	if message := validator.FieldErrors["name"]; message != "must be at least 3 characters" {
+		t.Errorf("name: unexpected default message %q", message)
+	}
+
+	validator.Localize(catalog, "uz-Latn-UZ")
+
+	if message := validator.FieldErrors["name"]; message != "kamida 3 ta belgidan iborat bo'lishi kerak" {
+		t.Errorf("name: unexpected localized message %q", message)
+	}
+
+	if message := validator.FieldErrors["email"]; message != "must be provided" {
+		t.Errorf("email: untranslated message should be kept, got %q", message)
+	}
+
+	if message := validator.FieldErrors["terms"]; message != "must be accepted" {
+		t.Errorf("terms: plain message should be kept, got %q", message)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Add("en", "required", "must be provided")
+	catalog.Add("uz", "required", "majburiy")
+	catalog.Add("ru-RU", "required", "обязательное поле")
+
+	var tests = []struct {
+		AcceptLanguage string
+		Expected       string
+	}{
+		{"uz-UZ,uz;q=0.9,en;q=0.8", "uz"},
+		{"fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5", "en"},
+		{"ru_RU", "ru-ru"},
+		{"en;q=0, de", "fallback"},
+		{"", "fallback"},
+	}
+
+	for _, test := range tests {
+		if actual := catalog.Negotiate(test.AcceptLanguage, "fallback"); actual != test.Expected {
+			t.Errorf("Negotiate(%q): expected %q but was %q", test.AcceptLanguage, test.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ibnsina
+
+import (
+	"net"
+	"net/netip"
+)
+
+// IsIP accepts IPv4 and IPv6 addresses without an IPv6 zone.
+func IsIP(value string) bool {
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Zone() == ""
+}
+
+func IsIPv4(value string) bool {
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Is4()
+}
+
+// IsIPv6 accepts IPv6 addresses, including IPv4-mapped ones such as
+// "::ffff:10.0.0.1", but not plain IPv4 addresses.
+func IsIPv6(value string) bool {
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// IsCIDR accepts a network prefix such as "10.0.0.0/8" or "2001:db8::/32".
+// Host bits may be set, as in "10.1.2.3/8".
+func IsCIDR(value string) bool {
+	_, err := netip.ParsePrefix(value)
+	return err == nil
+}
+
+// IsMAC accepts the hardware address formats understood by net.ParseMAC, such
+// as "00:00:5e:00:53:01", "00-00-5E-00-53-01" and "0000.5e00.5301".
+func IsMAC(value string) bool {
+	_, err := net.ParseMAC(value)
+	return err == nil
+}
@@ -0,0 +1,42 @@
+package ibnsina
+
+import (
+	"strings"
+)
+
+// IsNumericString accepts a non-empty string of ASCII digits, such as account
+// numbers and postal codes where leading zeros matter.
+func IsNumericString(value string) bool {
+	return isDigits(value)
+}
+
+// IsInteger accepts an optionally signed base 10 integer of any size.
+func IsInteger(value string) bool {
+	return isDigits(trimSign(value))
+}
+
+// IsDecimal accepts an optionally signed decimal number such as "-1234.50"
+// with at most maxIntDigits digits before the point and maxFracDigits after
+// it, the way amounts of money are transmitted as strings. Exponents and
+// leading or trailing points are rejected.
+func IsDecimal(value string, maxIntDigits, maxFracDigits int) bool {
+	integer, fraction, hasPoint := strings.Cut(trimSign(value), ".")
+
+	if !isDigits(integer) || len(integer) > maxIntDigits {
+		return false
+	}
+
+	if !hasPoint {
+		return true
+	}
+
+	return isDigits(fraction) && len(fraction) <= maxFracDigits
+}
+
+func trimSign(value string) string {
+	if len(value) > 0 && (value[0] == '-' || value[0] == '+') {
+		return value[1:]
+	}
+
+	return value
+}
@@ -0,0 +1,141 @@
+package ibnsina
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BreachedChecker reports whether a password is known from data breaches.
+type BreachedChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordChecker is consulted by NotBreached. It is nil by default, in which
+// case NotBreached accepts every password.
+var PasswordChecker BreachedChecker
+
+// PasswordEntropy estimates the entropy in bits of s from its length and the
+// size of the character classes it draws from. It is a coarse upper bound
+// meant for minimum-strength checks, not a guessability model.
+func PasswordEntropy(s string) float64 {
+	var lower, upper, digit, symbol, other bool
+	length := 0
+
+	for _, r := range s {
+		length++
+
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// NotBreached asks PasswordChecker whether password appeared in a breach. It
+// fails open: when the checker errors the password is accepted, so an outage
+// of the breach service does not block registrations.
+func NotBreached(password string) bool {
+	if PasswordChecker == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	breached, err := PasswordChecker.Breached(ctx, password)
+	if err != nil {
+		return true
+	}
+
+	return !breached
+}
+
+// HIBPChecker queries the Have I Been Pwned range API using k-anonymity: only
+// the first five characters of the password's SHA-1 hash leave the process.
+type HIBPChecker struct {
+	Client   *http.Client
+	Endpoint string
+}
+
+func NewHIBPChecker() *HIBPChecker {
+	return &HIBPChecker{
+		Client:   &http.Client{Timeout: 5 * time.Second},
+		Endpoint: "https://api.pwnedpasswords.com/range/",
+	}
+}
+
+func (checker *HIBPChecker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, checker.Endpoint+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// padding hides the number of matching suffixes from observers
+	request.Header.Set("Add-Padding", "true")
+
+	response, err := checker.Client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breached password check: unexpected status %d", response.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		// padded entries have a count of zero
+		return count != "0", nil
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,60 @@
+package ibnsina
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPasswordEntropy(t *testing.T) {
+	var tests = []struct {
+		Password string
+		Min      float64
+		Max      float64
+	}{
+		{"", 0, 0},
+		{"aaaaaaaa", 37, 38},
+		{"Tr0ub4dor&3", 71, 73},
+		{"correct horse battery staple", 130, 190},
+	}
+
+	for _, test := range tests {
+		actual := PasswordEntropy(test.Password)
+		if actual < test.Min || actual > test.Max {
+			t.Errorf("PasswordEntropy(%q): expected between %.0f and %.0f but was %.2f", test.Password, test.Min, test.Max, actual)
+		}
+	}
+}
+
+func TestNotBreached(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/range/5BAA6" {
+			response.Write([]byte("0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n"))
+			return
+		}
+
+		response.Write([]byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"))
+	}))
+	defer server.Close()
+
+	checker := NewHIBPChecker()
+	checker.Endpoint = server.URL + "/range/"
+
+	PasswordChecker = checker
+	defer func() { PasswordChecker = nil }()
+
+	if NotBreached("password") {
+		t.Errorf("expected %q to be reported as breached", "password")
+	}
+
+	if !NotBreached("a perfectly unique passphrase") {
+		t.Errorf("expected a unique password to pass")
+	}
+
+	server.Close()
+
+	if !NotBreached("password") {
+		t.Errorf("expected the check to fail open when the service is unavailable")
+	}
+}
@@ -0,0 +1,109 @@
+package ibnsina
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronFields = []struct {
+	min   int
+	max   int
+	names []string
+}{
+	{0, 59, nil},
+	{0, 23, nil},
+	{1, 31, nil},
+	{1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is accepted as Sunday, like most cron implementations do
+	{0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// IsTimeZone accepts IANA time zone names known to the tz database, such as
+// "Asia/Tashkent" or "UTC". The empty string and "Local", which
+// time.LoadLocation also accepts, are rejected since they do not name a zone.
+func IsTimeZone(value string) bool {
+	if value == "" || value == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(value)
+	return err == nil
+}
+
+// IsCronExpr accepts standard five-field cron expressions (minute, hour, day
+// of month, month, day of week) with lists, ranges, steps and month or day
+// names, as well as the @hourly, @daily, @weekly, @monthly and @yearly macros.
+func IsCronExpr(value string) bool {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "@") {
+		return In(strings.ToLower(value), cronMacros)
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) != len(cronFields) {
+		return false
+	}
+
+	for index, field := range fields {
+		for _, part := range strings.Split(field, ",") {
+			if !isCronPart(strings.ToLower(part), cronFields[index].min, cronFields[index].max, cronFields[index].names) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isCronPart(part string, minLimit, maxLimit int, names []string) bool {
+	span, step, hasStep := strings.Cut(part, "/")
+
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 || n > maxLimit {
+			return false
+		}
+	}
+
+	if span == "*" {
+		return true
+	}
+
+	low, high, isRange := strings.Cut(span, "-")
+
+	lowValue, ok := cronValue(low, minLimit, maxLimit, names)
+	if !ok {
+		return false
+	}
+
+	if !isRange {
+		return true
+	}
+
+	highValue, ok := cronValue(high, minLimit, maxLimit, names)
+
+	return ok && lowValue <= highValue
+}
+
+func cronValue(value string, minLimit, maxLimit int, names []string) (int, bool) {
+	for index, name := range names {
+		if value == name {
+			return index + minLimit, true
+		}
+	}
+
+	if !isDigits(value) {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minLimit || n > maxLimit {
+		return 0, false
+	}
+
+	return n, true
+}
@@ -0,0 +1,345 @@
+package ibnsina
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// tagRule is a rule usable in `validate` struct tags. check receives the field
+// value with pointers already dereferenced and the text after "=" in the tag.
+// Messages are looked up in DefaultCatalog under the rule name; size rules
+// use "min.string", "min.items" and so on depending on the field kind.
+type tagRule struct {
+	code  string
+	check func(value reflect.Value, param string) bool
+}
+
+var (
+	tagRules   = map[string]tagRule{}
+	tagRulesMu sync.RWMutex
+)
+
+func init() {
+	registerTagRule("required", tagRule{CodeRequired, func(value reflect.Value, param string) bool {
+		return !value.IsZero()
+	}})
+
+	registerTagRule("min", tagRule{CodeTooShort, func(value reflect.Value, param string) bool {
+		return compareTag(value, param) >= 0
+	}})
+
+	registerTagRule("max", tagRule{CodeTooLong, func(value reflect.Value, param string) bool {
+		return compareTag(value, param) <= 0
+	}})
+
+	registerTagRule("len", tagRule{CodeInvalid, func(value reflect.Value, param string) bool {
+		return compareTag(value, param) == 0
+	}})
+
+	registerTagRule("oneof", tagRule{CodeNotAllowed, func(value reflect.Value, param string) bool {
+		return In(fmt.Sprint(value.Interface()), strings.Fields(param))
+	}})
+
+	registerTagRule("unique", tagRule{CodeDuplicate, func(value reflect.Value, param string) bool {
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			return true
+		}
+
+		seen := make(map[any]bool, value.Len())
+		for index := 0; index < value.Len(); index++ {
+			item := value.Index(index)
+			if !item.Comparable() {
+				return true
+			}
+
+			if seen[item.Interface()] {
+				return false
+			}
+
+			seen[item.Interface()] = true
+		}
+
+		return true
+	}})
+
+	stringRules := map[string]struct {
+		message string
+		check   func(string) bool
+	}{
+		"email":    {"must be a valid email address", func(value string) bool { return Matches(value, EmailRX) }},
+		"url":      {"must be a valid URL", IsURL},
+		"http_url": {"must be a valid http or https URL", IsHTTPURL},
+		"hostname": {"must be a valid hostname", IsHostname},
+		"port":     {"must be a valid port", IsPort},
+		"rfc3339":  {"must be an RFC 3339 timestamp", IsRFC3339},
+		"luhn":     {"must be a valid card number", IsLuhnValid},
+		"iban":     {"must be a valid IBAN", IsIBAN},
+		"isbn":     {"must be a valid ISBN", IsISBN},
+		"uuid":     {"must be a valid UUID", IsUUID},
+	}
+
+	for name, rule := range stringRules {
+		check := rule.check
+
+		registerTagRule(name, tagRule{CodeInvalidFormat, func(value reflect.Value, param string) bool {
+			return value.Kind() != reflect.String || check(value.String())
+		}})
+
+		DefaultCatalog.Add("en", name, rule.message)
+	}
+
+	messages := map[string]string{
+		"required":   "must be provided",
+		"min":        "must be at least {min}",
+		"min.string": "must be at least {min} characters",
+		"min.items":  "must contain at least {min} items",
+		"max":        "must be at most {max}",
+		"max.string": "must be at most {max} characters",
+		"max.items":  "must contain at most {max} items",
+		"len":        "must be exactly {len}",
+		"len.string": "must be exactly {len} characters",
+		"len.items":  "must contain exactly {len} items",
+		"oneof":      "must be one of {oneof}",
+		"unique":     "must not contain duplicates",
+	}
+
+	for key, message := range messages {
+		DefaultCatalog.Add("en", key, message)
+	}
+}
+
+// RegisterRule makes a custom rule available to `validate` struct tags, so
+// that `validate:"username"` or `validate:"prefix=acct_"` call check with the
+// field value and the text after "=". Non-string fields are formatted with
+// fmt.Sprint. Failures are reported with the code "invalid".
+//
+// Rules are meant to be registered during initialization; registering a name
+// twice, or a name that cannot appear in a tag, panics.
+func RegisterRule(name string, check func(value string, param string) bool) {
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		panic(fmt.Sprintf("ibnsina: invalid validation rule name %q", name))
+	}
+
+	if _, exists := lookupTagRule(name); exists {
+		panic(fmt.Sprintf("ibnsina: validation rule %q is already registered", name))
+	}
+
+	registerTagRule(name, tagRule{CodeInvalid, func(value reflect.Value, param string) bool {
+		if value.Kind() == reflect.String {
+			return check(value.String(), param)
+		}
+
+		return check(fmt.Sprint(value.Interface()), param)
+	}})
+
+	DefaultCatalog.Add("en", name, "is not valid")
+}
+
+func registerTagRule(name string, rule tagRule) {
+	tagRulesMu.Lock()
+	defer tagRulesMu.Unlock()
+
+	tagRules[name] = rule
+}
+
+func lookupTagRule(name string) (tagRule, bool) {
+	tagRulesMu.RLock()
+	defer tagRulesMu.RUnlock()
+
+	rule, exists := tagRules[name]
+	return rule, exists
+}
+
+// Validate checks the fields of the struct v (or pointer to struct) against
+// their `validate` tags, e.g. `validate:"required,min=3,max=50,email"`.
+//
+// Field errors are keyed by the json tag name when there is one, nested
+// structs are reported as "address.city" and slice elements as "items[0].name".
+// Except for required, rules are skipped for nil pointers and empty strings,
+// slices and maps. An unknown rule name is a programming error and panics.
+//
+// When v is a pointer, its `sanitize` tags are applied first, see Sanitize.
+func Validate(v any) *Validator {
+	validator := NewValidator()
+
+	Sanitize(v)
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			validator.AddNonFieldError("no value to validate")
+			return validator
+		}
+
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Struct {
+		validateStruct(validator, "", value)
+	}
+
+	return validator
+}
+
+func validateStruct(validator *Validator, prefix string, value reflect.Value) {
+	typ := value.Type()
+
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		validateField(validator, prefix+name, value.Field(index), field.Tag.Get("validate"))
+	}
+}
+
+func validateField(validator *Validator, key string, value reflect.Value, tag string) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+
+		value = value.Elem()
+	}
+
+	if tag != "" && tag != "-" {
+		empty := isEmptyValue(value)
+
+	rules:
+		for _, part := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+
+			rule, exists := lookupTagRule(name)
+			if !exists {
+				panic(fmt.Sprintf("ibnsina: unknown validation rule %q on %s", name, key))
+			}
+
+			if name != "required" && empty {
+				continue
+			}
+
+			if !rule.check(value, param) {
+				code, messageKey := tagError(name, rule, value)
+				validator.AddFieldMessage(key, code, messageKey, map[string]string{name: param})
+				break rules
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if _, isTime := value.Interface().(time.Time); !isTime {
+			validateStruct(validator, key+".", value)
+		}
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			item := value.Index(index)
+			for item.Kind() == reflect.Pointer && !item.IsNil() {
+				item = item.Elem()
+			}
+
+			if item.Kind() == reflect.Struct {
+				validateStruct(validator, key+"["+strconv.Itoa(index)+"].", item)
+			}
+		}
+	}
+}
+
+func tagError(name string, rule tagRule, value reflect.Value) (string, string) {
+	if name != "min" && name != "max" && name != "len" {
+		return rule.code, name
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return rule.code, name + ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rule.code, name + ".items"
+	}
+
+	return CodeOutOfRange, name
+}
+
+// fieldName returns the key of field in errors: its JSON name or, for
+// fields filled by Typed from the path or the query, the param or query name.
+func fieldName(field reflect.StructField) string {
+	if tag := field.Tag.Get("json"); tag != "" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+
+	for _, key := range []string{"param", "query"} {
+		if name := field.Tag.Get(key); name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	}
+
+	return false
+}
+
+// compareTag compares the size of value with param: the rune count of strings,
+// the length of collections and the value itself for numbers.
+func compareTag(value reflect.Value, param string) int {
+	switch value.Kind() {
+	case reflect.String:
+		return compareInts(int64(utf8.RuneCountInString(value.String())), param)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return compareInts(int64(value.Len()), param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareInts(value.Int(), param)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		limit, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("ibnsina: invalid validation parameter %q", param))
+		}
+
+		return cmp.Compare(value.Uint(), limit)
+	case reflect.Float32, reflect.Float64:
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("ibnsina: invalid validation parameter %q", param))
+		}
+
+		return cmp.Compare(value.Float(), limit)
+	}
+
+	panic(fmt.Sprintf("ibnsina: size rules are not supported for %s", value.Kind()))
+}
+
+func compareInts(value int64, param string) int {
+	limit, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("ibnsina: invalid validation parameter %q", param))
+	}
+
+	return cmp.Compare(value, limit)
+}
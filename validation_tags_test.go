@@ -0,0 +1,134 @@
+package ibnsina
+
+import (
+	"strings"
+	"testing"
+)
+
+type testAddress struct {
+	City    string `json:"city" validate:"required"`
+	Country string `json:"country" validate:"required,len=2"`
+}
+
+type testItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1,max=100"`
+}
+
+type testOrder struct {
+	Name     string       `json:"name" validate:"required,min=3,max=50"`
+	Email    string       `json:"email" validate:"required,email"`
+	Website  string       `json:"website" validate:"http_url"`
+	Status   string       `json:"status" validate:"oneof=draft placed"`
+	Nickname *string      `json:"nickname" validate:"required"`
+	Address  *testAddress `json:"address"`
+	Items    []testItem   `json:"items" validate:"required,max=2"`
+	Tags     []string     `json:"tags" validate:"unique"`
+	internal string
+}
+
+func TestValidate(t *testing.T) {
+	nickname := "bob"
+
+	valid := testOrder{
+		Name:     "Ibn Sina",
+		Email:    "ibn@sina.org",
+		Status:   "draft",
+		Nickname: &nickname,
+		Address:  &testAddress{City: "Bukhara", Country: "UZ"},
+		Items:    []testItem{{SKU: "a", Quantity: 1}},
+	}
+
+	if validator := Validate(&valid); !validator.Ok() {
+		t.Fatalf("valid order: unexpected errors %v", validator.FieldErrors)
+	}
+
+	invalid := testOrder{
+		Name:    "Ib",
+		Email:   "not-an-email",
+		Website: "ftp://example.com",
+		Status:  "shipped",
+		Address: &testAddress{Country: "UZB"},
+		Items:   []testItem{{SKU: "a", Quantity: 0}, {Quantity: 1}, {SKU: "c", Quantity: 1}},
+		Tags:    []string{"x", "x"},
+	}
+
+	validator := Validate(invalid)
+
+	expected := map[string]string{
+		"name":              CodeTooShort,
+		"email":             CodeInvalidFormat,
+		"website":           CodeInvalidFormat,
+		"status":            CodeNotAllowed,
+		"nickname":          CodeRequired,
+		"address.city":      CodeRequired,
+		"address.country":   CodeInvalid,
+		"items":             CodeTooLong,
+		"items[0].quantity": CodeOutOfRange,
+		"items[1].sku":      CodeRequired,
+		"tags":              CodeDuplicate,
+	}
+
+	for key, code := range expected {
+		if validator.FieldCodes[key] != code {
+			t.Errorf("%s: expected code %q but was %q (%q)", key, code, validator.FieldCodes[key], validator.FieldErrors[key])
+		}
+	}
+
+	if len(validator.FieldErrors) != len(expected) {
+		t.Errorf("expected %d errors but got %v", len(expected), validator.FieldErrors)
+	}
+
+	if message := validator.FieldErrors["name"]; message != "must be at least 3 characters" {
+		t.Errorf("name: unexpected message %q", message)
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for an unknown rule")
+		}
+	}()
+
+	Validate(struct {
+		Name string `validate:"nonsense"`
+	}{})
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("test_prefix", func(value string, param string) bool {
+		return strings.HasPrefix(value, param)
+	})
+
+	// rules are global, so the test must leave none behind to run again
+	t.Cleanup(func() {
+		tagRulesMu.Lock()
+		defer tagRulesMu.Unlock()
+
+		delete(tagRules, "test_prefix")
+	})
+
+	type account struct {
+		ID     string `json:"id" validate:"required,test_prefix=acct_"`
+		Number int    `json:"number" validate:"test_prefix=42"`
+	}
+
+	validator := Validate(account{ID: "user_1", Number: 4201})
+
+	if validator.FieldCodes["id"] != CodeInvalid {
+		t.Errorf("id: expected code %q but was %q", CodeInvalid, validator.FieldCodes["id"])
+	}
+
+	if _, exists := validator.FieldErrors["number"]; exists {
+		t.Errorf("number: unexpected error %q", validator.FieldErrors["number"])
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic when registering a rule twice")
+		}
+	}()
+
+	RegisterRule("test_prefix", func(value string, param string) bool { return true })
+}
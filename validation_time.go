@@ -0,0 +1,41 @@
+package ibnsina
+
+import (
+	"time"
+)
+
+func IsRFC3339(value string) bool {
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
+
+func IsDate(value string, layout string) bool {
+	_, err := time.Parse(layout, value)
+	return err == nil
+}
+
+func IsBefore(value time.Time, limit time.Time) bool {
+	return value.Before(limit)
+}
+
+func IsAfter(value time.Time, limit time.Time) bool {
+	return value.After(limit)
+}
+
+// TimeInRange is inclusive on both ends, like RunesInRange and CharsInRange.
+func TimeInRange(value time.Time, minLimit, maxLimit time.Time) bool {
+	return !value.Before(minLimit) && !value.After(maxLimit)
+}
+
+// ParseTime parses value with layout once, recording a field error on failure,
+// so the returned time can be handed straight to IsBefore, IsAfter or
+// TimeInRange.
+func (validator *Validator) ParseTime(key string, value string, layout string, message string) (time.Time, bool) {
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		validator.AddFieldError(key, CodeInvalidFormat, message)
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
@@ -0,0 +1,141 @@
+package ibnsina
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scripts that are routinely mixed within a single writing system
+var scriptSets = map[string]string{
+	"Hiragana": "Han",
+	"Katakana": "Han",
+	"Bopomofo": "Han",
+	"Hangul":   "Han",
+}
+
+// confusables maps characters that render like ASCII letters and digits to
+// the character they imitate. It covers the lookalikes commonly used to spoof
+// Latin identifiers rather than the full Unicode confusables table.
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'һ': "h", 'і': "i", 'ї': "i", 'ј': "j", 'к': "k", 'м': "m",
+	'н': "h", 'о': "o", 'р': "p", 'с': "c", 'т': "t", 'у': "y", 'х': "x", 'ѕ': "s", 'ԁ': "d", 'ԛ': "q",
+	'ԝ': "w", 'ӏ': "l", 'ү': "y",
+	'А': "a", 'В': "b", 'Е': "e", 'К': "k", 'М': "m", 'Н': "h", 'О': "o", 'Р': "p", 'С': "c", 'Т': "t",
+	'Х': "x", 'І': "l", 'Ј': "j", 'Ѕ': "s", 'Ү': "y", 'Ԁ': "d", 'Ԛ': "q", 'Ԝ': "w",
+	// Greek
+	'α': "a", 'ο': "o", 'ν': "v", 'ρ': "p", 'ι': "i", 'κ': "k", 'υ': "u", 'χ': "x", 'τ': "t",
+	'Α': "a", 'Β': "b", 'Ε': "e", 'Ζ': "z", 'Η': "h", 'Ι': "l", 'Κ': "k", 'Μ': "m", 'Ν': "n", 'Ο': "o",
+	'Ρ': "p", 'Τ': "t", 'Υ': "y", 'Χ': "x",
+	// Latin lookalikes
+	'ı': "i", 'ȷ': "j", 'ℓ': "l", 'ǀ': "l", '|': "l", 'ß': "ss", 'ſ': "f", 'ɡ': "g",
+	// digits
+	'0': "o", '1': "l", '5': "s",
+}
+
+func IsPrintable(value string) bool {
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NoControlChars rejects control characters and invisible format characters
+// such as zero-width joiners and bidirectional overrides.
+func NoControlChars(value string) bool {
+	for _, r := range value {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSingleScript reports whether the letters of value belong to a single
+// script, ignoring characters shared by all scripts such as digits and
+// punctuation. Han may be combined with Hiragana, Katakana, Bopomofo or
+// Hangul, as in Japanese, Chinese and Korean text.
+func IsSingleScript(value string) bool {
+	found := ""
+
+	for _, r := range value {
+		script := scriptOf(r)
+		if script == "" {
+			continue
+		}
+
+		if set, exists := scriptSets[script]; exists {
+			script = set
+		}
+
+		if found == "" {
+			found = script
+			continue
+		}
+
+		if script != found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Skeleton maps value to a canonical form where lookalike characters collapse
+// together, so that "pаypal" with a Cyrillic а and "paypal" share a skeleton.
+func Skeleton(value string) string {
+	var builder strings.Builder
+
+	for _, r := range value {
+		// fullwidth forms of ASCII
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+
+		if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if replacement, exists := confusables[r]; exists {
+			builder.WriteString(replacement)
+			continue
+		}
+
+		builder.WriteRune(unicode.ToLower(r))
+	}
+
+	return strings.NewReplacer("rn", "m", "vv", "w", "cl", "d").Replace(builder.String())
+}
+
+// Confusable reports whether a and b could be mistaken for each other, for
+// instance a new username imitating an existing one.
+func Confusable(a, b string) bool {
+	return Skeleton(a) == Skeleton(b)
+}
+
+func scriptOf(r rune) string {
+	if !unicode.IsLetter(r) && !unicode.IsMark(r) {
+		return ""
+	}
+
+	if unicode.Is(unicode.Common, r) || unicode.Is(unicode.Inherited, r) {
+		return ""
+	}
+
+	// most identifiers are Latin, check it first
+	if unicode.Is(unicode.Latin, r) {
+		return "Latin"
+	}
+
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package ibnsina
+
+import (
+	"testing"
+)
+
+func TestUnicodeValidators(t *testing.T) {
+	var tests = []struct {
+		Value string
+
+		ExpectedPrintable    bool
+		ExpectedNoControl    bool
+		ExpectedSingleScript bool
+	}{
+		{"ibn_sina42", true, true, true},
+		{"Ибн Сино", true, true, true},
+		{"東京たワー", true, true, true},
+		{"pаypal", true, true, false},
+		{"tab\there", false, false, true},
+		{"admin\u202e", false, false, true},
+		{"zero\u200bwidth", false, false, true},
+		{"αβγ-123", true, true, true},
+		{"\u0261oogle", true, true, true},
+	}
+
+	for _, test := range tests {
+		if actual := IsPrintable(test.Value); actual != test.ExpectedPrintable {
+			t.Errorf("IsPrintable(%q): expected %t but was %t", test.Value, test.ExpectedPrintable, actual)
+		}
+
+		if actual := NoControlChars(test.Value); actual != test.ExpectedNoControl {
+			t.Errorf("NoControlChars(%q): expected %t but was %t", test.Value, test.ExpectedNoControl, actual)
+		}
+
+		if actual := IsSingleScript(test.Value); actual != test.ExpectedSingleScript {
+			t.Errorf("IsSingleScript(%q): expected %t but was %t", test.Value, test.ExpectedSingleScript, actual)
+		}
+	}
+}
+
+func TestConfusable(t *testing.T) {
+	var tests = []struct {
+		A        string
+		B        string
+		Expected bool
+	}{
+		{"paypal", "pаypal", true},
+		{"PayPal", "paypal", true},
+		{"admin", "adm1n", false},
+		{"google", "g00gle", true},
+		{"google", "\u0261oogle", true},
+		{"modern", "modem", true},
+		{"ｉｂｎｓｉｎａ", "ibnsina", true},
+		{"alice", "bob", false},
+	}
+
+	for _, test := range tests {
+		if actual := Confusable(test.A, test.B); actual != test.Expected {
+			t.Errorf("Confusable(%q, %q): expected %t but was %t (%q, %q)", test.A, test.B, test.Expected, actual, Skeleton(test.A), Skeleton(test.B))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ibnsina
+
+import (
+	"net/http"
+	"strings"
+)
+
+// VersionHeader is the request header selecting the API version of the
+// request, taking precedence over the Accept header.
+const VersionHeader = "X-API-Version"
+
+// Version returns a group whose routes only serve requests of the API
+// version name, as selected by the VersionHeader or a vendor media type in
+// Accept, such as "application/vnd.myapp.v2+json" for version "v2":
+//
+//	v2 := router.Version("v2")
+//	v2.Handle("/users/:id", getUserV2, "GET")
+//
+// Requests of a version are served by its routes first, then by the
+// unversioned routes, in registration order; requests of no or an unknown
+// version only by the unversioned routes. Once a version is registered,
+// responses vary on Accept and VersionHeader.
+func (router *Router) Version(name string) *Group {
+	return &Group{
+		router:  router,
+		version: name,
+	}
+}
+
+// requestVersion returns the API version requested by request, if any.
+func requestVersion(request *http.Request) string {
+	if version := request.Header.Get(VersionHeader); version != "" {
+		return version
+	}
+
+	for _, accept := range strings.Split(request.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accept, ";")
+
+		subtype, ok := strings.CutPrefix(strings.TrimSpace(mediaType), "application/vnd.")
+		if !ok {
+			continue
+		}
+
+		subtype, _, _ = strings.Cut(subtype, "+")
+
+		if index := strings.LastIndexByte(subtype, '.'); index > -1 {
+			return subtype[index+1:]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,108 @@
+package ibnsina
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	respond := func(body string) Handler {
+		return func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+			response.Write([]byte(body))
+		}
+	}
+
+	router := NewRouter()
+	router.EnableMatchCache(16)
+	router.Handle("/users/:id", respond("v1 user"), "GET")
+	router.Handle("/health", respond("health"), "GET")
+
+	v2 := router.Version("v2")
+	v2.Handle("/users/:id", respond("v2 user"), "GET")
+	v2.Handle("/users", respond("v2 users"), "POST")
+
+	tests := []struct {
+		method string
+		path   string
+		header string
+		value  string
+		status int
+		body   string
+	}{
+		{"GET", "/users/1", "", "", http.StatusOK, "v1 user"},
+		{"GET", "/users/1", "Accept", "application/vnd.myapp.v2+json", http.StatusOK, "v2 user"},
+		{"GET", "/users/1", "Accept", "text/html, application/vnd.myapp.v2+json;q=0.9", http.StatusOK, "v2 user"},
+		{"GET", "/users/1", VersionHeader, "v2", http.StatusOK, "v2 user"},
+		{"GET", "/users/1", VersionHeader, "v3", http.StatusOK, "v1 user"},
+		{"GET", "/health", VersionHeader, "v2", http.StatusOK, "health"},
+		{"POST", "/users", VersionHeader, "v2", http.StatusOK, "v2 users"},
+		{"POST", "/users", "", "", http.StatusNotFound, "the requested resource could not be found\n"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, nil)
+		if test.header != "" {
+			request.Header.Set(test.header, test.value)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status || recorder.Body.String() != test.body {
+			t.Errorf("%s %s %s=%s: expected %d %q but was %d %q", test.method, test.path, test.header, test.value, test.status, test.body, recorder.Code, recorder.Body.String())
+		}
+
+		if vary := recorder.Header().Values("Vary"); len(vary) != 2 {
+			t.Errorf("unexpected Vary %q", vary)
+		}
+	}
+
+	if snapshot := router.Snapshot(); snapshot != ""+
+		"GET /users/:id []\n"+
+		"HEAD /users/:id []\n"+
+		"GET /health []\n"+
+		"HEAD /health []\n"+
+		"GET /users/:id v2 []\n"+
+		"HEAD /users/:id v2 []\n"+
+		"POST /users v2 []\n" {
+		t.Errorf("unexpected snapshot\n%s", snapshot)
+	}
+}
+
+func TestVersionRemove(t *testing.T) {
+	router := NewRouter()
+	router.EnableMatchCache(16)
+
+	router.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("v1 user"))
+	}, "GET")
+
+	v2 := router.Version("v2")
+	v2.Handle("/users/:id", func(ctx context.Context, response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("v2 user"))
+	}, "GET")
+
+	serve := func() string {
+		request := httptest.NewRequest("GET", "/users/1", nil)
+		request.Header.Set(VersionHeader, "v2")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		return recorder.Body.String()
+	}
+
+	if body := serve(); body != "v2 user" {
+		t.Fatalf("expected the v2 route but got %q", body)
+	}
+
+	if router.Group().Remove(http.MethodPost, "/users/:id") || !v2.Remove(http.MethodGet, "/users/:id") || v2.Remove(http.MethodGet, "/users/:id") {
+		t.Error("expected the v2 route alone to be removed, once")
+	}
+
+	if body := serve(); body != "v1 user" {
+		t.Errorf("expected the unversioned route once v2 was removed but got %q", body)
+	}
+}
@@ -0,0 +1,161 @@
+package ibnsina
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// ErrResponseFailed is returned by the writes following a failure of the
+// response, once the response failed halfway.
+var ErrResponseFailed = errors.New("ibnsina: the response failed")
+
+// responseWriter records the status code written by handlers in their Values,
+// and counts the bytes of the body. It lives inside the pooled Values, so
+// wrapping costs no allocation.
+type responseWriter struct {
+	http.ResponseWriter
+	values  *Values
+	written int64
+	applied bool
+	failed  bool
+}
+
+func (writer *responseWriter) reset(response http.ResponseWriter, values *Values) {
+	writer.ResponseWriter = response
+	writer.values = values
+	writer.written = 0
+	writer.applied = false
+	writer.failed = false
+}
+
+func (writer *responseWriter) WriteHeader(status int) {
+	if writer.failed {
+		return
+	}
+
+	if writer.values.Status == 0 {
+		writer.values.Status = status
+	}
+
+	writer.applyHeaders()
+
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *responseWriter) Write(data []byte) (int, error) {
+	if writer.failed {
+		return 0, ErrResponseFailed
+	}
+
+	if writer.values.Status == 0 {
+		writer.values.Status = http.StatusOK
+	}
+
+	writer.applyHeaders()
+
+	n, err := writer.ResponseWriter.Write(data)
+	writer.written += int64(n)
+
+	return n, err
+}
+
+// Flush and Hijack keep the optional interfaces of the wrapped writer
+// reachable through type assertions; Unwrap does so for
+// http.ResponseController.
+func (writer *responseWriter) Flush() {
+	if writer.failed {
+		return
+	}
+
+	if writer.values.Status == 0 {
+		writer.values.Status = http.StatusOK
+	}
+
+	writer.applyHeaders()
+
+	http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(writer.ResponseWriter).Hijack()
+}
+
+func (writer *responseWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// failResponse handles err, which occurred once the response was started, so
+// that it can no longer be answered with an error status: err is logged with
+// the trace ID, unless the client went away, the writes that follow are
+// dropped, so that nothing is appended to the truncated body, and the status
+// in Values becomes 500 Internal Server Error, or StatusClientClosedRequest,
+// for AccessLog and metrics, though it was never sent. Once the handler
+// returns, the Router aborts the response with http.ErrAbortHandler, so that
+// clients see the failure instead of a well-formed truncated body. Only the
+// first failure of a response counts.
+func failResponse(ctx context.Context, err error) {
+	values := GetValues(ctx)
+	if values == nil || values.writer.failed {
+		return
+	}
+
+	values.writer.failed = true
+
+	if Disconnected(ctx) {
+		values.Status = StatusClientClosedRequest
+		return
+	}
+
+	values.failed(err, nil)
+
+	if values.Logger != nil {
+		values.Logger.Printf("%s: the response failed after status %d: %v", values.TraceID, values.Status, err)
+	}
+
+	values.Status = http.StatusInternalServerError
+}
+
+// bufferedResponse is a ResponseWriter keeping the response in memory, to
+// be sent later, possibly more than once.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (buffered *bufferedResponse) Header() http.Header {
+	return buffered.header
+}
+
+func (buffered *bufferedResponse) WriteHeader(status int) {
+	if buffered.status == 0 {
+		buffered.status = status
+	}
+}
+
+func (buffered *bufferedResponse) Write(data []byte) (int, error) {
+	if buffered.status == 0 {
+		buffered.status = http.StatusOK
+	}
+
+	return buffered.body.Write(data)
+}
+
+// writeTo sends the response, its header added to those already set.
+func (buffered *bufferedResponse) writeTo(response http.ResponseWriter) {
+	header := response.Header()
+	for name, values := range buffered.header {
+		header[name] = slices.Clone(values)
+	}
+
+	if buffered.status != 0 {
+		response.WriteHeader(buffered.status)
+	}
+
+	response.Write(buffered.body.Bytes())
+}